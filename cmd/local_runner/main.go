@@ -0,0 +1,213 @@
+// local_runner executes a handler against a JSONL file of mutations, without a
+// Couchbase cluster. Events are fed to a sandboxed eventing-consumer over the
+// regular Go to C++ worker protocol and bucket bindings are served from an
+// in-memory store, which retains writes across records of a run.
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+
+	"github.com/couchbase/eventing/common"
+	"github.com/couchbase/eventing/consumer"
+	"github.com/couchbase/eventing/gen/flatbuf/cfg"
+	"github.com/couchbase/eventing/logging"
+	"github.com/couchbase/eventing/util"
+	"github.com/google/flatbuffers/go"
+)
+
+const (
+	// Upper bound on length of a single JSONL record
+	maxRecordSize = 20 * 1024 * 1024
+)
+
+type recordResult struct {
+	Line      int                          `json:"line"`
+	Key       string                       `json:"key"`
+	Op        string                       `json:"op"`
+	Exception string                       `json:"exception,omitempty"`
+	Logs      []string                     `json:"logs"`
+	Mutations []common.HandlerTestMutation `json:"mutations"`
+}
+
+type runReport struct {
+	Results        []recordResult         `json:"results"`
+	Exceptions     int                    `json:"exceptions"`
+	ExecutionStats map[string]interface{} `json:"execution_stats"`
+	FailureStats   map[string]interface{} `json:"failure_stats"`
+}
+
+func main() {
+	initFlags()
+
+	logging.SetLogWriter(os.Stderr)
+	logging.SetLogLevel(logging.Level(flags.logLevel))
+	util.SetMaxVbuckets(flags.numVbuckets)
+
+	report, err := run()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(2)
+	}
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to marshal report, err: %v\n", err)
+		os.Exit(2)
+	}
+	fmt.Println(string(data))
+
+	if report.Exceptions > 0 {
+		os.Exit(1)
+	}
+}
+
+func run() (*runReport, error) {
+	appCode, err := ioutil.ReadFile(flags.handlerFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read handler, err: %v", err)
+	}
+
+	bindings, err := parseBindings(flags.bindings)
+	if err != nil {
+		return nil, err
+	}
+
+	if flags.constants != "" {
+		var constants map[string]interface{}
+		if err = json.Unmarshal([]byte(flags.constants), &constants); err != nil {
+			return nil, fmt.Errorf("constants must be a JSON object, err: %v", err)
+		}
+	}
+
+	input, err := os.Open(flags.inputFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open input, err: %v", err)
+	}
+	defer input.Close()
+
+	appContent := encodeAppPayload(flags.appName, string(appCode), bindings)
+
+	c := &consumer.Consumer{}
+	err = c.StartSandboxWorker(string(appCode), string(appContent), flags.appName, "",
+		toStringArray(flags.handlerHeaders), toStringArray(flags.handlerFooters))
+	if err != nil {
+		return nil, fmt.Errorf("failed to start eventing-consumer, err: %v", err)
+	}
+	defer c.StopSandboxWorker()
+
+	report := &runReport{Results: make([]recordResult, 0)}
+
+	scanner := bufio.NewScanner(input)
+	scanner.Buffer(make([]byte, 64*1024), maxRecordSize)
+
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var req common.HandlerTestRequest
+		err = json.Unmarshal(scanner.Bytes(), &req)
+		if err != nil {
+			return nil, fmt.Errorf("line: %d malformed record, err: %v", line, err)
+		}
+
+		if req.Key == "" {
+			return nil, fmt.Errorf("line: %d record has empty key", line)
+		}
+
+		switch req.Op {
+		case common.HandlerTestOpUpdate, common.HandlerTestOpDelete, common.HandlerTestOpExpire:
+		default:
+			return nil, fmt.Errorf("line: %d record has invalid op: %s", line, req.Op)
+		}
+
+		result, err := c.RunSandboxEvent(&req)
+		if err != nil {
+			return nil, fmt.Errorf("line: %d %v", line, err)
+		}
+
+		if result.Exception != "" {
+			report.Exceptions++
+		}
+
+		report.Results = append(report.Results, recordResult{
+			Line:      line,
+			Key:       req.Key,
+			Op:        req.Op,
+			Exception: result.Exception,
+			Logs:      result.Logs,
+			Mutations: result.Mutations,
+		})
+	}
+
+	if err = scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read input, err: %v", err)
+	}
+
+	report.ExecutionStats, report.FailureStats, err = c.SandboxWorkerStats()
+	if err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// encodeAppPayload frames deployment config the same way eventing service does,
+// worker reads bucket bindings and constants from it
+func encodeAppPayload(appName, appCode string, bindings map[string]bucketBinding) []byte {
+	builder := flatbuffers.NewBuilder(0)
+
+	aliases := make([]string, 0, len(bindings))
+	for alias := range bindings {
+		aliases = append(aliases, alias)
+	}
+	sort.Strings(aliases)
+
+	var bNames []flatbuffers.UOffsetT
+	for _, alias := range aliases {
+		aliasName := builder.CreateString(alias)
+		bName := builder.CreateString(bindings[alias].bucket)
+		access := builder.CreateString(bindings[alias].access)
+
+		cfg.BucketStart(builder)
+		cfg.BucketAddAlias(builder, aliasName)
+		cfg.BucketAddBucketName(builder, bName)
+		cfg.BucketAddAccess(builder, access)
+		bNames = append(bNames, cfg.BucketEnd(builder))
+	}
+
+	cfg.DepCfgStartBucketsVector(builder, len(bNames))
+	for i := 0; i < len(bNames); i++ {
+		builder.PrependUOffsetT(bNames[i])
+	}
+	buckets := builder.EndVector(len(bNames))
+
+	metaBucket := builder.CreateString(flags.metadataBucket)
+	sourceBucket := builder.CreateString(flags.sourceBucket)
+	constants := builder.CreateString(flags.constants)
+
+	cfg.DepCfgStart(builder)
+	cfg.DepCfgAddBuckets(builder, buckets)
+	cfg.DepCfgAddMetadataBucket(builder, metaBucket)
+	cfg.DepCfgAddSourceBucket(builder, sourceBucket)
+	cfg.DepCfgAddConstants(builder, constants)
+	depcfg := cfg.DepCfgEnd(builder)
+
+	code := builder.CreateString(appCode)
+	aName := builder.CreateString(appName)
+
+	cfg.ConfigStart(builder)
+	cfg.ConfigAddAppCode(builder, code)
+	cfg.ConfigAddAppName(builder, aName)
+	cfg.ConfigAddDepCfg(builder, depcfg)
+	config := cfg.ConfigEnd(builder)
+
+	builder.Finish(config)
+
+	return builder.FinishedBytes()
+}
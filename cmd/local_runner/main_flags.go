@@ -0,0 +1,134 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Flags encapsulates different command-line parameters local runner
+// executable exposes
+type Flags struct {
+	handlerFile    string
+	inputFile      string
+	appName        string
+	bindings       string
+	constants      string
+	sourceBucket   string
+	metadataBucket string
+	handlerHeaders string
+	handlerFooters string
+	numVbuckets    int
+	logLevel       string
+}
+
+var flags Flags
+
+// maxVbuckets is the vbucket count eventing-consumer keeps per vbucket state for
+const maxVbuckets = 1024
+
+func initFlags() {
+
+	fset := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+
+	fset.StringVar(&flags.handlerFile,
+		"handler", "",
+		"File containing handler code")
+
+	fset.StringVar(&flags.inputFile,
+		"input", "",
+		"JSONL file of mutations, one {op,key,value,vb,seqno} record per line. op is one of update, delete or expire")
+
+	fset.StringVar(&flags.appName,
+		"name", "local_runner",
+		"Function name reported to handler")
+
+	fset.StringVar(&flags.bindings,
+		"bindings", "",
+		"Comma separated bucket bindings in alias=bucket[:r|:rw] form, served from an in-memory store")
+
+	fset.StringVar(&flags.constants,
+		"constants", "",
+		"JSON object of constants exposed to handler as read-only globals, keyed by alias")
+
+	fset.StringVar(&flags.sourceBucket,
+		"source", "default",
+		"Source bucket name")
+
+	fset.StringVar(&flags.metadataBucket,
+		"metadata", "eventing",
+		"Metadata bucket name")
+
+	fset.StringVar(&flags.handlerHeaders,
+		"headers", "'use strict';",
+		"Semicolon terminated code prepended to handler")
+
+	fset.StringVar(&flags.handlerFooters,
+		"footers", "",
+		"Code appended to handler")
+
+	fset.IntVar(&flags.numVbuckets,
+		"vbuckets", 1024,
+		"Number of vbuckets, used to derive vbucket of records that don't carry one")
+
+	fset.StringVar(&flags.logLevel,
+		"loglevel", "ERROR",
+		"Log level of runner, logs are written to stderr")
+
+	fset.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage of %s:\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "eventing-consumer binary needs to be in PATH\n")
+		fset.PrintDefaults()
+	}
+
+	fset.Parse(os.Args[1:])
+
+	if flags.handlerFile == "" || flags.inputFile == "" {
+		fset.Usage()
+		os.Exit(2)
+	}
+
+	if flags.numVbuckets <= 0 || flags.numVbuckets > maxVbuckets {
+		fmt.Fprintf(os.Stderr, "vbuckets should be between 1 and %d, received: %d\n", maxVbuckets, flags.numVbuckets)
+		os.Exit(2)
+	}
+}
+
+// bucketBinding is bucket name and access mode an alias is bound to
+type bucketBinding struct {
+	bucket string
+	access string
+}
+
+func parseBindings(bindings string) (map[string]bucketBinding, error) {
+	aliases := make(map[string]bucketBinding)
+	if bindings == "" {
+		return aliases, nil
+	}
+
+	for _, binding := range strings.Split(bindings, ",") {
+		parts := strings.SplitN(binding, "=", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("invalid binding: %s, expected alias=bucket[:r|:rw]", binding)
+		}
+
+		b := bucketBinding{bucket: parts[1], access: "rw"}
+		if i := strings.LastIndex(parts[1], ":"); i >= 0 {
+			b.bucket, b.access = parts[1][:i], parts[1][i+1:]
+		}
+
+		if b.bucket == "" || (b.access != "r" && b.access != "rw") {
+			return nil, fmt.Errorf("invalid binding: %s, expected alias=bucket[:r|:rw]", binding)
+		}
+		aliases[parts[0]] = b
+	}
+	return aliases, nil
+}
+
+func toStringArray(code string) []string {
+	if code == "" {
+		return nil
+	}
+	return []string{code}
+}
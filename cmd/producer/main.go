@@ -21,6 +21,10 @@ func main() {
 
 	audit.Init(flags.restPort)
 
+	if err := util.InitSecretKey(); err != nil {
+		logging.Errorf("Failed to load secret key from metakv, will retry on use, err: %v", err)
+	}
+
 	adminPort := supervisor.AdminPortConfig{
 		DebuggerPort: flags.debugPort,
 		HTTPPort:     flags.adminHTTPPort,
@@ -1,7 +1,9 @@
 package common
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
 	"net"
 )
 
@@ -52,6 +54,7 @@ var ErrRetryTimeout = errors.New("retry timeout")
 type EventingProducer interface {
 	AddMetadataPrefix(key string) Key
 	Auth() string
+	CancelTimers(req *TimerCancelRequest) (int, error)
 	CfgData() string
 	CheckpointBlobDump() map[string]interface{}
 	CleanupMetadataBucket() error
@@ -60,6 +63,7 @@ type EventingProducer interface {
 	GetAppCode() string
 	GetDcpEventsRemainingToProcess() uint64
 	GetDebuggerURL() (string, error)
+	GetDcpEventsProcessedPSecPerVb() float64
 	GetEventingConsumerPids() map[string]int
 	GetEventProcessingStats() map[string]uint64
 	GetExecutionStats() map[string]interface{}
@@ -72,7 +76,9 @@ type EventingProducer interface {
 	GetNsServerPort() string
 	GetVbOwner(vb uint16) (string, string, error)
 	GetSeqsProcessed() map[int]int64
+	GetWorkerStats() map[string]*WorkerStats
 	GetSourceMap() string
+	GetTimerLatenessStats() map[string]uint64
 	GetDebuggerToken() string
 	InternalVbDistributionStats() map[string]string
 	IsEventingNodeAlive(eventingHostPortAddr, nodeUUID string) bool
@@ -80,6 +86,7 @@ type EventingProducer interface {
 	KillAndRespawnEventingConsumer(consumer EventingConsumer)
 	KvHostPorts() []string
 	LenRunningConsumers() int
+	ListTimers(query *TimerQuery) ([]*TimerRecord, error)
 	MetadataBucket() string
 	NotifyInit()
 	NotifyPrepareTopologyChange(ejectNodes, keepNodes []string)
@@ -93,6 +100,7 @@ type EventingProducer interface {
 	RebalanceStatus() bool
 	RebalanceTaskProgress() *RebalanceProgress
 	RemoveConsumerToken(workerName string)
+	ReplayDeadLetters() (*DeadLetterReplayStats, error)
 	SignalBootstrapFinish()
 	SignalStartDebugger(token string) error
 	SignalStopDebugger() error
@@ -117,6 +125,7 @@ type EventingProducer interface {
 
 // EventingConsumer interface to export functions from eventing_consumer
 type EventingConsumer interface {
+	CheckpointAndReleaseVbs() error
 	ClearEventStats()
 	ConsumerName() string
 	DcpEventsRemainingToProcess() uint64
@@ -130,6 +139,8 @@ type EventingConsumer interface {
 	GetLcbExceptionsStats() map[string]uint64
 	GetMetaStoreStats() map[string]uint64
 	GetSourceMap() string
+	GetTimerLatenessStats() map[string]uint64
+	GiveUpVbs(vbs []uint16) error
 	HandleV8Worker() error
 	HostPortAddr() string
 	Index() int
@@ -138,9 +149,11 @@ type EventingConsumer interface {
 	NotifyClusterChange()
 	NotifyRebalanceStop()
 	NotifySettingsChange()
+	OwnsVb(vb uint16) bool
 	Pid() int
 	RebalanceStatus() bool
 	RebalanceTaskProgress() *RebalanceProgress
+	ReplayMutation(vb uint16, docID string, value []byte, cas, seqNo uint64) error
 	Serve()
 	SetConnHandle(net.Conn)
 	SetFeedbackConnHandle(net.Conn)
@@ -150,8 +163,11 @@ type EventingConsumer interface {
 	SignalFeedbackConnected()
 	SignalStopDebugger() error
 	SpawnCompilationWorker(appCode, appContent, appName, eventingPort string, handlerHeaders, handlerFooters []string) (*CompileStatus, error)
+	SpawnTestWorker(appCode, appContent, appName, eventingPort string, handlerHeaders, handlerFooters []string,
+		req *HandlerTestRequest) (*HandlerTestResult, error)
 	Stop()
 	String() string
+	TakeOverVbs(vbs []uint16)
 	TimerDebugStats() map[int]map[string]interface{}
 	UpdateEventingNodesUUIDs(uuids []string)
 	UpdateWorkerQueueMemCap(quota int64)
@@ -164,6 +180,7 @@ type EventingConsumer interface {
 
 type EventingSuperSup interface {
 	BootstrapAppList() map[string]string
+	CancelTimers(appName string, req *TimerCancelRequest) (int, error)
 	CheckpointBlobDump(appName string) (interface{}, error)
 	ClearEventStats()
 	CleanupProducer(appName string, skipMetaCleanup bool) error
@@ -171,6 +188,7 @@ type EventingSuperSup interface {
 	GetEventProcessingStats(appName string) map[string]uint64
 	GetAppCode(appName string) string
 	GetAppState(appName string) int8
+	GetDcpEventsProcessedPSecPerVb() float64
 	GetDcpEventsRemainingToProcess(appName string) uint64
 	GetDebuggerURL(appName string) (string, error)
 	GetDeployedApps() map[string]string
@@ -184,12 +202,16 @@ type EventingSuperSup interface {
 	GetMetaStoreStats(appName string) map[string]uint64
 	GetSeqsProcessed(appName string) map[int]int64
 	GetSourceMap(appName string) string
+	GetTimerLatenessStats(appName string) map[string]uint64
+	GetWorkerStats(appName string) map[string]*WorkerStats
 	InternalVbDistributionStats(appName string) map[string]string
+	ListTimers(appName string, query *TimerQuery) ([]*TimerRecord, error)
 	NotifyPrepareTopologyChange(ejectNodes, keepNodes []string)
 	PlannerStats(appName string) []*PlannerNodeVbMapping
 	RebalanceStatus() bool
 	RebalanceTaskProgress(appName string) (*RebalanceProgress, error)
 	RemoveProducerToken(appName string)
+	ReplayDeadLetters(appName string) (*DeadLetterReplayStats, error)
 	RestPort() string
 	SignalStopDebugger(appName string) error
 	SpanBlobDump(appName string) (interface{}, error)
@@ -228,12 +250,158 @@ type RebalanceProgress struct {
 	NodeLevelStats        interface{}
 }
 
+// Possible ways of weighting vbucket share of an eventing node
+const (
+	PlannerWeightingNone       = "none"
+	PlannerWeightingCPUCount   = "cpu_count"
+	PlannerWeightingThroughput = "throughput"
+)
+
+// NodeWeight captures capacity of an eventing node, as sampled during topology change
+type NodeWeight struct {
+	CPUCount                    int     `json:"cpu_count"`
+	DcpEventsProcessedPSecPerVb float64 `json:"dcp_events_processed_psec_per_vb"`
+}
+
 type EventProcessingStats struct {
 	DcpEventsProcessedPSec   int    `json:"dcp_events_processed_psec"`
 	TimerEventsProcessedPSec int    `json:"timer_events_processed_psec"`
 	Timestamp                string `json:"timestamp"`
 }
 
+// WorkerStats captures stat counters reported by a single Eventing.Consumer instance
+type WorkerStats struct {
+	ExecutionStats       map[string]interface{}
+	FailureStats         map[string]interface{}
+	LatencyStats         map[string]uint64
+	LcbExceptionStats    map[string]uint64
+	MetaStoreStats       map[string]uint64
+	TimerLatenessStats   map[string]uint64
+	VbDcpEventsRemaining map[int]int64
+}
+
+// DeadLetterEntry captures a failed handler invocation, as persisted in dead letter bucket
+type DeadLetterEntry struct {
+	Cas       uint64 `json:"cas"`
+	Category  string `json:"category"` // Possible values are exception, timeout
+	DocID     string `json:"key"`
+	Event     string `json:"event"` // Possible values are mutation, deletion, expiration
+	Exception string `json:"exception"`
+	Function  string `json:"function"`
+	SeqNo     uint64 `json:"seq_no"`
+	Timestamp string `json:"timestamp"`
+	Vbucket   uint16 `json:"vb"`
+}
+
+// DeadLetterIndex tracks dead lettered doc ids per vbucket, to allow replay
+// without a secondary index on dead letter bucket
+type DeadLetterIndex struct {
+	Keys []string `json:"keys"`
+}
+
+type DeadLetterReplayStats struct {
+	Failed   int `json:"failed"`
+	Replayed int `json:"replayed"`
+	Skipped  int `json:"skipped"`
+}
+
+// TimerQuery selects pending timers of a function by vbucket and due time
+type TimerQuery struct {
+	Vbs   []uint16 // All vbuckets when empty
+	From  int64    // Unix time, zero leaves range unbounded
+	To    int64
+	Limit int // Only applies to listing
+}
+
+// TimerRecord describes a pending timer
+type TimerRecord struct {
+	Vb             uint16 `json:"vb"`
+	Due            int64  `json:"due"`
+	Callback       string `json:"callback"`
+	Reference      string `json:"reference"` // As passed to createTimer, empty for timers created before it was stored
+	ContextPreview string `json:"context_preview"`
+}
+
+// TimerCancelRequest cancels timer created with the given callback and reference, or
+// else all timers selected by query
+type TimerCancelRequest struct {
+	TimerQuery
+	Callback  string
+	Reference string
+}
+
+// Dead letter keys carry function name, as functions on the same bucket may share dead letter bucket and key prefix
+func DeadLetterEntryKey(keyPrefix, appName, docID string) string {
+	return keyPrefix + "::" + appName + "::entry::" + docID
+}
+
+func DeadLetterIndexKey(keyPrefix, appName string, vb uint16) string {
+	return fmt.Sprintf("%s::%s::index::%d", keyPrefix, appName, vb)
+}
+
+// Operators supported by event filter predicates
+const (
+	FilterOpEquals = "equals"
+	FilterOpExists = "exists"
+	FilterOpIn     = "in"
+)
+
+// EventFilter captures conditions a mutation has to satisfy to be sent to handler.
+// All conditions that are set need to hold.
+type EventFilter struct {
+	KeyPrefix  string
+	KeyRegex   string
+	Predicates []FilterPredicate
+}
+
+// FilterPredicate is a condition on a document field, addressed by dotted path
+type FilterPredicate struct {
+	Field  string
+	Op     string
+	Values []string // JSON encoded
+}
+
+// CurlCredential carries decrypted secrets of a curl binding, which are encrypted in
+// function definition stored in metakv
+type CurlCredential struct {
+	Alias     string
+	Password  string
+	BearerKey string
+}
+
+// Operations a handler can be test invoked with
+const (
+	HandlerTestOpUpdate = "update"
+	HandlerTestOpDelete = "delete"
+	HandlerTestOpExpire = "expire"
+)
+
+// HandlerTestRequest captures the document a handler is test invoked against.
+// Vbucket is derived from key and seqno defaults to 1, when not supplied.
+type HandlerTestRequest struct {
+	Key     string          `json:"key"`
+	Op      string          `json:"op"`
+	SeqNo   uint64          `json:"seqno,omitempty"`
+	Value   json.RawMessage `json:"value"`
+	Vbucket *uint16         `json:"vb,omitempty"`
+}
+
+// HandlerTestMutation is a bucket write made by handler during a test run
+type HandlerTestMutation struct {
+	Alias  string      `json:"alias"`
+	Bucket string      `json:"bucket"`
+	Key    string      `json:"key"`
+	Op     string      `json:"op"`
+	Value  interface{} `json:"value,omitempty"`
+}
+
+// HandlerTestResult is what a handler did during a test run. Nothing is persisted.
+type HandlerTestResult struct {
+	Exception string                `json:"exception,omitempty"`
+	Logs      []string              `json:"logs"`
+	Mutations []HandlerTestMutation `json:"mutations"`
+}
+
 type CompileStatus struct {
 	Area           string `json:"area"`
 	Column         int    `json:"column_number"`
@@ -250,9 +418,10 @@ type CompileStatus struct {
 // PlannerNodeVbMapping captures the vbucket distribution across all
 // eventing nodes as per planner
 type PlannerNodeVbMapping struct {
-	Hostname string `json:"host_name"`
-	StartVb  int    `json:"start_vb"`
-	VbsCount int    `json:"vb_count"`
+	Hostname    string `json:"host_name"`
+	ServerGroup string `json:"server_group,omitempty"`
+	StartVb     int    `json:"start_vb"`
+	VbsCount    int    `json:"vb_count"`
 }
 
 type HandlerConfig struct {
@@ -260,8 +429,14 @@ type HandlerConfig struct {
 	CheckpointInterval       int
 	IdleCheckpointInterval   int
 	CleanupTimers            bool
+	Constants                string
 	CPPWorkerThrCount        int
+	CPUShares                int
+	CurlCredentials          []CurlCredential
 	CurlTimeout              int64
+	DeadLetterBucket         string
+	DeadLetterKeyPrefix      string
+	EventFilter              *EventFilter
 	ExecuteTimerRoutineCount int
 	ExecutionTimeout         int
 	FeedbackBatchSize        int
@@ -271,6 +446,7 @@ type HandlerConfig struct {
 	HandlerFooters           []string
 	LcbInstCapacity          int
 	LogLevel                 string
+	MemoryLimit              int64
 	SocketWriteBatchSize     int
 	SocketTimeout            int
 	SourceBucket             string
@@ -281,6 +457,7 @@ type HandlerConfig struct {
 	TimerStorageChanSize     int
 	TimerQueueMemCap         uint64
 	TimerQueueSize           uint64
+	TimerResolution          int64
 	UndeployRoutineCount     int
 	UsingTimer               bool
 	WorkerCount              int
@@ -302,8 +479,11 @@ type ProcessConfig struct {
 }
 
 type RebalanceConfig struct {
+	PlannerWeighting                string
+	ServerGroupAwarePlanner         bool
 	VBOwnershipGiveUpRoutineCount   int
 	VBOwnershipTakeoverRoutineCount int
+	WorkerVbRebalanceInterval       int
 }
 
 type Key struct {
@@ -105,6 +105,20 @@ var gocbConnectMetaBucketCallback = func(args ...interface{}) error {
 	logging.Infof("%s [%s:%d] Successfully connected to metadata bucket %s connStr: %rs",
 		logPrefix, c.workerName, c.producer.LenRunningConsumers(), c.producer.MetadataBucket(), connStr)
 
+	if c.deadLetterBucket == "" {
+		return nil
+	}
+
+	c.gocbDeadLetterBucket, err = cluster.OpenBucket(c.deadLetterBucket, "")
+	if err != nil {
+		logging.Errorf("%s [%s:%d] Failed to connect to dead letter bucket %s, err: %v",
+			logPrefix, c.workerName, c.producer.LenRunningConsumers(), c.deadLetterBucket, err)
+		return err
+	}
+
+	logging.Infof("%s [%s:%d] Successfully connected to dead letter bucket %s",
+		logPrefix, c.workerName, c.producer.LenRunningConsumers(), c.deadLetterBucket)
+
 	return nil
 }
 
@@ -810,7 +824,7 @@ var startDCPFeedOpCallback = func(args ...interface{}) error {
 	}
 
 	dcpFeed, err := c.cbBucket.StartDcpFeedOver(
-		feedName, uint32(0), includeXATTRs, []string{kvHostPort}, 0xABCD, c.dcpConfig)
+		feedName, uint32(0), includeXATTRs|includeDeleteTimes, []string{kvHostPort}, 0xABCD, c.dcpConfig)
 
 	if err != nil {
 		logging.Errorf("%s [%s:%s:%d] Failed to start dcp feed for bucket: %v from kv node: %rs, err: %v",
@@ -886,7 +900,7 @@ var populateDcpFeedVbEntriesCallback = func(args ...interface{}) error {
 
 			var err error
 			feed, err = c.cbBucket.StartDcpFeedOver(
-				feedName, uint32(0), includeXATTRs, []string{kvHost}, 0xABCD, c.dcpConfig)
+				feedName, uint32(0), includeXATTRs|includeDeleteTimes, []string{kvHost}, 0xABCD, c.dcpConfig)
 			if err != nil {
 				logging.Errorf("%s [%s:%s:%d] Failed to start dcp feed, err: %v",
 					logPrefix, c.workerName, c.tcpPort, c.Pid(), err)
@@ -1093,3 +1107,49 @@ var checkIfReceivedTillEndSeqNoCallback = func(args ...interface{}) error {
 	dcpFeed.Close()
 	return nil
 }
+
+// writeDeadLetterEntry persists failed handler invocation to dead letter bucket and
+// records its doc id against vbucket level index, which is used during replay
+func (c *Consumer) writeDeadLetterEntry(msg *deadLetterMsg) error {
+	logPrefix := "Consumer::writeDeadLetterEntry"
+
+	if c.gocbDeadLetterBucket == nil {
+		logging.Errorf("%s [%s:%s:%d] vb: %d seqNo: %d dead letter bucket handle not initialized",
+			logPrefix, c.workerName, c.tcpPort, c.Pid(), msg.Vbucket, msg.SeqNo)
+		return fmt.Errorf("dead letter bucket handle not initialized")
+	}
+
+	entry := &common.DeadLetterEntry{
+		Cas:       msg.Meta.Cas,
+		Category:  msg.Category,
+		DocID:     msg.Meta.DocID,
+		Event:     msg.Event,
+		Exception: msg.Exception,
+		Function:  c.app.AppName,
+		SeqNo:     msg.SeqNo,
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+		Vbucket:   msg.Vbucket,
+	}
+
+	entryKey := common.DeadLetterEntryKey(c.deadLetterKeyPrefix, c.app.AppName, entry.DocID)
+	_, err := c.gocbDeadLetterBucket.Upsert(entryKey, entry, 0)
+	if err != nil {
+		logging.Errorf("%s [%s:%s:%d] vb: %d seqNo: %d Failed to write dead letter entry: %ru, err: %v",
+			logPrefix, c.workerName, c.tcpPort, c.Pid(), entry.Vbucket, entry.SeqNo, entryKey, err)
+		return err
+	}
+
+	indexKey := common.DeadLetterIndexKey(c.deadLetterKeyPrefix, c.app.AppName, entry.Vbucket)
+	_, err = c.gocbDeadLetterBucket.MutateInEx(indexKey, gocb.SubdocDocFlagMkDoc, 0, 0).
+		ArrayAppend("keys", entry.DocID, true).
+		Execute()
+	if err != nil {
+		logging.Errorf("%s [%s:%s:%d] vb: %d seqNo: %d Failed to update dead letter index: %s, err: %v",
+			logPrefix, c.workerName, c.tcpPort, c.Pid(), entry.Vbucket, entry.SeqNo, indexKey, err)
+		return err
+	}
+
+	logging.Tracef("%s [%s:%s:%d] vb: %d seqNo: %d Written dead letter entry: %ru",
+		logPrefix, c.workerName, c.tcpPort, c.Pid(), entry.Vbucket, entry.SeqNo, entryKey)
+	return nil
+}
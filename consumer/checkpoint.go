@@ -169,3 +169,34 @@ func (c *Consumer) updateBackupVbStats(vbno uint16) {
 	c.backupVbStats.updateVbStat(vbno, "sent_to_worker_counter", doctimerCount)
 	c.backupVbStats.updateVbStat(vbno, "processed_crontimer_counter", crontimerCount)
 }
+
+// CheckpointAndReleaseVbs writes final checkpoint for all vbuckets owned by the
+// consumer and gives up their ownership, so that DCP streams could later be
+// resumed from last processed seq no by whichever worker gets assigned the vbucket
+func (c *Consumer) CheckpointAndReleaseVbs() error {
+	logPrefix := "Consumer::CheckpointAndReleaseVbs"
+
+	// Prevents periodic checkpoint routine from reclaiming ownership of released vbuckets
+	if c.checkpointTicker != nil {
+		c.checkpointTicker.Stop()
+	}
+
+	vbsOwned := c.getCurrentlyOwnedVbs()
+	logging.Infof("%s [%s:%s:%d] Releasing vbs len: %d dump: %s",
+		logPrefix, c.workerName, c.tcpPort, c.Pid(), len(vbsOwned), util.Condense(vbsOwned))
+
+	for _, vb := range vbsOwned {
+		vbKey := fmt.Sprintf("%s::vb::%d", c.app.AppName, vb)
+
+		var vbBlob vbucketKVBlob
+		vbBlob.LastSeqNoProcessed = c.vbProcessingStats.getVbStat(vb, "last_processed_seq_no").(uint64)
+
+		err := c.updateCheckpoint(vbKey, vb, &vbBlob)
+		if err == common.ErrRetryTimeout {
+			logging.Errorf("%s [%s:%s:%d] Exiting due to timeout", logPrefix, c.workerName, c.tcpPort, c.Pid())
+			return common.ErrRetryTimeout
+		}
+	}
+
+	return nil
+}
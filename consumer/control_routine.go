@@ -4,7 +4,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"sort"
-	"time"
 
 	"github.com/couchbase/eventing/common"
 	"github.com/couchbase/eventing/logging"
@@ -100,6 +99,19 @@ func (c *Consumer) controlRoutine() error {
 				c.sendTimerContextSize(c.timerContextSize, false)
 			}
 
+			if constants, err := util.ConstantsToJSON(settings["constants"]); err != nil {
+				logging.Errorf("%s [%s:%s:%d] Failed to decode constants, err: %v",
+					logPrefix, c.workerName, c.tcpPort, c.Pid(), err)
+			} else if constants != c.constants {
+				c.constants = constants
+
+				// Empty object makes workers unbind constants dropped from settings
+				if constants == "" {
+					constants = "{}"
+				}
+				c.sendConstants(constants, false)
+			}
+
 			if val, ok := settings["vb_ownership_giveup_routine_count"]; ok {
 				c.vbOwnershipGiveUpRoutineCount = int(val.(float64))
 			}
@@ -189,28 +201,7 @@ func (c *Consumer) controlRoutine() error {
 					continue
 				}
 
-				logging.Infof("%s [%s:%s:%d] vb: %d Issuing dcp close stream", logPrefix, c.workerName, c.tcpPort, c.Pid(), vb)
-				c.dcpCloseStreamCounter++
-				c.RLock()
-				err := c.vbDcpFeedMap[vb].DcpCloseStream(vb, vb)
-				c.RUnlock()
-				if err != nil {
-					c.dcpCloseStreamErrCounter++
-					logging.Errorf("%s [%s:%s:%d] vb: %v Failed to close dcp stream, err: %v",
-						logPrefix, c.workerName, c.tcpPort, c.Pid(), vb, err)
-				} else {
-					logging.Infof("%s [%s:%s:%d] vb: %v Issued dcp close stream as current worker isn't supposed to own per plan",
-						logPrefix, c.workerName, c.tcpPort, c.Pid(), vb)
-				}
-
-				lastSeqNo := c.vbProcessingStats.getVbStat(uint16(vb), "last_read_seq_no").(uint64)
-				c.vbProcessingStats.updateVbStat(vb, "seq_no_after_close_stream", lastSeqNo)
-				c.vbProcessingStats.updateVbStat(vb, "timestamp", time.Now().Format(time.RFC3339))
-
-				var vbBlob vbucketKVBlob
-				vbKey := fmt.Sprintf("%s::vb::%d", c.app.AppName, vb)
-
-				err = c.updateCheckpoint(vbKey, vb, &vbBlob)
+				err := c.closeVbStream(vb)
 				if err == common.ErrRetryTimeout {
 					logging.Errorf("%s [%s:%s:%d] Exiting due to timeout", logPrefix, c.workerName, c.tcpPort, c.Pid())
 					return common.ErrRetryTimeout
@@ -305,7 +305,7 @@ func (c *Consumer) startDebugger(e *cb.DcpEvent) {
 
 	payload, pBuilder := c.makeV8InitPayload(c.app.AppName, c.debuggerPort,
 		currHost, c.eventingDir, c.eventingAdminPort, c.eventingSSLPort,
-		c.getKvNodes()[0], c.producer.CfgData(), c.lcbInstCapacity,
+		c.getKvNodes()[0], c.producer.CfgData(), c.constants, c.lcbInstCapacity,
 		c.executionTimeout, int(c.checkpointInterval.Nanoseconds()/(1000*1000)),
 		false, c.curlTimeout, c.timerContextSize)
 
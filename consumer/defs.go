@@ -31,6 +31,9 @@ const (
 	dcpDatatypeJSON      = uint8(1)
 	dcpDatatypeJSONXattr = uint8(5)
 	includeXATTRs        = uint32(4)
+
+	// Required by KV for enabling expiry opcode on DCP connection
+	includeDeleteTimes = uint32(0x20)
 )
 
 const (
@@ -60,6 +63,16 @@ const (
 	socketWriteTimerInterval = time.Duration(5000) * time.Millisecond
 
 	updateCPPStatsTickInterval = time.Duration(5000) * time.Millisecond
+
+	// Upper bound on time taken by worker spawned for handler test run
+	testWorkerTimeout = time.Duration(30) * time.Second
+
+	// Timers later than this many milliseconds are all counted in the last lateness bin
+	timerLatenessMaxBin = int64(60 * 60 * 1000)
+
+	// Vbuckets whose oldest overdue timer is late by more than this many timer scan
+	// intervals, i.e. timer resolutions, are flagged as lagging
+	timerLagThresholdScans = int64(10)
 )
 
 const (
@@ -92,6 +105,16 @@ type vbFlogEntry struct {
 	vb              uint16
 }
 
+// Failed handler invocation reported by eventing-consumer
+type deadLetterMsg struct {
+	Category  string      `json:"category"`
+	Event     string      `json:"event"`
+	Exception string      `json:"exception"`
+	Meta      dcpMetadata `json:"meta"`
+	SeqNo     uint64      `json:"seq_no"`
+	Vbucket   uint16      `json:"vb"`
+}
+
 type dcpMetadata struct {
 	Cas     uint64 `json:"cas"`
 	DocID   string `json:"id"`
@@ -99,6 +122,7 @@ type dcpMetadata struct {
 	Flag    uint32 `json:"flags"`
 	Vbucket uint16 `json:"vb"`
 	SeqNo   uint64 `json:"seq"`
+	Replay  bool   `json:"replay,omitempty"` // Dead lettered mutation sent once more
 }
 
 type vbSeqNo struct {
@@ -118,6 +142,9 @@ type Consumer struct {
 	handlerFooters []string
 	handlerHeaders []string
 
+	// Decrypted secrets of curl bindings, sent to eventing-consumer only in init payload
+	curlCredentials []common.CurlCredential
+
 	connMutex    *sync.RWMutex
 	conn         net.Conn // Access controlled by connMutex
 	feedbackConn net.Conn // Access controlled by connMutex
@@ -130,6 +157,7 @@ type Consumer struct {
 	workerQueueCap    int64
 	workerQueueMemCap int64
 
+	constants             string // JSON object of constant bindings exposed to handler, keyed by alias
 	cppThrPartitionMap    map[int][]uint16
 	cppWorkerThrCount     int // No. of worker threads per CPP worker process
 	crcTable              *crc32.Table
@@ -154,6 +182,8 @@ type Consumer struct {
 	dcpEventsRemaining            uint64
 	dcpFeedsClosed                bool
 	dcpFeedVbMap                  map[*couchbase.DcpFeed][]uint16 // Access controlled by default lock
+	deadLetterBucket              string                          // Bucket to persist failed handler invocations, empty if disabled
+	deadLetterKeyPrefix           string
 	debuggerPort                  string
 	eventingAdminPort             string
 	eventingDir                   string
@@ -161,11 +191,13 @@ type Consumer struct {
 	eventingNodeAddrs             []string
 	eventingNodeUUIDs             []string
 	executeTimerRoutineCount      int
+	eventFilter                   *eventFilter
 	executionTimeout              int
 	filterVbEvents                map[uint16]struct{} // Access controlled by filterVbEventsRWMutex
 	filterVbEventsRWMutex         *sync.RWMutex
 	filterDataCh                  chan *vbSeqNo
 	gocbBucket                    *gocb.Bucket
+	gocbDeadLetterBucket          *gocb.Bucket
 	gocbMetaBucket                *gocb.Bucket
 	idleCheckpointInterval        time.Duration
 	index                         int
@@ -183,12 +215,18 @@ type Consumer struct {
 	logLevel                      string
 	numVbuckets                   int
 	reqStreamCh                   chan *streamRequestInfo
+	sandbox                       bool // Bucket writes from handler are only recorded, used for test invocation
+	sandboxListener               net.Listener
+	sandboxPid                    int
 	statsTickDuration             time.Duration
 	stoppingConsumer              bool
 	superSup                      common.EventingSuperSup
+	testRequestID                 uint64                    // Access controlled by statsRWMutex
+	testResult                    *common.HandlerTestResult // Access controlled by statsRWMutex
 	timerContextSize              int64
 	timerStorageChanSize          int
 	timerQueueSize                uint64
+	timerResolution               int64
 	timerQueueMemCap              uint64
 	timerStorageMetaChsRWMutex    *sync.RWMutex
 	timerStorageRoutineCount      int
@@ -215,11 +253,12 @@ type Consumer struct {
 	workerVbucketMap              map[string][]uint16 // Access controlled by workerVbucketMapRWMutex
 	workerVbucketMapRWMutex       *sync.RWMutex
 
-	executionStats    map[string]interface{} // Access controlled by statsRWMutex
-	failureStats      map[string]interface{} // Access controlled by statsRWMutex
-	latencyStats      map[string]uint64      // Access controlled by statsRWMutex
-	lcbExceptionStats map[string]uint64      // Access controlled by statsRWMutex
-	statsRWMutex      *sync.RWMutex
+	executionStats     map[string]interface{} // Access controlled by statsRWMutex
+	failureStats       map[string]interface{} // Access controlled by statsRWMutex
+	latencyStats       map[string]uint64      // Access controlled by statsRWMutex
+	lcbExceptionStats  map[string]uint64      // Access controlled by statsRWMutex
+	timerLatenessStats map[string]uint64      // Access controlled by statsRWMutex
+	statsRWMutex       *sync.RWMutex
 
 	// Time when last response from CPP worker was received on main loop
 	workerRespMainLoopTs        atomic.Value
@@ -334,22 +373,29 @@ type Consumer struct {
 	timerMessagesProcessed      uint64
 
 	// DCP and timer related counters
-	timerResponsesRecieved     uint64
-	aggMessagesSentCounter     uint64
-	dcpDeletionCounter         uint64
-	dcpMutationCounter         uint64
-	errorParsingTimerResponses uint64
-	timerMessagesProcessedPSec int
+	timerResponsesRecieved       uint64
+	aggMessagesSentCounter       uint64
+	dcpDeletionCounter           uint64
+	dcpDeletionFilteredCounter   uint64
+	dcpExpirationCounter         uint64
+	dcpExpirationFilteredCounter uint64
+	dcpMutationCounter           uint64
+	dcpMutationFilteredCounter   uint64
+	errorParsingTimerResponses   uint64
+	deadLetterEntriesWritten     uint64
+	deadLetterWriteFailures      uint64
+	timerMessagesProcessedPSec   int
 
 	// metastore related timer stats
-	metastoreDeleteCounter      uint64
-	metastoreDeleteErrCounter   uint64
-	metastoreNotFoundErrCounter uint64
-	metastoreScanCounter        uint64
-	metastoreScanDueCounter     uint64
-	metastoreScanErrCounter     uint64
-	metastoreSetCounter         uint64
-	metastoreSetErrCounter      uint64
+	metastoreDeleteCounter        uint64
+	metastoreDeleteErrCounter     uint64
+	metastoreNotFoundErrCounter   uint64
+	metastoreRescheduleErrCounter uint64
+	metastoreScanCounter          uint64
+	metastoreScanDueCounter       uint64
+	metastoreScanErrCounter       uint64
+	metastoreSetCounter           uint64
+	metastoreSetErrCounter        uint64
 
 	// capture dcp operation stats, granularity of these stats depend on statsTickInterval
 	dcpOpsProcessed     uint64
@@ -471,11 +517,15 @@ type TimerInfo struct {
 	Callback  string `json:"callback"`
 	Reference string `json:"reference"`
 	Context   string `json:"context"`
+
+	// Set for recurring timers, see timers.Schedule
+	Cron     string `json:"cron,omitempty"`
+	Interval int64  `json:"interval,omitempty"`
 }
 
 func (info *TimerInfo) Size() uint64 {
 	return uint64(unsafe.Sizeof(*info)) + uint64(len(info.Callback)) +
-		uint64(len(info.Reference)) + uint64(len(info.Context))
+		uint64(len(info.Reference)) + uint64(len(info.Context)) + uint64(len(info.Cron))
 }
 
 // This is struct that will be stored in
@@ -483,17 +533,24 @@ func (info *TimerInfo) Size() uint64 {
 type timerContext struct {
 	Callback  string `json:"callback"`
 	Vb        uint64 `json:"vb"`
-	Context   string `json:"context"` // This is the context provided by the user
+	Context   string `json:"context"`             // This is the context provided by the user
+	Reference string `json:"reference,omitempty"` // Reference provided by the user, to cancel timer by
 	reference string
 }
 
 func (ctx *timerContext) Size() uint64 {
-	return uint64(unsafe.Sizeof(*ctx)) + uint64(len(ctx.Callback)) + uint64(len(ctx.Context))
+	return uint64(unsafe.Sizeof(*ctx)) + uint64(len(ctx.Callback)) + uint64(len(ctx.Context)) +
+		uint64(len(ctx.Reference))
 }
 
 type TimerEvent struct {
 	Context *timerContext
 	Token   *timers.DeleteToken
+	Due     int64
+
+	// Store the timer was scanned from, to account it as fired once sent to the worker
+	store *timers.TimerStore
+	entry *timers.TimerEntry
 }
 
 func (e *TimerEvent) Size() uint64 {
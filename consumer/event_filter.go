@@ -0,0 +1,189 @@
+package consumer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/couchbase/eventing/common"
+	mcd "github.com/couchbase/eventing/dcp/transport"
+	cb "github.com/couchbase/eventing/dcp/transport/client"
+)
+
+type filterPredicate struct {
+	path   []string
+	op     string
+	values []interface{}
+}
+
+// eventFilter is the compiled form of filter supplied in deployment config
+type eventFilter struct {
+	keyPrefix  []byte
+	keyRegex   *regexp.Regexp
+	predicates []filterPredicate
+}
+
+func newEventFilter(f *common.EventFilter) (*eventFilter, error) {
+	if f == nil {
+		return nil, nil
+	}
+
+	filter := &eventFilter{keyPrefix: []byte(f.KeyPrefix)}
+
+	if f.KeyRegex != "" {
+		re, err := regexp.Compile(f.KeyRegex)
+		if err != nil {
+			return nil, err
+		}
+		filter.keyRegex = re
+	}
+
+	for _, p := range f.Predicates {
+		predicate := filterPredicate{
+			path: strings.Split(p.Field, "."),
+			op:   p.Op,
+		}
+
+		for _, encoded := range p.Values {
+			var value interface{}
+			err := json.Unmarshal([]byte(encoded), &value)
+			if err != nil {
+				return nil, fmt.Errorf("field: %s has invalid value: %s, err: %v", p.Field, encoded, err)
+			}
+			predicate.values = append(predicate.values, value)
+		}
+
+		filter.predicates = append(filter.predicates, predicate)
+	}
+
+	return filter, nil
+}
+
+func (f *eventFilter) matchesKey(key []byte) bool {
+	if !bytes.HasPrefix(key, f.keyPrefix) {
+		return false
+	}
+
+	return f.keyRegex == nil || f.keyRegex.Match(key)
+}
+
+func (f *eventFilter) matchesDoc(value []byte) bool {
+	if len(f.predicates) == 0 {
+		return true
+	}
+
+	var doc interface{}
+	if err := json.Unmarshal(value, &doc); err != nil {
+		return false
+	}
+
+	for _, predicate := range f.predicates {
+		field, found := lookupField(doc, predicate.path)
+
+		switch predicate.op {
+		case common.FilterOpExists:
+			if !found {
+				return false
+			}
+
+		case common.FilterOpEquals, common.FilterOpIn:
+			if !found || !containsValue(predicate.values, field) {
+				return false
+			}
+
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+func lookupField(doc interface{}, path []string) (interface{}, bool) {
+	for _, name := range path {
+		obj, ok := doc.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+
+		doc, ok = obj[name]
+		if !ok {
+			return nil, false
+		}
+	}
+	return doc, true
+}
+
+func containsValue(values []interface{}, field interface{}) bool {
+	for _, value := range values {
+		if reflect.DeepEqual(value, field) {
+			return true
+		}
+	}
+	return false
+}
+
+// dcpEventBody returns document body with extended attributes stripped off
+func dcpEventBody(e *cb.DcpEvent) []byte {
+	if e.Datatype != dcpDatatypeJSONXattr || len(e.Value) < 4 {
+		return e.Value
+	}
+
+	totalXattrLen := binary.BigEndian.Uint32(e.Value[0:])
+	if uint32(len(e.Value)) < 4+totalXattrLen {
+		return nil
+	}
+	return e.Value[4+totalXattrLen:]
+}
+
+// filterEvent reports if event has to be dropped as per deployment config filter.
+// Deletions and expirations carry no document body, hence only key conditions apply to them.
+func (c *Consumer) filterEvent(e *cb.DcpEvent) bool {
+	if c.eventFilter == nil {
+		return false
+	}
+
+	if c.eventFilter.matchesKey(e.Key) && (e.Opcode != mcd.DCP_MUTATION || c.eventFilter.matchesDoc(dcpEventBody(e))) {
+		return false
+	}
+
+	switch e.Opcode {
+	case mcd.DCP_MUTATION:
+		c.dcpMutationFilteredCounter++
+	case mcd.DCP_DELETION:
+		c.dcpDeletionFilteredCounter++
+	case mcd.DCP_EXPIRATION:
+		c.dcpExpirationFilteredCounter++
+	}
+
+	c.updateFilteredSeqNo(e.VBucket, e.Seqno)
+	return true
+}
+
+// updateFilteredSeqNo accounts seqno of filtered event towards checkpoint. It's applied right away only
+// if eventing-consumer has processed everything sent on the vbucket, else checkpoint could move past
+// events yet to be processed. Otherwise it's applied once eventing-consumer catches up.
+func (c *Consumer) updateFilteredSeqNo(vb uint16, seqNo uint64) {
+	lastSentSeqNo := c.vbProcessingStats.getVbStat(vb, "last_sent_seq_no").(uint64)
+	lastProcessedSeqNo := c.vbProcessingStats.getVbStat(vb, "last_processed_seq_no").(uint64)
+
+	if lastProcessedSeqNo >= lastSentSeqNo {
+		c.vbProcessingStats.updateVbStat(vb, "last_processed_seq_no", seqNo)
+		return
+	}
+
+	c.vbProcessingStats.updateVbStat(vb, "last_filtered_seq_no", seqNo)
+}
+
+func (c *Consumer) applyFilteredSeqNo(vb uint16, processedSeqNo uint64) {
+	lastSentSeqNo := c.vbProcessingStats.getVbStat(vb, "last_sent_seq_no").(uint64)
+	lastFilteredSeqNo := c.vbProcessingStats.getVbStat(vb, "last_filtered_seq_no").(uint64)
+
+	if processedSeqNo >= lastSentSeqNo && lastFilteredSeqNo > processedSeqNo {
+		c.vbProcessingStats.updateVbStat(vb, "last_processed_seq_no", lastFilteredSeqNo)
+	}
+}
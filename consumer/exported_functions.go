@@ -2,10 +2,12 @@ package consumer
 
 import (
 	"bufio"
+	"encoding/json"
 	"fmt"
 	"net"
 	"os"
 	"os/exec"
+	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -79,6 +81,22 @@ func (c *Consumer) GetEventProcessingStats() map[string]uint64 {
 		stats["dcp_mutation_sent_to_worker"] = c.dcpMutationCounter
 	}
 
+	if c.dcpExpirationCounter > 0 {
+		stats["dcp_expiration_sent_to_worker"] = c.dcpExpirationCounter
+	}
+
+	if c.dcpDeletionFilteredCounter > 0 {
+		stats["dcp_deletion_filtered"] = c.dcpDeletionFilteredCounter
+	}
+
+	if c.dcpExpirationFilteredCounter > 0 {
+		stats["dcp_expiration_filtered"] = c.dcpExpirationFilteredCounter
+	}
+
+	if c.dcpMutationFilteredCounter > 0 {
+		stats["dcp_mutation_filtered"] = c.dcpMutationFilteredCounter
+	}
+
 	if c.dcpCloseStreamCounter > 0 {
 		stats["dcp_stream_close_counter"] = c.dcpCloseStreamCounter
 	}
@@ -107,6 +125,14 @@ func (c *Consumer) GetEventProcessingStats() map[string]uint64 {
 		stats["error_parsing_timer_response"] = c.errorParsingTimerResponses
 	}
 
+	if c.deadLetterEntriesWritten > 0 {
+		stats["dead_letter_entries_written"] = c.deadLetterEntriesWritten
+	}
+
+	if c.deadLetterWriteFailures > 0 {
+		stats["dead_letter_write_failures"] = c.deadLetterWriteFailures
+	}
+
 	if c.isBootstrapping {
 		stats["is_bootstrapping"] = 1
 	}
@@ -244,6 +270,7 @@ func (c *Consumer) GetMetaStoreStats() map[string]uint64 {
 	stats["metastore_deletes"] = atomic.LoadUint64(&c.metastoreDeleteCounter)
 	stats["metastore_delete_err"] = atomic.LoadUint64(&c.metastoreDeleteErrCounter)
 	stats["metastore_not_found"] = atomic.LoadUint64(&c.metastoreNotFoundErrCounter)
+	stats["metastore_reschedule_err"] = atomic.LoadUint64(&c.metastoreRescheduleErrCounter)
 	stats["metastore_scan"] = atomic.LoadUint64(&c.metastoreScanCounter)
 	stats["metastore_scan_due"] = atomic.LoadUint64(&c.metastoreScanDueCounter)
 	stats["metastore_scan_err"] = atomic.LoadUint64(&c.metastoreScanErrCounter)
@@ -372,6 +399,21 @@ func (c *Consumer) GetLatencyStats() map[string]uint64 {
 	return latencyStats
 }
 
+// GetTimerLatenessStats returns histogram of how late timers fired relative to their due time,
+// keyed by upper bound of each bin in milliseconds
+func (c *Consumer) GetTimerLatenessStats() map[string]uint64 {
+	c.statsRWMutex.RLock()
+	defer c.statsRWMutex.RUnlock()
+
+	latenessStats := make(map[string]uint64)
+
+	for k, v := range c.timerLatenessStats {
+		latenessStats[k] = v
+	}
+
+	return latenessStats
+}
+
 // GetExecutionStats returns OnUpdate/OnDelete success/failure stats for event handlers from cpp world
 func (c *Consumer) GetExecutionStats() map[string]interface{} {
 	c.statsRWMutex.RLock()
@@ -421,15 +463,16 @@ func (c *Consumer) GetLcbExceptionsStats() map[string]uint64 {
 	return lcbExceptionStats
 }
 
-// SpawnCompilationWorker bring up a CPP worker to compile the user supplied handler code
-func (c *Consumer) SpawnCompilationWorker(appCode, appContent, appName, eventingPort string, handlerHeaders, handlerFooters []string) (*common.CompileStatus, error) {
-	logPrefix := "Consumer::SpawnCompilationWorker"
+// spawnThrowawayWorker launches a short lived CPP worker, which isn't part of function's
+// worker pool, and waits for it to connect back
+func (c *Consumer) spawnThrowawayWorker(appName, tag string) (net.Listener, int, error) {
+	logPrefix := "Consumer::spawnThrowawayWorker"
 
 	listener, err := net.Listen("tcp", net.JoinHostPort(util.Localhost(), "0"))
 	if err != nil {
-		logging.Errorf("%s [%s:%s:%d] Compilation worker: Failed to listen on tcp port, err: %v",
-			logPrefix, c.workerName, c.tcpPort, c.Pid(), err)
-		return nil, err
+		logging.Errorf("%s [%s:%s:%d] %s worker: Failed to listen on tcp port, err: %v",
+			logPrefix, c.workerName, c.tcpPort, c.Pid(), tag, err)
+		return nil, 0, err
 	}
 
 	connectedCh := make(chan struct{}, 1)
@@ -440,13 +483,13 @@ func (c *Consumer) SpawnCompilationWorker(appCode, appContent, appName, eventing
 		var err error
 		c.conn, err = listener.Accept()
 		if err != nil {
-			logging.Errorf("%s [%s:%s:%d] Compilation worker: Error on accept, err: %v",
-				logPrefix, c.workerName, c.tcpPort, c.Pid(), err)
+			logging.Errorf("%s [%s:%s:%d] %s worker: Error on accept, err: %v",
+				logPrefix, c.workerName, c.tcpPort, c.Pid(), tag, err)
 			return
 		}
 
-		logging.Infof("%s [%s:%s:%d] Compilation worker: got connection: %rs",
-			logPrefix, c.workerName, c.tcpPort, c.Pid(), c.conn)
+		logging.Infof("%s [%s:%s:%d] %s worker: got connection: %rs",
+			logPrefix, c.workerName, c.tcpPort, c.Pid(), tag, c.conn)
 
 		connectedCh <- struct{}{}
 	}(listener, connectedCh)
@@ -472,7 +515,7 @@ func (c *Consumer) SpawnCompilationWorker(appCode, appContent, appName, eventing
 			os.TempDir(),
 			util.GetIPMode(),
 			"true",
-			"validate") // this parameter is not read, for tagging
+			tag) // this parameter is not read, for tagging
 
 		cmd.Env = append(os.Environ(),
 			fmt.Sprintf("CBEVT_CALLBACK_USR=%s", user),
@@ -503,13 +546,13 @@ func (c *Consumer) SpawnCompilationWorker(appCode, appContent, appName, eventing
 
 		err = cmd.Start()
 		if err != nil {
-			logging.Errorf("%s [%s:%s:%d] Failed to spawn compilation worker, err: %v",
-				logPrefix, c.workerName, c.tcpPort, c.Pid(), err)
+			logging.Errorf("%s [%s:%s:%d] Failed to spawn %s worker, err: %v",
+				logPrefix, c.workerName, c.tcpPort, c.Pid(), tag, err)
 			return
 		}
 		pid = cmd.Process.Pid
-		logging.Infof("%s [%s:%s:%d] compilation worker launched",
-			logPrefix, c.workerName, c.tcpPort, pid)
+		logging.Infof("%s [%s:%s:%d] %s worker launched",
+			logPrefix, c.workerName, c.tcpPort, pid, tag)
 
 		bufErr := bufio.NewReader(errPipe)
 		go func(bufErr *bufio.Reader) {
@@ -543,13 +586,25 @@ func (c *Consumer) SpawnCompilationWorker(appCode, appContent, appName, eventing
 
 		err = cmd.Wait()
 
-		logging.Infof("%s [%s:%s:%d] compilation worker exited with status %v",
-			logPrefix, c.workerName, c.tcpPort, pid, err)
+		logging.Infof("%s [%s:%s:%d] %s worker exited with status %v",
+			logPrefix, c.workerName, c.tcpPort, pid, tag, err)
 
 	}()
 	<-connectedCh
 	c.sockReader = bufio.NewReader(c.conn)
 
+	return listener, pid, nil
+}
+
+// SpawnCompilationWorker bring up a CPP worker to compile the user supplied handler code
+func (c *Consumer) SpawnCompilationWorker(appCode, appContent, appName, eventingPort string, handlerHeaders, handlerFooters []string) (*common.CompileStatus, error) {
+	logPrefix := "Consumer::SpawnCompilationWorker"
+
+	listener, pid, err := c.spawnThrowawayWorker(appName, "validate")
+	if err != nil {
+		return nil, err
+	}
+
 	c.sendWorkerThrCount(1, false)
 	logging.Infof("%s [%s:%s:%d] Handler headers %v", logPrefix, c.workerName, c.tcpPort, pid, c.handlerHeaders)
 	logging.Infof("%s [%s:%s:%d] Handler footers %v", logPrefix, c.workerName, c.tcpPort, pid, c.handlerFooters)
@@ -558,7 +613,7 @@ func (c *Consumer) SpawnCompilationWorker(appCode, appContent, appName, eventing
 	c.handlerFooters = handlerFooters
 	// Framing bare minimum V8 worker init payload
 	payload, pBuilder := c.makeV8InitPayload(appName, c.debuggerPort, util.Localhost(), "", eventingPort, "",
-		"", appContent, 5, 10, 10*1000, true, 500, 1024)
+		"", appContent, "", 5, 10, 10*1000, true, 500, 1024)
 
 	c.sendInitV8Worker(payload, false, pBuilder)
 
@@ -585,6 +640,214 @@ func (c *Consumer) SpawnCompilationWorker(appCode, appContent, appName, eventing
 	return c.compileInfo, nil
 }
 
+// SpawnTestWorker brings up a CPP worker to run the user supplied handler code against a
+// single document. Bucket writes made by handler are recorded by worker instead of being persisted.
+func (c *Consumer) SpawnTestWorker(appCode, appContent, appName, eventingPort string, handlerHeaders, handlerFooters []string,
+	req *common.HandlerTestRequest) (*common.HandlerTestResult, error) {
+
+	err := c.StartSandboxWorker(appCode, appContent, appName, eventingPort, handlerHeaders, handlerFooters)
+	if err != nil {
+		return nil, err
+	}
+	defer c.StopSandboxWorker()
+
+	return c.RunSandboxEvent(req)
+}
+
+// StartSandboxWorker brings up a CPP worker that runs the user supplied handler code outside of
+// regular event flow. Bucket bindings are served from worker's in-memory store, which retains
+// writes across events until worker is stopped.
+func (c *Consumer) StartSandboxWorker(appCode, appContent, appName, eventingPort string, handlerHeaders, handlerFooters []string) error {
+	c.sandbox = true
+	listener, pid, err := c.spawnThrowawayWorker(appName, "test")
+	if err != nil {
+		return err
+	}
+
+	c.sandboxListener = listener
+	c.sandboxPid = pid
+
+	c.sendWorkerThrCount(1, false)
+
+	c.handlerHeaders = handlerHeaders
+	c.handlerFooters = handlerFooters
+	// Framing bare minimum V8 worker init payload, lcb bootstrap is skipped as
+	// bucket bindings are served by the sandbox. Constants are picked from appContent
+	payload, pBuilder := c.makeV8InitPayload(appName, c.debuggerPort, util.Localhost(), "", eventingPort, "",
+		"", appContent, "", 5, 10, 10*1000, true, 500, 1024)
+
+	c.sendInitV8Worker(payload, false, pBuilder)
+	c.sendLoadV8Worker(appCode, false)
+
+	go c.readMessageLoop()
+
+	return nil
+}
+
+// StopSandboxWorker tears down worker brought up by StartSandboxWorker
+func (c *Consumer) StopSandboxWorker() {
+	logPrefix := "Consumer::StopSandboxWorker"
+
+	c.conn.Close()
+	c.sandboxListener.Close()
+
+	err := util.KillProcess(c.sandboxPid)
+	if err != nil {
+		logging.Errorf("%s [%s:%s:%d] Unable to kill C++ worker spawned for testing, err: %v",
+			logPrefix, c.workerName, c.tcpPort, c.sandboxPid, err)
+	}
+}
+
+// RunSandboxEvent invokes handler running in sandbox worker against a single document
+func (c *Consumer) RunSandboxEvent(req *common.HandlerTestRequest) (*common.HandlerTestResult, error) {
+	logPrefix := "Consumer::RunSandboxEvent"
+
+	meta := dcpMetadata{
+		DocID:   req.Key,
+		Vbucket: util.VbucketByKey([]byte(req.Key), util.GetMaxVbuckets()),
+		SeqNo:   1,
+	}
+
+	if req.Vbucket != nil {
+		if int(*req.Vbucket) >= util.GetMaxVbuckets() {
+			return nil, fmt.Errorf("vbucket: %d is out of range, vbucket count: %d", *req.Vbucket, util.GetMaxVbuckets())
+		}
+		meta.Vbucket = *req.Vbucket
+	}
+
+	if req.SeqNo > 0 {
+		meta.SeqNo = req.SeqNo
+	}
+
+	metadata, err := json.Marshal(&meta)
+	if err != nil {
+		return nil, err
+	}
+
+	// Event is framed and routed by worker as a regular DCP event would be
+	partition := int16(util.VbucketByKey([]byte(req.Key), cppWorkerPartitionCount))
+
+	var dcpHeader []byte
+	var hBuilder *flatbuffers.Builder
+	var value []byte
+
+	switch req.Op {
+	case common.HandlerTestOpDelete:
+		dcpHeader, hBuilder = c.makeDcpDeletionHeader(partition, string(metadata))
+	case common.HandlerTestOpExpire:
+		dcpHeader, hBuilder = c.makeDcpExpirationHeader(partition, string(metadata))
+	default:
+		dcpHeader, hBuilder = c.makeDcpMutationHeader(partition, string(metadata))
+		value = req.Value
+		if len(value) == 0 {
+			value = []byte("null")
+		}
+	}
+
+	dcpPayload, pBuilder := c.makeDcpPayload([]byte(req.Key), value)
+
+	c.statsRWMutex.Lock()
+	c.testRequestID++
+	requestID := c.testRequestID
+	c.testResult = nil
+	c.statsRWMutex.Unlock()
+
+	c.sendMessage(&msgToTransmit{
+		msg: &message{
+			Header:  dcpHeader,
+			Payload: dcpPayload,
+		},
+		prioritize:     true,
+		headerBuilder:  hBuilder,
+		payloadBuilder: pBuilder,
+	})
+	c.sendTestRequest(strconv.FormatUint(requestID, 10))
+
+	for start := time.Now(); ; time.Sleep(100 * time.Millisecond) {
+		c.statsRWMutex.RLock()
+		result := c.testResult
+		c.statsRWMutex.RUnlock()
+
+		if result != nil {
+			logging.Infof("%s [%s:%s:%d] test run finished, mutations: %d logs: %d exception: %ru",
+				logPrefix, c.workerName, c.tcpPort, c.sandboxPid, len(result.Mutations), len(result.Logs),
+				result.Exception)
+			return result, nil
+		}
+
+		if time.Since(start) > testWorkerTimeout {
+			logging.Errorf("%s [%s:%s:%d] Test worker didn't respond within %v",
+				logPrefix, c.workerName, c.tcpPort, c.sandboxPid, testWorkerTimeout)
+			return nil, fmt.Errorf("handler test run didn't finish within %v", testWorkerTimeout)
+		}
+	}
+}
+
+// OwnsVb reports if consumer is currently streaming the vbucket
+func (c *Consumer) OwnsVb(vb uint16) bool {
+	return c.checkIfVbAlreadyOwnedByCurrConsumer(vb)
+}
+
+// ReplayMutation sends a dead lettered mutation to the worker once more. Event is marked as
+// replayed, so that worker neither takes it for a write of handler itself nor moves progress
+// of the vbucket back to seqno of the original mutation.
+func (c *Consumer) ReplayMutation(vb uint16, docID string, value []byte, cas, seqNo uint64) error {
+	if !c.checkIfVbAlreadyOwnedByCurrConsumer(vb) {
+		return fmt.Errorf("vb: %d isn't owned by worker: %s", vb, c.workerName)
+	}
+
+	metadata, err := json.Marshal(&dcpMetadata{
+		Cas:     cas,
+		DocID:   docID,
+		Vbucket: vb,
+		SeqNo:   seqNo,
+		Replay:  true,
+	})
+	if err != nil {
+		return err
+	}
+
+	partition := int16(util.VbucketByKey([]byte(docID), cppWorkerPartitionCount))
+	dcpHeader, hBuilder := c.makeDcpMutationHeader(partition, string(metadata))
+	dcpPayload, pBuilder := c.makeDcpPayload([]byte(docID), value)
+
+	c.sendMessage(&msgToTransmit{
+		msg: &message{
+			Header:  dcpHeader,
+			Payload: dcpPayload,
+		},
+		prioritize:     false,
+		headerBuilder:  hBuilder,
+		payloadBuilder: pBuilder,
+	})
+	return nil
+}
+
+// SandboxWorkerStats fetches execution and failure stats from sandbox worker
+func (c *Consumer) SandboxWorkerStats() (executionStats, failureStats map[string]interface{}, err error) {
+	c.statsRWMutex.Lock()
+	c.executionStats = nil
+	c.failureStats = nil
+	c.statsRWMutex.Unlock()
+
+	c.sendGetExecutionStats(false)
+	c.sendGetFailureStats(false)
+
+	for start := time.Now(); ; time.Sleep(100 * time.Millisecond) {
+		c.statsRWMutex.RLock()
+		received := c.executionStats != nil && c.failureStats != nil
+		c.statsRWMutex.RUnlock()
+
+		if received {
+			return c.GetExecutionStats(), c.GetFailureStats(), nil
+		}
+
+		if time.Since(start) > testWorkerTimeout {
+			return nil, nil, fmt.Errorf("stats weren't received within %v", testWorkerTimeout)
+		}
+	}
+}
+
 func (c *Consumer) initConsumer(appName string) {
 	c.executionTimeout = 10000
 	c.lcbInstCapacity = 1
@@ -596,6 +859,7 @@ func (c *Consumer) initConsumer(appName string) {
 	c.connMutex = &sync.RWMutex{}
 	c.msgProcessedRWMutex = &sync.RWMutex{}
 	c.sendMsgBufferRWMutex = &sync.RWMutex{}
+	c.statsRWMutex = &sync.RWMutex{}
 	c.app = &common.AppConfig{AppName: appName}
 	c.socketTimeout = 1 * time.Second
 
@@ -645,6 +909,26 @@ func (c *Consumer) TimerDebugStats() map[int]map[string]interface{} {
 		}
 	}
 
+	if !c.usingTimer {
+		return stats
+	}
+
+	// Backlog gauges are only known to the worker owning the vbucket
+	for _, vb := range c.getCurrentlyOwnedVbs() {
+		store, found := timers.Fetch(c.producer.GetMetadataPrefix(), int(vb))
+		if !found {
+			continue
+		}
+
+		backlog := store.BacklogStats()
+		stats[int(vb)]["timer_pending_alarms"] = backlog["pending_alarms"]
+		stats[int(vb)]["timer_overdue_alarms"] = backlog["overdue_alarms"]
+		stats[int(vb)]["timer_oldest_overdue"] = backlog["oldest_overdue"]
+		stats[int(vb)]["timer_fire_lag"] = backlog["fire_lag"]
+		stats[int(vb)]["timer_lag"] = backlog["lag"]
+		stats[int(vb)]["timer_lagging"] = backlog["lag"] > timerLagThresholdScans*store.Resolution()
+	}
+
 	return stats
 }
 
@@ -719,6 +1003,39 @@ func (c *Consumer) WorkerVbMapUpdate(workerVbucketMap map[string][]uint16) {
 	}
 }
 
+// GiveUpVbs closes dcp streams of given vbuckets that have been reassigned to another local
+// worker and checkpoints them, leaving streams of other vbuckets undisturbed
+func (c *Consumer) GiveUpVbs(vbs []uint16) error {
+	logPrefix := "Consumer::GiveUpVbs"
+
+	for _, vb := range vbs {
+		if !c.checkIfVbAlreadyOwnedByCurrConsumer(vb) || c.checkIfCurrentConsumerShouldOwnVb(vb) {
+			continue
+		}
+
+		err := c.closeVbStream(vb)
+		if err == common.ErrRetryTimeout {
+			logging.Errorf("%s [%s:%s:%d] Exiting due to timeout", logPrefix, c.workerName, c.tcpPort, c.Pid())
+			return err
+		}
+	}
+
+	return nil
+}
+
+// TakeOverVbs queues given vbuckets, reassigned from another local worker, for the
+// control routine to request their dcp streams
+func (c *Consumer) TakeOverVbs(vbs []uint16) {
+	c.Lock()
+	defer c.Unlock()
+
+	for _, vb := range vbs {
+		if !util.Contains(vb, c.vbsRemainingToRestream) {
+			c.vbsRemainingToRestream = append(c.vbsRemainingToRestream, vb)
+		}
+	}
+}
+
 func (c *Consumer) getAssignedVbs(workerName string) ([]uint16, error) {
 	c.workerVbucketMapRWMutex.RLock()
 	defer c.workerVbucketMapRWMutex.RUnlock()
@@ -67,6 +67,33 @@ func (c *Consumer) sendTimerContextSize(timerContextSize int64, sendToDebugger b
 	c.sendMessage(m)
 }
 
+func (c *Consumer) sendConstants(constants string, sendToDebugger bool) {
+	logPrefix := "Consumer::sendConstants"
+
+	header, hBuilder := c.makeConstantsHeader(constants)
+
+	c.msgProcessedRWMutex.Lock()
+	if _, ok := c.v8WorkerMessagesProcessed["constants"]; !ok {
+		c.v8WorkerMessagesProcessed["constants"] = 0
+	}
+	c.v8WorkerMessagesProcessed["constants"]++
+	c.msgProcessedRWMutex.Unlock()
+
+	m := &msgToTransmit{
+		msg: &message{
+			Header: header,
+		},
+		sendToDebugger: sendToDebugger,
+		prioritize:     true,
+		headerBuilder:  hBuilder,
+	}
+
+	logging.Infof("%s [%s:%s:%d] Sending constants: %ru",
+		logPrefix, c.workerName, c.tcpPort, c.Pid(), constants)
+
+	c.sendMessage(m)
+}
+
 func (c *Consumer) sendWorkerThrCount(thrCount int, sendToDebugger bool) {
 	var header []byte
 	var hBuilder *flatbuffers.Builder
@@ -220,6 +247,28 @@ func (c *Consumer) sendCompileRequest(appCode string) {
 	c.sendMessage(m)
 }
 
+func (c *Consumer) sendTestRequest(request string) {
+	header, hBuilder := c.makeV8TestRunOpcodeHeader(request)
+
+	c.msgProcessedRWMutex.Lock()
+	if _, ok := c.v8WorkerMessagesProcessed["v8_test"]; !ok {
+		c.v8WorkerMessagesProcessed["v8_test"] = 0
+	}
+	c.v8WorkerMessagesProcessed["v8_test"]++
+	c.msgProcessedRWMutex.Unlock()
+
+	m := &msgToTransmit{
+		msg: &message{
+			Header: header,
+		},
+		sendToDebugger: false,
+		prioritize:     true,
+		headerBuilder:  hBuilder,
+	}
+
+	c.sendMessage(m)
+}
+
 func (c *Consumer) sendLoadV8Worker(appCode string, sendToDebugger bool) {
 
 	header, hBuilder := c.makeV8LoadOpcodeHeader(appCode)
@@ -378,7 +427,12 @@ func (c *Consumer) sendDcpEvent(e *memcached.DcpEvent, sendToDebugger bool) {
 		dcpHeader, hBuilder = c.makeDcpDeletionHeader(partition, string(metadata))
 	}
 
+	if e.Opcode == mcd.DCP_EXPIRATION {
+		dcpHeader, hBuilder = c.makeDcpExpirationHeader(partition, string(metadata))
+	}
+
 	dcpPayload, pBuilder := c.makeDcpPayload(e.Key, e.Value)
+	c.vbProcessingStats.updateVbStat(e.VBucket, "last_sent_seq_no", e.Seqno)
 
 	msg := &msgToTransmit{
 		msg: &message{
@@ -70,6 +70,10 @@ func (c *Consumer) processEvents() {
 				logging.Tracef("%s [%s:%s:%d] Got DCP_MUTATION for key: %ru datatype: %v",
 					logPrefix, c.workerName, c.tcpPort, c.Pid(), string(e.Key), e.Datatype)
 
+				if c.filterEvent(e) {
+					continue
+				}
+
 				switch e.Datatype {
 				case dcpDatatypeJSON:
 					c.dcpMutationCounter++
@@ -149,9 +153,31 @@ func (c *Consumer) processEvents() {
 				c.filterVbEventsRWMutex.RUnlock()
 
 				c.vbProcessingStats.updateVbStat(e.VBucket, "last_read_seq_no", e.Seqno)
+
+				if c.filterEvent(e) {
+					continue
+				}
+
 				c.dcpDeletionCounter++
 				c.sendEvent(e)
 
+			case mcd.DCP_EXPIRATION:
+				c.filterVbEventsRWMutex.RLock()
+				if _, ok := c.filterVbEvents[e.VBucket]; ok {
+					c.filterVbEventsRWMutex.RUnlock()
+					continue
+				}
+				c.filterVbEventsRWMutex.RUnlock()
+
+				c.vbProcessingStats.updateVbStat(e.VBucket, "last_read_seq_no", e.Seqno)
+
+				if c.filterEvent(e) {
+					continue
+				}
+
+				c.dcpExpirationCounter++
+				c.sendEvent(e)
+
 			case mcd.DCP_STREAMREQ:
 
 				logging.Infof("%s [%s:%s:%d] vb: %d got STREAMREQ status: %v",
@@ -192,7 +218,7 @@ func (c *Consumer) processEvents() {
 
 					if c.usingTimer {
 						err := timers.Create(c.producer.GetMetadataPrefix(),
-							int(e.VBucket), connStr, c.producer.MetadataBucket())
+							int(e.VBucket), connStr, c.producer.MetadataBucket(), c.timerResolution)
 						if err == common.ErrRetryTimeout {
 							logging.Infof("%s [%s:%s:%d] Exiting due to timeout",
 								logPrefix, c.workerName, c.tcpPort, c.Pid())
@@ -507,7 +533,9 @@ func (c *Consumer) processTimerEvents() {
 			}
 			event := ev.(*TimerEvent)
 			c.timerMessagesProcessed++
+			c.recordTimerLateness(event.Due)
 			c.sendTimerEvent(event, false)
+			event.store.Fired(event.entry)
 		}
 	}
 }
@@ -54,12 +54,14 @@ const (
 	v8WorkerExecutionStats
 	v8WorkerCompile
 	v8WorkerLcbExceptions
+	v8WorkerTestRun
 )
 
 const (
 	dcpOpcode int8 = iota
 	dcpDeletion
 	dcpMutation
+	dcpExpiration
 )
 
 const (
@@ -68,6 +70,7 @@ const (
 	workerThreadCount
 	workerThreadPartitionMap
 	timerContextSize
+	handlerConstants
 )
 
 // message and opcode types for interpreting messages from C++ To Go
@@ -77,6 +80,7 @@ const (
 	docTimerResponse
 	bucketOpsResponse
 	bucketOpsFilterAck
+	deadLetterResponse
 )
 
 const (
@@ -91,6 +95,7 @@ const (
 	compileInfo
 	queueSize
 	lcbExceptions
+	testResult
 )
 
 const (
@@ -105,6 +110,10 @@ const (
 	bucketOpsFilterAckOpCode int8 = iota
 )
 
+const (
+	deadLetterEntry int8 = iota
+)
+
 type message struct {
 	Header  []byte
 	Payload []byte
@@ -122,6 +131,10 @@ func (c *Consumer) makeDcpDeletionHeader(partition int16, deletionMeta string) (
 	return c.makeDcpHeader(dcpDeletion, partition, deletionMeta)
 }
 
+func (c *Consumer) makeDcpExpirationHeader(partition int16, expirationMeta string) ([]byte, *flatbuffers.Builder) {
+	return c.makeDcpHeader(dcpExpiration, partition, expirationMeta)
+}
+
 func (c *Consumer) makeDcpHeader(opcode int8, partition int16, meta string) ([]byte, *flatbuffers.Builder) {
 	return c.makeHeader(dcpEvent, opcode, partition, meta)
 }
@@ -162,6 +175,10 @@ func (c *Consumer) makeV8CompileOpcodeHeader(appCode string) ([]byte, *flatbuffe
 	return c.makeV8EventHeader(v8WorkerCompile, appCode)
 }
 
+func (c *Consumer) makeV8TestRunOpcodeHeader(request string) ([]byte, *flatbuffers.Builder) {
+	return c.makeV8EventHeader(v8WorkerTestRun, request)
+}
+
 func (c *Consumer) makeV8LoadOpcodeHeader(appCode string) ([]byte, *flatbuffers.Builder) {
 	return c.makeV8EventHeader(v8WorkerLoad, appCode)
 }
@@ -178,6 +195,10 @@ func (c *Consumer) makeTimerContextSizeHeader(meta string) ([]byte, *flatbuffers
 	return c.makeHeader(appWorkerSetting, timerContextSize, 0, meta)
 }
 
+func (c *Consumer) makeConstantsHeader(meta string) ([]byte, *flatbuffers.Builder) {
+	return c.makeHeader(appWorkerSetting, handlerConstants, 0, meta)
+}
+
 func (c *Consumer) makeThrCountHeader(meta string) ([]byte, *flatbuffers.Builder) {
 	return c.makeHeader(appWorkerSetting, workerThreadCount, 0, meta)
 }
@@ -214,6 +235,27 @@ func (c *Consumer) createHandlerHeaders(builder *flatbuffers.Builder) flatbuffer
 	return builder.EndVector(len(c.handlerHeaders))
 }
 
+func (c *Consumer) createCurlCredentials(builder *flatbuffers.Builder) flatbuffers.UOffsetT {
+	credentials := make([]flatbuffers.UOffsetT, 0, len(c.curlCredentials))
+	for _, credential := range c.curlCredentials {
+		alias := builder.CreateString(credential.Alias)
+		password := builder.CreateString(credential.Password)
+		bearerKey := builder.CreateString(credential.BearerKey)
+
+		payload.CurlCredentialStart(builder)
+		payload.CurlCredentialAddAlias(builder, alias)
+		payload.CurlCredentialAddPassword(builder, password)
+		payload.CurlCredentialAddBearerKey(builder, bearerKey)
+		credentials = append(credentials, payload.CurlCredentialEnd(builder))
+	}
+
+	payload.PayloadStartCurlCredentialsVector(builder, len(credentials))
+	for i := len(credentials) - 1; i >= 0; i-- {
+		builder.PrependUOffsetT(credentials[i])
+	}
+	return builder.EndVector(len(credentials))
+}
+
 func (c *Consumer) createHandlerFooters(builder *flatbuffers.Builder) flatbuffers.UOffsetT {
 	for i := len(c.handlerFooters) - 1; i >= 0; i-- {
 		builder.PrependUOffsetT(builder.CreateString(c.handlerFooters[i]))
@@ -302,7 +344,7 @@ func (c *Consumer) makeDcpPayload(key, value []byte) (encodedPayload []byte, bui
 }
 
 func (c *Consumer) makeV8InitPayload(appName, debuggerPort, currHost, eventingDir, eventingPort,
-	eventingSSLPort, kvHostPort, depCfg string, capacity, executionTimeout, checkpointInterval int,
+	eventingSSLPort, kvHostPort, depCfg, constants string, capacity, executionTimeout, checkpointInterval int,
 	skipLcbBootstrap bool, curlTimeout int64, timerContextSize int64) (encodedPayload []byte, builder *flatbuffers.Builder) {
 	builder = c.getBuilder()
 
@@ -313,13 +355,18 @@ func (c *Consumer) makeV8InitPayload(appName, debuggerPort, currHost, eventingDi
 	ep := builder.CreateString(eventingPort)
 	esp := builder.CreateString(eventingSSLPort)
 	dcfg := builder.CreateString(depCfg)
+	cnst := builder.CreateString(constants)
 	khp := builder.CreateString(kvHostPort)
 	handlerHeaders := c.createHandlerHeaders(builder)
 	handlerFooters := c.createHandlerFooters(builder)
+	curlCredentials := c.createCurlCredentials(builder)
 
 	lcb := make([]byte, 1)
 	flatbuffers.WriteBool(lcb, skipLcbBootstrap)
 
+	sb := make([]byte, 1)
+	flatbuffers.WriteBool(sb, c.sandbox)
+
 	payload.PayloadStart(builder)
 
 	payload.PayloadAddAppName(builder, app)
@@ -329,6 +376,7 @@ func (c *Consumer) makeV8InitPayload(appName, debuggerPort, currHost, eventingDi
 	payload.PayloadAddCurrEventingPort(builder, ep)
 	payload.PayloadAddCurrEventingSslport(builder, esp)
 	payload.PayloadAddDepcfg(builder, dcfg)
+	payload.PayloadAddConstants(builder, cnst)
 	payload.PayloadAddKvHostPort(builder, khp)
 	payload.PayloadAddLcbInstCapacity(builder, int32(capacity))
 	payload.PayloadAddExecutionTimeout(builder, int32(executionTimeout))
@@ -336,8 +384,10 @@ func (c *Consumer) makeV8InitPayload(appName, debuggerPort, currHost, eventingDi
 	payload.PayloadAddCurlTimeout(builder, curlTimeout)
 	payload.PayloadAddTimerContextSize(builder, timerContextSize)
 	payload.PayloadAddSkipLcbBootstrap(builder, lcb[0])
+	payload.PayloadAddSandbox(builder, sb[0])
 	payload.PayloadAddHandlerHeaders(builder, handlerHeaders)
 	payload.PayloadAddHandlerFooters(builder, handlerFooters)
+	payload.PayloadAddCurlCredentials(builder, curlCredentials)
 
 	msgPos := payload.PayloadEnd(builder)
 	builder.Finish(msgPos)
@@ -423,6 +473,28 @@ func (c *Consumer) routeResponse(msgType, opcode int8, msg string) {
 				logging.Errorf("%s [%s:%s:%d] Failed to unmarshal compilation stats, msg: %v err: %v",
 					logPrefix, c.workerName, c.tcpPort, c.Pid(), msg, err)
 			}
+		case testResult:
+			var result struct {
+				common.HandlerTestResult
+				RequestID string `json:"request_id"`
+			}
+
+			err := json.Unmarshal([]byte(msg), &result)
+			if err != nil {
+				logging.Errorf("%s [%s:%s:%d] Failed to unmarshal test result, msg: %ru err: %v",
+					logPrefix, c.workerName, c.tcpPort, c.Pid(), msg, err)
+				return
+			}
+
+			c.statsRWMutex.Lock()
+			defer c.statsRWMutex.Unlock()
+			// Reply of a request that had timed out is dropped, rather than taken for result of the current one
+			if result.RequestID != strconv.FormatUint(c.testRequestID, 10) {
+				logging.Infof("%s [%s:%s:%d] Dropping test result of request: %s, awaiting request: %d",
+					logPrefix, c.workerName, c.tcpPort, c.Pid(), result.RequestID, c.testRequestID)
+				return
+			}
+			c.testResult = &result.HandlerTestResult
 		case queueSize:
 			c.workerRespMainLoopTs.Store(time.Now())
 
@@ -493,6 +565,7 @@ func (c *Consumer) routeResponse(msgType, opcode int8, msg string) {
 			logging.Tracef("%s [%s:%s:%d] vb: %d Updating last_processed_seq_no to seqNo: %d",
 				logPrefix, c.workerName, c.tcpPort, c.Pid(), vb, seqNo)
 		}
+		c.applyFilteredSeqNo(uint16(vb), seqNo)
 	case bucketOpsFilterAck:
 		var ack vbSeqNo
 		err := json.Unmarshal([]byte(msg), &ack)
@@ -504,6 +577,21 @@ func (c *Consumer) routeResponse(msgType, opcode int8, msg string) {
 		logging.Infof("%s [%s:%s:%d] vb: %d seqNo: %d received filter ack from C++",
 			logPrefix, c.workerName, c.tcpPort, c.Pid(), ack.Vbucket, ack.SeqNo)
 		c.filterDataCh <- &ack
+	case deadLetterResponse:
+		var entry deadLetterMsg
+		err := json.Unmarshal([]byte(msg), &entry)
+		if err != nil {
+			logging.Errorf("%s [%s:%s:%d] Failed to unmarshal dead letter entry, msg: %ru err: %v",
+				logPrefix, c.workerName, c.tcpPort, c.Pid(), msg, err)
+			c.deadLetterWriteFailures++
+			return
+		}
+
+		if err = c.writeDeadLetterEntry(&entry); err != nil {
+			c.deadLetterWriteFailures++
+			return
+		}
+		c.deadLetterEntriesWritten++
 	default:
 		logging.Infof("%s [%s:%s:%d] Unknown message %s",
 			logPrefix, c.workerName, c.tcpPort, c.Pid(), msg)
@@ -1,6 +1,7 @@
 package consumer
 
 import (
+	"strconv"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -35,14 +36,38 @@ func (c *Consumer) scanTimers() {
 
 			wg.Wait()
 
-			delta := timers.Resolution - int64(time.Now().Sub(startTs).Seconds())
-			if delta > 0 {
-				time.Sleep(time.Duration(delta) * time.Second)
+			// Scan again as rows of the next resolution boundary become due, so that
+			// timers fire within a fraction of a second of it
+			resolution := c.timerScanResolution(vbs)
+			next := time.Unix((startTs.Unix()/resolution+1)*resolution, 0)
+			if delta := next.Sub(time.Now()); delta > 0 {
+				time.Sleep(delta)
 			}
 		}
 	}
 }
 
+// timerScanResolution returns the finest resolution among timer stores of the given vbuckets,
+// as a store keeps the resolution persisted with its span over the configured one
+func (c *Consumer) timerScanResolution(vbs []uint16) int64 {
+	var resolution int64
+	for _, vb := range vbs {
+		store, found := timers.Fetch(c.producer.GetMetadataPrefix(), int(vb))
+		if !found {
+			continue
+		}
+
+		if r := store.Resolution(); r > 0 && (resolution == 0 || r < resolution) {
+			resolution = r
+		}
+	}
+
+	if resolution == 0 {
+		return c.timerResolution
+	}
+	return resolution
+}
+
 func (c *Consumer) executeTimers(vbs []uint16, wg *sync.WaitGroup) {
 	logPrefix := "Consumer::executeTimers"
 
@@ -90,6 +115,14 @@ func (c *Consumer) executeTimersImpl(store *timers.TimerStore, iterator *timers.
 		}
 		atomic.AddUint64(&c.metastoreScanCounter, 1)
 
+		// Next alarm of a recurring timer is set up ahead of firing, so that the fired
+		// alarm alone gets deleted once its callback has run
+		if _, err = store.Reschedule(entry); err != nil {
+			logging.Errorf("%s [%s:%s:%d] vb: %d unable to reschedule recurring timer, series ends with this firing, err: %v",
+				logPrefix, c.workerName, c.tcpPort, c.Pid(), vb, err)
+			atomic.AddUint64(&c.metastoreRescheduleErrCounter, 1)
+		}
+
 		e := entry.Context.(map[string]interface{})
 		timer := &timerContext{
 			Callback:  e["callback"].(string),
@@ -101,15 +134,50 @@ func (c *Consumer) executeTimersImpl(store *timers.TimerStore, iterator *timers.
 		event := &TimerEvent{
 			Context: timer,
 			Token:   store.GetToken(entry),
+			Due:     entry.AlarmDue,
+			store:   store,
+			entry:   entry,
 		}
 		if err = c.fireTimerQueue.Push(event); err != nil {
 			logging.Errorf("%s [%s:%s:%d] Failed to write to fireTimerQueue, size: %d, quota: %d err : %v",
 				logPrefix, c.workerName, c.tcpPort, c.Pid(), timer.Size(), c.timerQueueMemCap, err)
+			store.Dropped(entry)
 			return
 		}
 	}
 }
 
+// timerLatenessBin buckets lateness in milliseconds into bins of 100ms up to a second,
+// of a second up to a minute and of a minute beyond that, keyed by the upper bound
+func timerLatenessBin(lateness int64) int64 {
+	switch {
+	case lateness <= 0:
+		return 0
+	case lateness <= 1000:
+		return roundUpTo(lateness, 100)
+	case lateness <= 60*1000:
+		return roundUpTo(lateness, 1000)
+	case lateness <= timerLatenessMaxBin:
+		return roundUpTo(lateness, 60*1000)
+	default:
+		return timerLatenessMaxBin
+	}
+}
+
+func roundUpTo(val, multiple int64) int64 {
+	return ((val + multiple - 1) / multiple) * multiple
+}
+
+// recordTimerLateness captures how late a timer is being sent for execution relative to its due time
+func (c *Consumer) recordTimerLateness(due int64) {
+	lateness := time.Now().UnixNano()/int64(time.Millisecond) - due*1000
+	bin := strconv.FormatInt(timerLatenessBin(lateness), 10)
+
+	c.statsRWMutex.Lock()
+	defer c.statsRWMutex.Unlock()
+	c.timerLatenessStats[bin]++
+}
+
 func (c *Consumer) routeTimers() {
 	logPrefix := "Consumer::routeTimers"
 
@@ -180,15 +248,22 @@ func (c *Consumer) storeTimers(index int, timerQueue *util.BoundedQueue) {
 			}
 
 			context := &timerContext{
-				Callback: timer.Callback,
-				Context:  timer.Context,
-				Vb:       timer.Vb,
+				Callback:  timer.Callback,
+				Context:   timer.Context,
+				Reference: timer.Reference,
+				Vb:        timer.Vb,
 			}
 
-			err = store.Set(timer.Epoch, timer.Callback+":"+timer.Reference, context)
+			ref := timer.Callback + ":" + timer.Reference
+			if timer.Cron != "" || timer.Interval != 0 {
+				schedule := &timers.Schedule{Cron: timer.Cron, Interval: timer.Interval}
+				err = store.SetRecurring(timer.Epoch, ref, context, schedule)
+			} else {
+				err = store.Set(timer.Epoch, ref, context)
+			}
 			if err != nil {
-				logging.Errorf("%s [%s:%s:%d] vb: %d seq: %d failed to store",
-					logPrefix, c.workerName, c.tcpPort, c.Pid(), timer.Vb, timer.SeqNum)
+				logging.Errorf("%s [%s:%s:%d] vb: %d seq: %d failed to store, err: %v",
+					logPrefix, c.workerName, c.tcpPort, c.Pid(), timer.Vb, timer.SeqNum, err)
 				atomic.AddUint64(&c.metastoreSetErrCounter, 1)
 				continue
 			}
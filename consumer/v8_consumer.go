@@ -41,11 +41,14 @@ func NewConsumer(hConfig *common.HandlerConfig, pConfig *common.ProcessConfig, r
 		cleanupTimers:                   hConfig.CleanupTimers,
 		clusterStateChangeNotifCh:       make(chan struct{}, ClusterChangeNotifChBufSize),
 		connMutex:                       &sync.RWMutex{},
+		constants:                       hConfig.Constants,
 		controlRoutineWg:                &sync.WaitGroup{},
 		cppThrPartitionMap:              make(map[int][]uint16),
 		cppWorkerThrCount:               hConfig.CPPWorkerThrCount,
 		crcTable:                        crc32.MakeTable(crc32.Castagnoli),
 		curlTimeout:                     hConfig.CurlTimeout,
+		deadLetterBucket:                hConfig.DeadLetterBucket,
+		deadLetterKeyPrefix:             hConfig.DeadLetterKeyPrefix,
 		dcpConfig:                       dcpConfig,
 		dcpFeedVbMap:                    make(map[*couchbase.DcpFeed][]uint16),
 		dcpStreamBoundary:               hConfig.StreamBoundary,
@@ -68,6 +71,7 @@ func NewConsumer(hConfig *common.HandlerConfig, pConfig *common.ProcessConfig, r
 		gracefulShutdownChan:            make(chan struct{}, 1),
 		handlerFooters:                  hConfig.HandlerFooters,
 		handlerHeaders:                  hConfig.HandlerHeaders,
+		curlCredentials:                 hConfig.CurlCredentials,
 		index:                           index,
 		ipcType:                         pConfig.IPCType,
 		inflightDcpStreams:              make(map[uint16]struct{}),
@@ -97,6 +101,7 @@ func NewConsumer(hConfig *common.HandlerConfig, pConfig *common.ProcessConfig, r
 		socketWriteLoopStopCh:           make(chan struct{}, 1),
 		socketWriteTicker:               time.NewTicker(socketWriteTimerInterval),
 		statsRWMutex:                    &sync.RWMutex{},
+		timerLatenessStats:              make(map[string]uint64),
 		statsTickDuration:               time.Duration(hConfig.StatsLogInterval) * time.Millisecond,
 		stopControlRoutineCh:            make(chan struct{}, 1),
 		stopVbOwnerTakeoverCh:           make(chan struct{}),
@@ -105,6 +110,7 @@ func NewConsumer(hConfig *common.HandlerConfig, pConfig *common.ProcessConfig, r
 		tcpPort:                         pConfig.SockIdentifier,
 		timerContextSize:                hConfig.TimerContextSize,
 		timerQueueSize:                  hConfig.TimerQueueSize,
+		timerResolution:                 hConfig.TimerResolution,
 		timerQueueMemCap:                hConfig.TimerQueueMemCap,
 		timerStorageChanSize:            hConfig.TimerStorageChanSize,
 		timerStorageMetaChsRWMutex:      &sync.RWMutex{},
@@ -147,6 +153,14 @@ func NewConsumer(hConfig *common.HandlerConfig, pConfig *common.ProcessConfig, r
 		},
 	}
 
+	filter, err := newEventFilter(hConfig.EventFilter)
+	if err != nil {
+		// Deployment config is validated upfront, falling back to sending all events to handler
+		logging.Errorf("Consumer::NewConsumer [%s:%d] Failed to compile event filter, err: %v",
+			consumer.workerName, index, err)
+	}
+	consumer.eventFilter = filter
+
 	return consumer
 }
 
@@ -329,7 +343,7 @@ func (c *Consumer) HandleV8Worker() error {
 
 	payload, pBuilder := c.makeV8InitPayload(c.app.AppName, c.debuggerPort, currHost,
 		c.eventingDir, c.eventingAdminPort, c.eventingSSLPort, c.getKvNodes()[0],
-		c.producer.CfgData(), c.lcbInstCapacity, c.executionTimeout,
+		c.producer.CfgData(), c.constants, c.lcbInstCapacity, c.executionTimeout,
 		int(c.checkpointInterval.Nanoseconds()/(1000*1000)), false, c.curlTimeout, c.timerContextSize)
 
 	c.sendInitV8Worker(payload, false, pBuilder)
@@ -372,6 +386,10 @@ func (c *Consumer) Stop() {
 		c.gocbMetaBucket.Close()
 	}
 
+	if c.gocbDeadLetterBucket != nil {
+		c.gocbDeadLetterBucket.Close()
+	}
+
 	logging.Infof("%s [%s:%s:%d] Issued close for go-couchbase and gocb handles",
 		logPrefix, c.workerName, c.tcpPort, c.Pid())
 
@@ -590,6 +590,38 @@ func (c *Consumer) cleanupVbMetadata(vb uint16) error {
 	return nil
 }
 
+// closeVbStream closes dcp stream of vb and checkpoints it as stopped, so that the worker
+// supposed to own it per plan can request the stream
+func (c *Consumer) closeVbStream(vb uint16) error {
+	logPrefix := "Consumer::closeVbStream"
+
+	logging.Infof("%s [%s:%s:%d] vb: %d Issuing dcp close stream", logPrefix, c.workerName, c.tcpPort, c.Pid(), vb)
+	c.dcpCloseStreamCounter++
+	c.RLock()
+	err := c.vbDcpFeedMap[vb].DcpCloseStream(vb, vb)
+	c.RUnlock()
+	if err != nil {
+		c.dcpCloseStreamErrCounter++
+		logging.Errorf("%s [%s:%s:%d] vb: %v Failed to close dcp stream, err: %v",
+			logPrefix, c.workerName, c.tcpPort, c.Pid(), vb, err)
+	} else {
+		logging.Infof("%s [%s:%s:%d] vb: %v Issued dcp close stream as current worker isn't supposed to own per plan",
+			logPrefix, c.workerName, c.tcpPort, c.Pid(), vb)
+	}
+
+	lastSeqNo := c.vbProcessingStats.getVbStat(vb, "last_read_seq_no").(uint64)
+	c.vbProcessingStats.updateVbStat(vb, "seq_no_after_close_stream", lastSeqNo)
+	c.vbProcessingStats.updateVbStat(vb, "timestamp", time.Now().Format(time.RFC3339))
+
+	// Next owner resumes the stream from where this worker got to
+	vbBlob := vbucketKVBlob{
+		LastSeqNoProcessed: c.vbProcessingStats.getVbStat(vb, "last_processed_seq_no").(uint64),
+	}
+	vbKey := fmt.Sprintf("%s::vb::%d", c.app.AppName, vb)
+
+	return c.updateCheckpoint(vbKey, vb, &vbBlob)
+}
+
 func (c *Consumer) closeAllRunningDcpFeeds() {
 	logPrefix := "Consumer::closeAllRunningDcpFeeds"
 
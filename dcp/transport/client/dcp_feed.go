@@ -20,6 +20,7 @@ const opaqueOpen = 0xBEAF0001
 const opaqueFailover = 0xDEADBEEF
 const opaqueGetseqno = 0xDEADBEEF
 const openConnFlag = uint32(0x1)
+const includeDeleteTimesFlag = uint32(0x20)
 
 // error codes
 var ErrorInvalidLog = errors.New("couchbase.errorInvalidLog")
@@ -711,6 +712,42 @@ func (feed *DcpFeed) doDcpOpen(
 		logging.Debugf(fmsg, prefix, opaque)
 
 	}
+
+	// Expiry opcode lets consumer tell expired documents apart from deleted ones,
+	// KV requires delete times to be included in order to enable it
+	if flags&includeDeleteTimesFlag != 0 {
+		rq := &transport.MCRequest{
+			Opcode: transport.DCP_CONTROL,
+			Key:    []byte("enable_expiry_opcode"),
+			Body:   []byte("true"),
+		}
+		if err := feed.conn.Transmit(rq); err != nil {
+			fmsg := "%v ##%x doDcpOpen.Transmit(enable_expiry_opcode): %v"
+			logging.Errorf(fmsg, prefix, opaque, err)
+			return err
+		}
+		logging.Debugf("%v ##%x sending enable_expiry_opcode", prefix, opaque)
+		msg, ok := <-rcvch
+		if !ok {
+			fmsg := "%v ##%x doDcpOpen.rcvch (enable_expiry_opcode) closed"
+			logging.Errorf(fmsg, prefix, opaque)
+			return ErrorConnection
+		}
+		pkt := msg[0].(*transport.MCRequest)
+		opcode, status := pkt.Opcode, transport.Status(pkt.VBucket)
+		if opcode != transport.DCP_CONTROL {
+			fmsg := "%v ##%x DCP_CONTROL (enable_expiry_opcode) != #%v"
+			logging.Errorf(fmsg, prefix, opaque, opcode)
+			return ErrorConnection
+		} else if status != transport.SUCCESS {
+			// Older KV versions don't support it, expirations continue to arrive as deletions
+			fmsg := "%v ##%x doDcpOpen (enable_expiry_opcode) response status %v"
+			logging.Warnf(fmsg, prefix, opaque, status)
+		} else {
+			fmsg := "%v ##%x received response for enable_expiry_opcode"
+			logging.Debugf(fmsg, prefix, opaque)
+		}
+	}
 	return nil
 }
 
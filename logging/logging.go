@@ -1,5 +1,6 @@
 package logging
 
+import "io"
 import "os"
 import "fmt"
 import "strings"
@@ -158,6 +159,11 @@ func SetLogLevel(to LogLevel) {
 	baselevel = to
 }
 
+// SetLogWriter redirects log output, which defaults to stdout
+func SetLogWriter(w io.Writer) {
+	target = l.New(w, "", 0)
+}
+
 func StackTrace() string {
 	var buf bytes.Buffer
 	lines := strings.Split(string(debug.Stack()), "\n")
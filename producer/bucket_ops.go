@@ -103,19 +103,7 @@ var gocbConnectMetaBucketCallback = func(args ...interface{}) error {
 		return nil
 	}
 
-	kvNodes := p.KvHostPorts()
-
-	connStr := "couchbase://"
-	for index, kvNode := range kvNodes {
-		if index != 0 {
-			connStr = connStr + ","
-		}
-		connStr = connStr + kvNode
-	}
-
-	if util.IsIPv6() {
-		connStr += "?ipv6=allow"
-	}
+	connStr := p.kvConnStr()
 
 	cluster, err := gocb.Connect(connStr)
 	if err != nil {
@@ -100,6 +100,27 @@ var getEventingNodesAddressesOpCallback = func(args ...interface{}) error {
 
 }
 
+var getEventingNodesServerGroupsOpCallback = func(args ...interface{}) error {
+	logPrefix := "Producer::getEventingNodesServerGroupsOpCallback"
+
+	p := args[0].(*Producer)
+	nodeGroups := args[1].(*map[string]string)
+
+	hostAddress := net.JoinHostPort(util.Localhost(), p.nsServerPort)
+
+	var err error
+	*nodeGroups, err = util.EventingNodesServerGroups(p.auth, hostAddress)
+	if err != nil {
+		logging.Errorf("%s [%s:%d] Failed to get server groups of eventing nodes, err: %v",
+			logPrefix, p.appName, p.LenRunningConsumers(), err)
+		return err
+	}
+
+	logging.Infof("%s [%s:%d] Got server groups of eventing nodes: %rs",
+		logPrefix, p.appName, p.LenRunningConsumers(), fmt.Sprintf("%#v", *nodeGroups))
+	return nil
+}
+
 var getHTTPServiceAuth = func(args ...interface{}) error {
 	logPrefix := "Producer::getHTTPServiceAuth"
 
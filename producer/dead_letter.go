@@ -0,0 +1,245 @@
+package producer
+
+import (
+	"fmt"
+
+	"github.com/couchbase/eventing/common"
+	"github.com/couchbase/eventing/logging"
+	"github.com/couchbase/eventing/util"
+	"github.com/couchbase/gocb"
+)
+
+const (
+	// Attempts at trimming dead letter index, which consumers may be appending to meanwhile
+	deadLetterIndexUpdateAttempts = 5
+)
+
+var gocbConnectDeadLetterBucketsCallback = func(args ...interface{}) error {
+	logPrefix := "Producer::gocbConnectDeadLetterBucketsCallback"
+
+	p := args[0].(*Producer)
+	sourceBucket := args[1].(**gocb.Bucket)
+	deadLetterBucket := args[2].(**gocb.Bucket)
+
+	kvNodes := p.KvHostPorts()
+
+	connStr := "couchbase://"
+	for index, kvNode := range kvNodes {
+		if index != 0 {
+			connStr = connStr + ","
+		}
+		connStr = connStr + kvNode
+	}
+
+	if util.IsIPv6() {
+		connStr += "?ipv6=allow"
+	}
+
+	cluster, err := gocb.Connect(connStr)
+	if err != nil {
+		logging.Errorf("%s [%s:%d] Connect to cluster %rs failed, err: %v",
+			logPrefix, p.appName, p.LenRunningConsumers(), connStr, err)
+		return err
+	}
+
+	err = cluster.Authenticate(&util.DynamicAuthenticator{Caller: logPrefix})
+	if err != nil {
+		logging.Errorf("%s [%s:%d] Failed to authenticate to the cluster %rs failed, err: %v",
+			logPrefix, p.appName, p.LenRunningConsumers(), connStr, err)
+		return err
+	}
+
+	*sourceBucket, err = cluster.OpenBucket(p.handlerConfig.SourceBucket, "")
+	if err != nil {
+		logging.Errorf("%s [%s:%d] Failed to connect to source bucket %s, err: %v",
+			logPrefix, p.appName, p.LenRunningConsumers(), p.handlerConfig.SourceBucket, err)
+		return err
+	}
+
+	*deadLetterBucket, err = cluster.OpenBucket(p.handlerConfig.DeadLetterBucket, "")
+	if err != nil {
+		logging.Errorf("%s [%s:%d] Failed to connect to dead letter bucket %s, err: %v",
+			logPrefix, p.appName, p.LenRunningConsumers(), p.handlerConfig.DeadLetterBucket, err)
+		(*sourceBucket).Close()
+		return err
+	}
+
+	return nil
+}
+
+// ReplayDeadLetters re-triggers handler execution for events captured in dead letter bucket.
+// Current body of source document is sent straight to the consumer streaming its vbucket, so
+// that neither other functions on the bucket nor XDCR get to see the replay. Only vbuckets
+// owned by the current node are replayed.
+func (p *Producer) ReplayDeadLetters() (*common.DeadLetterReplayStats, error) {
+	logPrefix := "Producer::ReplayDeadLetters"
+
+	if p.handlerConfig.DeadLetterBucket == "" {
+		return nil, fmt.Errorf("dead letter bucket isn't configured")
+	}
+
+	var sourceBucket, deadLetterBucket *gocb.Bucket
+	err := gocbConnectDeadLetterBucketsCallback(p, &sourceBucket, &deadLetterBucket)
+	if err != nil {
+		return nil, err
+	}
+	defer sourceBucket.Close()
+	defer deadLetterBucket.Close()
+
+	stats := &common.DeadLetterReplayStats{}
+	for vb := 0; vb < p.numVbuckets; vb++ {
+		if consumer := p.vbConsumer(uint16(vb)); consumer != nil {
+			p.replayVbDeadLetters(sourceBucket, deadLetterBucket, consumer, uint16(vb), stats)
+		}
+	}
+
+	logging.Infof("%s [%s:%d] Dead letter replay finished, replayed: %d skipped: %d failed: %d",
+		logPrefix, p.appName, p.LenRunningConsumers(), stats.Replayed, stats.Skipped, stats.Failed)
+
+	return stats, nil
+}
+
+// vbConsumer returns running consumer streaming the vbucket, if any
+func (p *Producer) vbConsumer(vb uint16) common.EventingConsumer {
+	p.runningConsumersRWMutex.RLock()
+	defer p.runningConsumersRWMutex.RUnlock()
+
+	for _, consumer := range p.runningConsumers {
+		if consumer.OwnsVb(vb) {
+			return consumer
+		}
+	}
+	return nil
+}
+
+// trimDeadLetterIndex returns doc ids left pending by replay of the first handled ids of index,
+// followed by ids consumers appended to it since, which may include ones just replayed
+func trimDeadLetterIndex(keys []string, handled int, pending []string) []string {
+	trimmed := append([]string{}, pending...)
+	if handled >= len(keys) {
+		return trimmed
+	}
+
+	present := make(map[string]struct{})
+	for _, docID := range pending {
+		present[docID] = struct{}{}
+	}
+
+	for _, docID := range keys[handled:] {
+		if _, ok := present[docID]; !ok {
+			present[docID] = struct{}{}
+			trimmed = append(trimmed, docID)
+		}
+	}
+	return trimmed
+}
+
+func (p *Producer) replayVbDeadLetters(sourceBucket, deadLetterBucket *gocb.Bucket,
+	consumer common.EventingConsumer, vb uint16, stats *common.DeadLetterReplayStats) {
+	logPrefix := "Producer::replayVbDeadLetters"
+
+	keyPrefix := p.handlerConfig.DeadLetterKeyPrefix
+	indexKey := common.DeadLetterIndexKey(keyPrefix, p.appName, vb)
+
+	var index common.DeadLetterIndex
+	cas, err := deadLetterBucket.Get(indexKey, &index)
+	if err == gocb.ErrKeyNotFound {
+		return
+	}
+
+	if err != nil {
+		logging.Errorf("%s [%s:%d] vb: %d Failed to read dead letter index, err: %v",
+			logPrefix, p.appName, p.LenRunningConsumers(), vb, err)
+		return
+	}
+
+	pending := make([]string, 0)
+	seen := make(map[string]struct{})
+	handled := len(index.Keys)
+
+	for _, docID := range index.Keys {
+		if _, ok := seen[docID]; ok {
+			continue
+		}
+		seen[docID] = struct{}{}
+
+		var entry common.DeadLetterEntry
+		entryKey := common.DeadLetterEntryKey(keyPrefix, p.appName, docID)
+
+		_, err = deadLetterBucket.Get(entryKey, &entry)
+		if err == gocb.ErrKeyNotFound {
+			// Already replayed
+			continue
+		}
+
+		if err != nil {
+			logging.Errorf("%s [%s:%d] vb: %d Failed to read dead letter entry: %ru, err: %v",
+				logPrefix, p.appName, p.LenRunningConsumers(), vb, entryKey, err)
+			stats.Failed++
+			pending = append(pending, docID)
+			continue
+		}
+
+		// Deletions and expirations can't be regenerated without losing data, hence they're left for manual handling
+		if entry.Event == "deletion" || entry.Event == "expiration" {
+			stats.Skipped++
+			pending = append(pending, docID)
+			continue
+		}
+
+		var value []byte
+		docCas, err := sourceBucket.Get(docID, &value)
+		if err == gocb.ErrKeyNotFound {
+			stats.Skipped++
+			pending = append(pending, docID)
+			continue
+		}
+
+		if err == nil {
+			err = consumer.ReplayMutation(vb, docID, value, uint64(docCas), entry.SeqNo)
+		}
+
+		if err != nil {
+			logging.Errorf("%s [%s:%d] vb: %d Failed to replay doc: %ru, err: %v",
+				logPrefix, p.appName, p.LenRunningConsumers(), vb, docID, err)
+			stats.Failed++
+			pending = append(pending, docID)
+			continue
+		}
+
+		_, err = deadLetterBucket.Remove(entryKey, 0)
+		if err != nil {
+			logging.Errorf("%s [%s:%d] vb: %d Failed to remove dead letter entry: %ru, err: %v",
+				logPrefix, p.appName, p.LenRunningConsumers(), vb, entryKey, err)
+		}
+
+		stats.Replayed++
+	}
+
+	// Index update is cas protected, as consumers might have appended fresh failures in the
+	// meantime. On cas mismatch, doc ids still pending are worked out again from the fresh index.
+	for attempt := 1; ; attempt++ {
+		if len(pending) == 0 {
+			_, err = deadLetterBucket.Remove(indexKey, cas)
+		} else {
+			_, err = deadLetterBucket.Replace(indexKey, &common.DeadLetterIndex{Keys: pending}, cas, 0)
+		}
+
+		if err != gocb.ErrKeyExists || attempt == deadLetterIndexUpdateAttempts {
+			break
+		}
+
+		index = common.DeadLetterIndex{}
+		if cas, err = deadLetterBucket.Get(indexKey, &index); err != nil {
+			break
+		}
+
+		pending = trimDeadLetterIndex(index.Keys, handled, pending)
+		handled = len(index.Keys)
+	}
+
+	if err != nil {
+		logging.Infof("%s [%s:%d] vb: %d Dead letter index not updated, err: %v",
+			logPrefix, p.appName, p.LenRunningConsumers(), vb, err)
+	}
+}
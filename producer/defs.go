@@ -12,11 +12,12 @@ import (
 )
 
 const (
-	metakvEventingPath    = "/eventing/"
-	metakvAppsPath        = metakvEventingPath + "apps/"
-	metakvAppSettingsPath = metakvEventingPath + "appsettings/"
-	metakvConfigKeepNodes = metakvEventingPath + "config/keepNodes" // Store list of eventing keepNodes
-	metakvChecksumPath    = metakvEventingPath + "checksum/"
+	metakvEventingPath      = "/eventing/"
+	metakvAppsPath          = metakvEventingPath + "apps/"
+	metakvAppSettingsPath   = metakvEventingPath + "appsettings/"
+	metakvConfigKeepNodes   = metakvEventingPath + "config/keepNodes"   // Store list of eventing keepNodes
+	metakvConfigNodeWeights = metakvEventingPath + "config/nodeWeights" // Store capacity of eventing nodes
+	metakvChecksumPath      = metakvEventingPath + "checksum/"
 )
 
 const (
@@ -32,6 +33,18 @@ const (
 	// for instantiating V8 Debugger instance
 	startDebuggerFlag    = "startDebugger"
 	debuggerInstanceAddr = "debuggerInstAddr"
+
+	// Intra node vbucket rebalancing kicks in only when the most backlogged worker has at least
+	// these many events pending and is imbalanced by given ratio against the least backlogged one
+	workerVbRebalanceMinBacklog     = 10000
+	workerVbRebalanceImbalanceRatio = 2
+
+	// Cap on vbuckets moved between workers in a single round
+	workerVbRebalanceMaxMoves = 8
+
+	// Interval at which new workers are placed under cpu_shares and memory_limit,
+	// and memory footprint of the function's workers is checked against memory_limit
+	resourceLimitCheckInterval = 2 * time.Second
 )
 
 type appStatus uint16
@@ -73,6 +86,13 @@ type Producer struct {
 	uuid                   string
 	workerSpawnCounter     uint64
 
+	// Resource limits enforcement. Counters are read by stats handlers while
+	// enforceResourceLimits routine updates them, so they're accessed atomically.
+	resourceLimitsStopCh        chan struct{}
+	memoryLimitKillCounter      uint64
+	memoryLimitKernelKills      uint64 // Accessed only by enforceResourceLimits routine
+	resourceLimitFailureCounter uint64
+
 	handlerConfig   *common.HandlerConfig
 	processConfig   *common.ProcessConfig
 	rebalanceConfig *common.RebalanceConfig
@@ -157,6 +177,8 @@ type Producer struct {
 	workerVbucketMap   map[string][]uint16 // Access controlled by workerVbMapRWMutex
 	workerVbMapRWMutex *sync.RWMutex
 
+	workerVbRebalanceStopCh chan struct{}
+
 	// Supervisor of workers responsible for
 	// pipelining messages to V8
 	workerSupervisor *suptree.Supervisor
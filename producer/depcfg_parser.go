@@ -10,6 +10,7 @@ import (
 	"github.com/couchbase/eventing/common"
 	"github.com/couchbase/eventing/gen/flatbuf/cfg"
 	"github.com/couchbase/eventing/logging"
+	"github.com/couchbase/eventing/timers"
 	"github.com/couchbase/eventing/util"
 )
 
@@ -59,9 +60,61 @@ func (p *Producer) parseDepcfg() error {
 	p.auth = fmt.Sprintf("%s:%s", user, password)
 
 	p.handlerConfig.SourceBucket = string(depcfg.SourceBucket())
+	p.handlerConfig.Constants = string(depcfg.Constants())
 	p.cfgData = string(cfgData)
 	p.metadatabucket = string(depcfg.MetadataBucket())
 
+	if deadLetter := depcfg.DeadLetter(new(cfg.DeadLetter)); deadLetter != nil {
+		p.handlerConfig.DeadLetterBucket = string(deadLetter.BucketName())
+		p.handlerConfig.DeadLetterKeyPrefix = string(deadLetter.KeyPrefix())
+	}
+
+	if filter := depcfg.Filter(new(cfg.Filter)); filter != nil {
+		p.handlerConfig.EventFilter = &common.EventFilter{
+			KeyPrefix: string(filter.KeyPrefix()),
+			KeyRegex:  string(filter.KeyRegex()),
+		}
+
+		predicate := new(cfg.FilterPredicate)
+		for i := 0; i < filter.PredicatesLength(); i++ {
+			if !filter.Predicates(predicate, i) {
+				continue
+			}
+
+			fp := common.FilterPredicate{
+				Field: string(predicate.Field()),
+				Op:    string(predicate.Op()),
+			}
+			for j := 0; j < predicate.ValuesLength(); j++ {
+				fp.Values = append(fp.Values, string(predicate.Values(j)))
+			}
+			p.handlerConfig.EventFilter.Predicates = append(p.handlerConfig.EventFilter.Predicates, fp)
+		}
+	}
+
+	p.handlerConfig.CurlCredentials = nil
+	binding := new(cfg.Curl)
+	for i := 0; i < depcfg.CurlLength(); i++ {
+		if !depcfg.Curl(binding, i) {
+			continue
+		}
+
+		// Binding is left without credentials rather than failing deployment, its requests
+		// would be rejected by the remote end
+		password, pErr := util.DecryptSecret(string(binding.Password()))
+		bearerKey, bErr := util.DecryptSecret(string(binding.BearerKey()))
+		if pErr != nil || bErr != nil {
+			logging.Errorf("%s [%s] Failed to decrypt credentials of curl binding: %s, password err: %v bearer key err: %v",
+				logPrefix, p.appName, binding.Alias(), pErr, bErr)
+		}
+
+		p.handlerConfig.CurlCredentials = append(p.handlerConfig.CurlCredentials, common.CurlCredential{
+			Alias:     string(binding.Alias()),
+			Password:  password,
+			BearerKey: bearerKey,
+		})
+	}
+
 	settingsPath := metakvAppSettingsPath + p.appName
 	sData, sErr := util.MetakvGet(settingsPath)
 	if sErr != nil {
@@ -90,12 +143,28 @@ func (p *Producer) parseDepcfg() error {
 		p.handlerConfig.CleanupTimers = false
 	}
 
+	// Constants in settings supersede ones in deployment config, as they get updated without redeploy
+	if val, ok := settings["constants"]; ok {
+		constants, err := util.ConstantsToJSON(val)
+		if err != nil {
+			logging.Errorf("%s [%s] Failed to decode constants, err: %v", logPrefix, p.appName, err)
+			return err
+		}
+		p.handlerConfig.Constants = constants
+	}
+
 	if val, ok := settings["cpp_worker_thread_count"]; ok {
 		p.handlerConfig.CPPWorkerThrCount = int(val.(float64))
 	} else {
 		p.handlerConfig.CPPWorkerThrCount = 2
 	}
 
+	if val, ok := settings["cpu_shares"]; ok {
+		p.handlerConfig.CPUShares = int(val.(float64))
+	} else {
+		p.handlerConfig.CPUShares = 0
+	}
+
 	if val, ok := settings["curl_timeout"]; ok {
 		p.handlerConfig.CurlTimeout = int64(val.(float64))
 	} else {
@@ -160,6 +229,12 @@ func (p *Producer) parseDepcfg() error {
 		p.handlerConfig.LogLevel = "INFO"
 	}
 
+	if val, ok := settings["memory_limit"]; ok {
+		p.handlerConfig.MemoryLimit = int64(val.(float64)) * 1024 * 1024
+	} else {
+		p.handlerConfig.MemoryLimit = 0
+	}
+
 	if val, ok := settings["poll_bucket_interval"]; ok {
 		p.pollBucketInterval = time.Duration(val.(float64)) * time.Second
 	} else {
@@ -257,6 +332,12 @@ func (p *Producer) parseDepcfg() error {
 		p.handlerConfig.TimerQueueSize = 10000
 	}
 
+	if val, ok := settings["timer_resolution"]; ok {
+		p.handlerConfig.TimerResolution = int64(val.(float64))
+	} else {
+		p.handlerConfig.TimerResolution = timers.DefaultResolution
+	}
+
 	if val, ok := settings["undeploy_routine_count"]; ok {
 		p.handlerConfig.UndeployRoutineCount = int(val.(float64))
 	} else {
@@ -273,6 +354,18 @@ func (p *Producer) parseDepcfg() error {
 
 	// Rebalance related configurations
 
+	if val, ok := settings["planner_weighting"]; ok {
+		p.rebalanceConfig.PlannerWeighting = val.(string)
+	} else {
+		p.rebalanceConfig.PlannerWeighting = common.PlannerWeightingNone
+	}
+
+	if val, ok := settings["server_group_aware_planner"]; ok {
+		p.rebalanceConfig.ServerGroupAwarePlanner = val.(bool)
+	} else {
+		p.rebalanceConfig.ServerGroupAwarePlanner = false
+	}
+
 	if val, ok := settings["vb_ownership_giveup_routine_count"]; ok {
 		p.rebalanceConfig.VBOwnershipGiveUpRoutineCount = int(val.(float64))
 	} else {
@@ -285,6 +378,12 @@ func (p *Producer) parseDepcfg() error {
 		p.rebalanceConfig.VBOwnershipTakeoverRoutineCount = 3
 	}
 
+	if val, ok := settings["worker_vb_rebalance_interval"]; ok {
+		p.rebalanceConfig.WorkerVbRebalanceInterval = int(val.(float64))
+	} else {
+		p.rebalanceConfig.WorkerVbRebalanceInterval = 0
+	}
+
 	// Application logging related configurations
 
 	if val, ok := settings["app_log_dir"]; ok {
@@ -53,6 +53,18 @@ func (p *Producer) GetLatencyStats() map[string]uint64 {
 	return latencyStats
 }
 
+// GetTimerLatenessStats returns timer lateness histogram aggregated from Eventing.Consumer instances
+func (p *Producer) GetTimerLatenessStats() map[string]uint64 {
+	latenessStats := make(map[string]uint64)
+
+	for _, c := range p.getConsumers() {
+		for k, v := range c.GetTimerLatenessStats() {
+			latenessStats[k] += v
+		}
+	}
+	return latenessStats
+}
+
 // GetExecutionStats returns execution stats aggregated from Eventing.Consumer instances
 func (p *Producer) GetExecutionStats() map[string]interface{} {
 	executionStats := make(map[string]interface{})
@@ -115,11 +127,45 @@ func (p *Producer) GetLcbExceptionsStats() map[string]uint64 {
 	return exceptionStats
 }
 
+// GetWorkerStats returns stat counters for each running Eventing.Consumer instance, keyed by worker name
+func (p *Producer) GetWorkerStats() map[string]*common.WorkerStats {
+	workerStats := make(map[string]*common.WorkerStats)
+
+	for _, c := range p.getConsumers() {
+		workerStats[c.ConsumerName()] = &common.WorkerStats{
+			ExecutionStats:       c.GetExecutionStats(),
+			FailureStats:         c.GetFailureStats(),
+			LatencyStats:         c.GetLatencyStats(),
+			LcbExceptionStats:    c.GetLcbExceptionsStats(),
+			MetaStoreStats:       c.GetMetaStoreStats(),
+			TimerLatenessStats:   c.GetTimerLatenessStats(),
+			VbDcpEventsRemaining: c.VbDcpEventsRemainingToProcess(),
+		}
+	}
+
+	return workerStats
+}
+
 // GetAppCode returns handler code for the current app
 func (p *Producer) GetAppCode() string {
 	return p.app.AppCode
 }
 
+// GetDcpEventsProcessedPSecPerVb returns dcp events processed per second across all consumers,
+// per vbucket streamed by them, so that rate doesn't grow with share of vbuckets owned by the node
+func (p *Producer) GetDcpEventsProcessedPSecPerVb() float64 {
+	var processed, vbs int
+	for _, consumer := range p.getConsumers() {
+		processed += consumer.EventsProcessedPSec().DcpEventsProcessedPSec
+		vbs += len(consumer.InternalVbDistributionStats())
+	}
+
+	if vbs == 0 {
+		return 0
+	}
+	return float64(processed) / float64(vbs)
+}
+
 // GetEventProcessingStats exposes dcp/timer processing stats
 func (p *Producer) GetEventProcessingStats() map[string]uint64 {
 	aggStats := make(map[string]uint64)
@@ -138,6 +184,14 @@ func (p *Producer) GetEventProcessingStats() map[string]uint64 {
 		aggStats["WORKER_SPAWN_COUNTER"] = p.workerSpawnCounter
 	}
 
+	if kills := atomic.LoadUint64(&p.memoryLimitKillCounter); kills > 0 {
+		aggStats["MEMORY_LIMIT_KILL_COUNTER"] = kills
+	}
+
+	if failures := atomic.LoadUint64(&p.resourceLimitFailureCounter); failures > 0 {
+		aggStats["RESOURCE_LIMIT_FAILURE_COUNTER"] = failures
+	}
+
 	return aggStats
 }
 
@@ -795,6 +849,13 @@ func (p *Producer) TimerDebugStats() map[int]map[string]interface{} {
 				aggStats[vb]["timers_in_past_counter"] = timersInPastCounter
 				aggStats[vb]["timers_in_past_from_backfill_counter"] = timersInPastFromBackfill
 				aggStats[vb]["timers_recreated_from_dcp_backfill"] = timersCreatedFromBackfill
+
+				// Timer backlog gauges are reported by the worker owning the vbucket alone
+				for stat, value := range stats {
+					if _, ok := aggStats[vb][stat]; !ok {
+						aggStats[vb][stat] = value
+					}
+				}
 			}
 		}
 	}
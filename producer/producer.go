@@ -45,6 +45,7 @@ func NewProducer(appName, debuggerPort, eventingPort, eventingSSLPort, eventingD
 		plannerNodeMappingsRWMutex: &sync.RWMutex{},
 		MemoryQuota:                memoryQuota,
 		pollBucketStopCh:           make(chan struct{}, 1),
+		resourceLimitsStopCh:       make(chan struct{}, 1),
 		retryCount:                 -1,
 		runningConsumersRWMutex:    &sync.RWMutex{},
 		seqsNoProcessed:            make(map[int]int64),
@@ -61,6 +62,7 @@ func NewProducer(appName, debuggerPort, eventingPort, eventingSSLPort, eventingD
 		workerNameConsumerMap:        make(map[string]common.EventingConsumer),
 		workerNameConsumerMapRWMutex: &sync.RWMutex{},
 		workerVbMapRWMutex:           &sync.RWMutex{},
+		workerVbRebalanceStopCh:      make(chan struct{}, 1),
 		handlerConfig:                &common.HandlerConfig{},
 		processConfig:                &common.ProcessConfig{},
 		rebalanceConfig:              &common.RebalanceConfig{},
@@ -177,6 +179,14 @@ func (p *Producer) Serve() {
 
 	go p.updateStats()
 
+	if p.rebalanceConfig.WorkerVbRebalanceInterval > 0 {
+		go p.rebalanceWorkerVbs()
+	}
+
+	if p.handlerConfig.CPUShares > 0 || p.handlerConfig.MemoryLimit > 0 {
+		go p.enforceResourceLimits()
+	}
+
 	// Inserting twice because producer can be stopped either because of pause/undeploy
 	for i := 0; i < 2; i++ {
 		p.notifyInitCh <- struct{}{}
@@ -249,13 +259,23 @@ func (p *Producer) Serve() {
 			logging.SetLogLevel(util.GetLogLevel(logLevel))
 			p.updateAppLogSetting(settings)
 
+			// Consumers spawned later on, e.g. during rebalance, should start with updated constants
+			if constants, err := util.ConstantsToJSON(settings["constants"]); err == nil {
+				p.handlerConfig.Constants = constants
+			}
+
 		case <-p.pauseProducerCh:
 
 			// This routine cleans up everything apart from metadataBucketHandle,
 			// which would be needed to clean up metadata bucket
 			logging.Infof("%s [%s:%d] Pausing processing", logPrefix, p.appName, p.LenRunningConsumers())
 
+			// Checkpoints are retained, so that resume could start streams from last processed seq no
 			for _, c := range p.getConsumers() {
+				if err := c.CheckpointAndReleaseVbs(); err != nil {
+					logging.Errorf("%s [%s:%d] Consumer: %s failed to checkpoint owned vbs, err: %v",
+						logPrefix, p.appName, p.LenRunningConsumers(), c.ConsumerName(), err)
+				}
 				p.stopAndDeleteConsumer(c)
 			}
 
@@ -380,6 +400,14 @@ func (p *Producer) Stop() {
 		p.pollBucketStopCh <- struct{}{}
 	}
 
+	if p.workerVbRebalanceStopCh != nil {
+		p.workerVbRebalanceStopCh <- struct{}{}
+	}
+
+	if p.resourceLimitsStopCh != nil {
+		p.resourceLimitsStopCh <- struct{}{}
+	}
+
 	logging.Infof("%s [%s:%d] Exiting from Producer::Stop routine",
 		logPrefix, p.appName, p.LenRunningConsumers())
 }
@@ -0,0 +1,139 @@
+package producer
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/couchbase/eventing/logging"
+	"github.com/couchbase/eventing/util"
+)
+
+// resourceLimiter confines eventing-consumer processes of a function, implementation is platform specific
+type resourceLimiter interface {
+	// attach places the process under function's cpu and memory limits
+	attach(pid int) error
+
+	// processMemory returns resident memory of the process in bytes
+	processMemory(pid int) (uint64, error)
+
+	// kernelKills returns count of processes the OS killed for exceeding memory limit
+	kernelKills() uint64
+
+	release()
+}
+
+// enforceResourceLimits applies cpu_shares and memory_limit settings to eventing-consumer processes
+// as they get spawned, and kills the largest one when function's memory footprint exceeds the cap.
+// Killed workers get respawned via KillAndRespawnEventingConsumer, same as any other worker crash.
+func (p *Producer) enforceResourceLimits() {
+	logPrefix := "Producer::enforceResourceLimits"
+
+	// Limiter is usable even on error, it then falls back to weaker OS level controls
+	limiter, err := newResourceLimiter(p.appName, p.handlerConfig.CPUShares, p.handlerConfig.MemoryLimit)
+	if err != nil {
+		atomic.AddUint64(&p.resourceLimitFailureCounter, 1)
+		logging.Errorf("%s [%s:%d] Failed to set up cgroup, falling back to process priority and memory polling, err: %v",
+			logPrefix, p.appName, p.LenRunningConsumers(), err)
+	}
+
+	logging.Infof("%s [%s:%d] Enforcing cpu shares: %d memory limit: %d bytes",
+		logPrefix, p.appName, p.LenRunningConsumers(), p.handlerConfig.CPUShares, p.handlerConfig.MemoryLimit)
+
+	ticker := time.NewTicker(resourceLimitCheckInterval)
+	limitedPids := make(map[int]struct{})
+
+	defer func() {
+		ticker.Stop()
+		limiter.release()
+	}()
+
+	for {
+		select {
+		case <-ticker.C:
+			p.checkResourceLimits(limiter, limitedPids)
+
+		case <-p.resourceLimitsStopCh:
+			logging.Infof("%s [%s:%d] Stopped enforcing resource limits",
+				logPrefix, p.appName, p.LenRunningConsumers())
+			return
+		}
+	}
+}
+
+func (p *Producer) checkResourceLimits(limiter resourceLimiter, limitedPids map[int]struct{}) {
+	logPrefix := "Producer::checkResourceLimits"
+
+	workerPids := p.GetEventingConsumerPids()
+
+	alivePids := make(map[int]struct{})
+	for workerName, pid := range workerPids {
+		if pid <= 0 {
+			continue
+		}
+		alivePids[pid] = struct{}{}
+
+		if _, ok := limitedPids[pid]; ok {
+			continue
+		}
+
+		if err := limiter.attach(pid); err != nil {
+			atomic.AddUint64(&p.resourceLimitFailureCounter, 1)
+			logging.Errorf("%s [%s:%d] Failed to apply resource limits to worker: %s pid: %d, err: %v",
+				logPrefix, p.appName, p.LenRunningConsumers(), workerName, pid, err)
+		}
+		limitedPids[pid] = struct{}{}
+	}
+
+	for pid := range limitedPids {
+		if _, ok := alivePids[pid]; !ok {
+			delete(limitedPids, pid)
+		}
+	}
+
+	if kills := limiter.kernelKills(); kills > p.memoryLimitKernelKills {
+		atomic.AddUint64(&p.memoryLimitKillCounter, kills-p.memoryLimitKernelKills)
+		p.memoryLimitKernelKills = kills
+		logging.Errorf("%s [%s:%d] Workers killed by OS for exceeding memory limit: %d bytes, total: %d",
+			logPrefix, p.appName, p.LenRunningConsumers(), p.handlerConfig.MemoryLimit, kills)
+	}
+
+	if p.handlerConfig.MemoryLimit <= 0 {
+		return
+	}
+
+	var total, largest uint64
+	var largestPid int
+	var largestWorker string
+
+	for workerName, pid := range workerPids {
+		if pid <= 0 {
+			continue
+		}
+
+		rss, err := limiter.processMemory(pid)
+		if err != nil {
+			logging.Tracef("%s [%s:%d] Failed to read memory usage of worker: %s pid: %d, err: %v",
+				logPrefix, p.appName, p.LenRunningConsumers(), workerName, pid, err)
+			continue
+		}
+
+		total += rss
+		if rss > largest {
+			largest, largestPid, largestWorker = rss, pid, workerName
+		}
+	}
+
+	if total <= uint64(p.handlerConfig.MemoryLimit) || largestPid == 0 {
+		return
+	}
+
+	logging.Errorf("%s [%s:%d] Memory usage: %d bytes exceeds limit: %d bytes, killing worker: %s pid: %d rss: %d bytes",
+		logPrefix, p.appName, p.LenRunningConsumers(), total, p.handlerConfig.MemoryLimit, largestWorker, largestPid, largest)
+
+	if err := util.KillProcess(largestPid); err != nil {
+		logging.Errorf("%s [%s:%d] Failed to kill worker: %s pid: %d, err: %v",
+			logPrefix, p.appName, p.LenRunningConsumers(), largestWorker, largestPid, err)
+		return
+	}
+	atomic.AddUint64(&p.memoryLimitKillCounter, 1)
+}
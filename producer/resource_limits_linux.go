@@ -0,0 +1,281 @@
+// +build linux
+
+package producer
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+const (
+	cgroupRoot = "/sys/fs/cgroup"
+
+	// cpu.shares bounds enforced by cgroup v1, cpu_shares setting uses the same scale
+	cgroupMinCPUShares     = 2
+	cgroupMaxCPUShares     = 262144
+	cgroupDefaultCPUShares = 1024
+)
+
+// cgroupLimiter places workers in a per function cgroup. When cgroups aren't writable
+// it degrades to lowering process priority for cpu_shares, with memory_limit then
+// enforced solely by producer's polling of worker RSS.
+type cgroupLimiter struct {
+	cpuShares   int
+	memoryLimit int64
+
+	// Directories workers get attached to, cpu and memory hierarchies
+	// are the same directory under cgroup v2
+	cpuDir    string
+	memoryDir string
+	unified   bool
+}
+
+func newResourceLimiter(appName string, cpuShares int, memoryLimit int64) (resourceLimiter, error) {
+	l := &cgroupLimiter{
+		cpuShares:   cpuShares,
+		memoryLimit: memoryLimit,
+	}
+
+	var err error
+	if _, statErr := os.Stat(filepath.Join(cgroupRoot, "cgroup.controllers")); statErr == nil {
+		l.unified = true
+		err = l.setupV2(appName)
+	} else {
+		err = l.setupV1(appName)
+	}
+
+	if err != nil {
+		l.release()
+		l.cpuDir, l.memoryDir = "", ""
+		return l, err
+	}
+	return l, nil
+}
+
+func (l *cgroupLimiter) setupV2(appName string) error {
+	parent, err := selfCgroupPath("")
+	if err != nil {
+		return err
+	}
+
+	parentDir := filepath.Join(cgroupRoot, parent)
+
+	// Controllers may already be enabled by the service manager, failure is caught
+	// below when limits are written
+	ioutil.WriteFile(filepath.Join(parentDir, "cgroup.subtree_control"), []byte("+cpu +memory"), 0644)
+
+	dir := filepath.Join(parentDir, "eventing-"+appName)
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	l.cpuDir, l.memoryDir = dir, dir
+
+	if l.cpuShares > 0 {
+		weight := 1 + ((clampCPUShares(l.cpuShares)-cgroupMinCPUShares)*9999)/(cgroupMaxCPUShares-cgroupMinCPUShares)
+		if err = writeCgroupFile(dir, "cpu.weight", strconv.Itoa(weight)); err != nil {
+			return err
+		}
+	}
+
+	if l.memoryLimit > 0 {
+		if err = writeCgroupFile(dir, "memory.max", strconv.FormatInt(l.memoryLimit, 10)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (l *cgroupLimiter) setupV1(appName string) error {
+	if l.cpuShares > 0 {
+		dir, err := v1CgroupDir(appName, "cpu")
+		if err != nil {
+			return err
+		}
+		l.cpuDir = dir
+
+		if err = writeCgroupFile(dir, "cpu.shares", strconv.Itoa(clampCPUShares(l.cpuShares))); err != nil {
+			return err
+		}
+	}
+
+	if l.memoryLimit > 0 {
+		dir, err := v1CgroupDir(appName, "memory")
+		if err != nil {
+			return err
+		}
+		l.memoryDir = dir
+
+		if err = writeCgroupFile(dir, "memory.limit_in_bytes", strconv.FormatInt(l.memoryLimit, 10)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (l *cgroupLimiter) attach(pid int) error {
+	if l.cpuDir == "" && l.memoryDir == "" {
+		return l.renice(pid)
+	}
+
+	for _, dir := range []string{l.cpuDir, l.memoryDir} {
+		if dir == "" {
+			continue
+		}
+		if err := writeCgroupFile(dir, "cgroup.procs", strconv.Itoa(pid)); err != nil {
+			return err
+		}
+		if l.unified {
+			break
+		}
+	}
+	return nil
+}
+
+// renice approximates cpu_shares through scheduler priority, each nice level
+// being worth roughly 1.25x cpu time relative to its neighbour
+func (l *cgroupLimiter) renice(pid int) error {
+	if l.cpuShares <= 0 {
+		return nil
+	}
+
+	nice := int(math.Floor(math.Log(float64(cgroupDefaultCPUShares)/float64(clampCPUShares(l.cpuShares)))/math.Log(1.25) + 0.5))
+	if nice < 0 {
+		nice = 0
+	}
+	if nice > 19 {
+		nice = 19
+	}
+
+	return syscall.Setpriority(syscall.PRIO_PROCESS, pid, nice)
+}
+
+func (l *cgroupLimiter) processMemory(pid int) (uint64, error) {
+	data, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/statm", pid))
+	if err != nil {
+		return 0, err
+	}
+
+	fields := strings.Fields(string(data))
+	if len(fields) < 2 {
+		return 0, fmt.Errorf("unexpected statm format: %s", data)
+	}
+
+	pages, err := strconv.ParseUint(fields[1], 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	return pages * uint64(os.Getpagesize()), nil
+}
+
+func (l *cgroupLimiter) kernelKills() uint64 {
+	if l.memoryDir == "" {
+		return 0
+	}
+
+	file := "memory.oom_control"
+	if l.unified {
+		file = "memory.events"
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(l.memoryDir, file))
+	if err != nil {
+		return 0
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[0] == "oom_kill" {
+			kills, _ := strconv.ParseUint(fields[1], 10, 64)
+			return kills
+		}
+	}
+	return 0
+}
+
+// release removes function's cgroups, which only succeeds once all workers have exited
+func (l *cgroupLimiter) release() {
+	for _, dir := range []string{l.cpuDir, l.memoryDir} {
+		if dir != "" {
+			os.Remove(dir)
+		}
+	}
+}
+
+// selfCgroupPath returns cgroup of the current process for the given v1 controller,
+// or the unified hierarchy path when controller is empty
+func selfCgroupPath(controller string) (string, error) {
+	data, err := ioutil.ReadFile("/proc/self/cgroup")
+	if err != nil {
+		return "", err
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		// Format: hierarchy-id:controller-list:path
+		parts := strings.SplitN(scanner.Text(), ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+
+		if controller == "" {
+			if parts[0] == "0" && parts[1] == "" {
+				return parts[2], nil
+			}
+			continue
+		}
+
+		for _, c := range strings.Split(parts[1], ",") {
+			if c == controller {
+				return parts[2], nil
+			}
+		}
+	}
+	return "", fmt.Errorf("cgroup for controller: %q not found", controller)
+}
+
+func v1CgroupDir(appName, controller string) (string, error) {
+	self, err := selfCgroupPath(controller)
+	if err != nil {
+		return "", err
+	}
+
+	// Hierarchy could be mounted as "cpu", "cpu,cpuacct" or "cpuacct,cpu"
+	mounts, _ := filepath.Glob(filepath.Join(cgroupRoot, "*"))
+	for _, mount := range mounts {
+		for _, c := range strings.Split(filepath.Base(mount), ",") {
+			if c != controller {
+				continue
+			}
+
+			dir := filepath.Join(mount, self, "eventing-"+appName)
+			if err = os.MkdirAll(dir, 0755); err != nil {
+				return "", err
+			}
+			return dir, nil
+		}
+	}
+	return "", fmt.Errorf("cgroup hierarchy for controller: %q not mounted", controller)
+}
+
+func writeCgroupFile(dir, file, value string) error {
+	return ioutil.WriteFile(filepath.Join(dir, file), []byte(value), 0644)
+}
+
+func clampCPUShares(shares int) int {
+	if shares < cgroupMinCPUShares {
+		return cgroupMinCPUShares
+	}
+	if shares > cgroupMaxCPUShares {
+		return cgroupMaxCPUShares
+	}
+	return shares
+}
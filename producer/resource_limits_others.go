@@ -0,0 +1,31 @@
+// +build !linux
+
+package producer
+
+import (
+	"errors"
+	"runtime"
+)
+
+var errResourceLimitsUnsupported = errors.New("cpu and memory limits aren't supported on " + runtime.GOOS)
+
+// noopLimiter leaves workers unconfined, as there is no portable way to read their memory usage
+type noopLimiter struct{}
+
+func newResourceLimiter(appName string, cpuShares int, memoryLimit int64) (resourceLimiter, error) {
+	return noopLimiter{}, errResourceLimitsUnsupported
+}
+
+func (noopLimiter) attach(pid int) error {
+	return nil
+}
+
+func (noopLimiter) processMemory(pid int) (uint64, error) {
+	return 0, errResourceLimitsUnsupported
+}
+
+func (noopLimiter) kernelKills() uint64 {
+	return 0
+}
+
+func (noopLimiter) release() {}
@@ -0,0 +1,162 @@
+package producer
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/couchbase/eventing/common"
+	"github.com/couchbase/eventing/logging"
+	"github.com/couchbase/eventing/timers"
+	"github.com/couchbase/eventing/util"
+)
+
+// Length of user supplied timer context returned while listing timers
+const timerContextPreviewLength = 256
+
+func (p *Producer) kvConnStr() string {
+	connStr := "couchbase://" + strings.Join(p.KvHostPorts(), ",")
+
+	if util.IsIPv6() {
+		connStr += "?ipv6=allow"
+	}
+	return connStr
+}
+
+func (p *Producer) timerQueryVbs(query *common.TimerQuery) ([]uint16, error) {
+	if len(query.Vbs) == 0 {
+		vbs := make([]uint16, 0, p.numVbuckets)
+		for vb := 0; vb < p.numVbuckets; vb++ {
+			vbs = append(vbs, uint16(vb))
+		}
+		return vbs, nil
+	}
+
+	for _, vb := range query.Vbs {
+		if int(vb) >= p.numVbuckets {
+			return nil, fmt.Errorf("vb: %d out of range, function has %d vbuckets", vb, p.numVbuckets)
+		}
+	}
+	return query.Vbs, nil
+}
+
+// openTimerStore prefers store of vbucket owned by the current node, as its span may
+// not have been persisted yet
+func (p *Producer) openTimerStore(vb uint16) (*timers.TimerStore, error) {
+	if store, found := timers.Fetch(p.GetMetadataPrefix(), int(vb)); found {
+		return store, nil
+	}
+	return timers.Open(p.GetMetadataPrefix(), int(vb), p.kvConnStr(), p.metadatabucket)
+}
+
+// scanTimers invokes cb for every timer selected by query, until it returns false
+func (p *Producer) scanTimers(query *common.TimerQuery,
+	cb func(store *timers.TimerStore, vb uint16, entry *timers.TimerEntry) (bool, error)) error {
+
+	if !p.app.UsingTimer {
+		return fmt.Errorf("function doesn't use timers")
+	}
+
+	vbs, err := p.timerQueryVbs(query)
+	if err != nil {
+		return err
+	}
+
+	for _, vb := range vbs {
+		store, err := p.openTimerStore(vb)
+		if err != nil {
+			return err
+		}
+
+		iterator := store.ScanRange(query.From, query.To)
+		for iterator != nil {
+			entry, err := iterator.ScanNext()
+			if err != nil {
+				return err
+			}
+			if entry == nil {
+				break
+			}
+
+			more, err := cb(store, vb, entry)
+			if err != nil {
+				return err
+			}
+			if !more {
+				return nil
+			}
+		}
+	}
+	return nil
+}
+
+// ListTimers returns pending timers selected by query, in order of vbucket and due time
+func (p *Producer) ListTimers(query *common.TimerQuery) ([]*common.TimerRecord, error) {
+	records := make([]*common.TimerRecord, 0)
+
+	err := p.scanTimers(query, func(store *timers.TimerStore, vb uint16, entry *timers.TimerEntry) (bool, error) {
+		record := &common.TimerRecord{
+			Vb:  vb,
+			Due: entry.AlarmDue,
+		}
+
+		// Context is as stored by eventing-consumer, with user supplied context and reference nested within
+		if ctx, ok := entry.Context.(map[string]interface{}); ok {
+			record.Callback, _ = ctx["callback"].(string)
+			record.Reference, _ = ctx["reference"].(string)
+			record.ContextPreview, _ = ctx["context"].(string)
+		}
+		if len(record.ContextPreview) > timerContextPreviewLength {
+			record.ContextPreview = record.ContextPreview[:timerContextPreviewLength]
+		}
+
+		records = append(records, record)
+		return query.Limit <= 0 || len(records) < query.Limit, nil
+	})
+
+	return records, err
+}
+
+// CancelTimers cancels timer of the given callback and reference, or else all timers
+// selected by query. Returns number of timers cancelled, a reference is counted as one
+// even if its timer had already fired.
+func (p *Producer) CancelTimers(req *common.TimerCancelRequest) (int, error) {
+	logPrefix := "Producer::CancelTimers"
+
+	if req.Reference != "" {
+		if len(req.Vbs) != 1 {
+			return 0, fmt.Errorf("vb of the timer is required to cancel it by reference")
+		}
+
+		vbs, err := p.timerQueryVbs(&req.TimerQuery)
+		if err != nil {
+			return 0, err
+		}
+
+		store, err := p.openTimerStore(vbs[0])
+		if err != nil {
+			return 0, err
+		}
+
+		// eventing-consumer stores timers with callback qualified reference
+		if err = store.Cancel(req.Callback + ":" + req.Reference); err != nil {
+			return 0, err
+		}
+
+		logging.Infof("%s [%s:%d] vb: %d Cancelled timer of callback: %s reference: %ru",
+			logPrefix, p.appName, p.LenRunningConsumers(), vbs[0], req.Callback, req.Reference)
+		return 1, nil
+	}
+
+	cancelled := 0
+	err := p.scanTimers(&req.TimerQuery, func(store *timers.TimerStore, vb uint16, entry *timers.TimerEntry) (bool, error) {
+		if err := store.CancelToken(store.GetToken(entry)); err != nil {
+			return false, err
+		}
+		cancelled++
+		return true, nil
+	})
+
+	logging.Infof("%s [%s:%d] Cancelled %d timers due between %d and %d, err: %v",
+		logPrefix, p.appName, p.LenRunningConsumers(), cancelled, req.From, req.To, err)
+	return cancelled, err
+}
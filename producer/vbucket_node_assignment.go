@@ -89,40 +89,50 @@ func (p *Producer) vbEventingNodeAssign() error {
 	logging.Infof("%s [%s:%d] EventingNodeUUIDs: %v eventingNodeAddrs: %rs",
 		logPrefix, p.appName, p.LenRunningConsumers(), p.eventingNodeUUIDs, eventingNodeAddrs)
 
-	vbucketsPerNode := p.numVbuckets / len(eventingNodeAddrs)
-	var vbNo int
+	var nodeGroups map[string]string
+	if p.rebalanceConfig.ServerGroupAwarePlanner {
+		err = util.Retry(util.NewFixedBackoff(time.Second), &p.retryCount, getEventingNodesServerGroupsOpCallback, p, &nodeGroups)
+		if err == common.ErrRetryTimeout {
+			logging.Errorf("%s [%s:%d] Exiting due to timeout", logPrefix, p.appName, p.LenRunningConsumers())
+			return common.ErrRetryTimeout
+		}
+	}
+
+	var nodeWeights map[string]float64
+	if p.rebalanceConfig.PlannerWeighting != common.PlannerWeightingNone {
+		nodeWeights, err = p.getNodeWeights(eventingNodeAddrs, addrUUIDMap)
+		if err != nil {
+			return err
+		}
+	}
+
+	var vbCountPerNode []int
+	if nodeGroups != nil {
+		eventingNodeAddrs, vbCountPerNode = serverGroupVbDistribution(p.numVbuckets, eventingNodeAddrs, nodeGroups, nodeWeights)
+	} else {
+		vbCountPerNode = weightedVbDistribution(p.numVbuckets, eventingNodeAddrs, nodeWeights)
+	}
+
 	var startVb uint16
 
 	p.vbEventingNodeAssignRWMutex.Lock()
 	defer p.vbEventingNodeAssignRWMutex.Unlock()
 	p.vbEventingNodeAssignMap = make(map[uint16]string)
 
-	vbCountPerNode := make([]int, len(eventingNodeAddrs))
-	for i := 0; i < len(eventingNodeAddrs); i++ {
-		vbCountPerNode[i] = vbucketsPerNode
-		vbNo += vbucketsPerNode
-	}
-
-	remainingVbs := p.numVbuckets - vbNo
-	if remainingVbs > 0 {
-		for i := 0; i < remainingVbs; i++ {
-			vbCountPerNode[i] = vbCountPerNode[i] + 1
-		}
-	}
-
 	p.plannerNodeMappingsRWMutex.Lock()
 	defer p.plannerNodeMappingsRWMutex.Unlock()
 	p.plannerNodeMappings = make([]*common.PlannerNodeVbMapping, 0)
 
 	for i, v := range vbCountPerNode {
 
-		logging.Infof("%s [%s:%d] EventingNodeUUIDs: %v Eventing node index: %d eventing node addr: %rs startVb: %v vbs count: %v",
-			logPrefix, p.appName, p.LenRunningConsumers(), p.eventingNodeUUIDs, i, eventingNodeAddrs[i], startVb, v)
+		logging.Infof("%s [%s:%d] EventingNodeUUIDs: %v Eventing node index: %d eventing node addr: %rs server group: %rs startVb: %v vbs count: %v",
+			logPrefix, p.appName, p.LenRunningConsumers(), p.eventingNodeUUIDs, i, eventingNodeAddrs[i], nodeGroups[eventingNodeAddrs[i]], startVb, v)
 
 		nodeMapping := &common.PlannerNodeVbMapping{
-			Hostname: eventingNodeAddrs[i],
-			StartVb:  int(startVb),
-			VbsCount: v,
+			Hostname:    eventingNodeAddrs[i],
+			ServerGroup: nodeGroups[eventingNodeAddrs[i]],
+			StartVb:     int(startVb),
+			VbsCount:    v,
 		}
 		p.plannerNodeMappings = append(p.plannerNodeMappings, nodeMapping)
 
@@ -144,6 +154,189 @@ func (p *Producer) vbEventingNodeAssign() error {
 	return nil
 }
 
+// evenVbDistribution splits vbuckets evenly across n owners, leftover vbuckets
+// go to owners at the start
+func evenVbDistribution(numVbuckets, n int) []int {
+	vbCounts := make([]int, n)
+	for i := 0; i < n; i++ {
+		vbCounts[i] = numVbuckets / n
+	}
+
+	for i := 0; i < numVbuckets%n; i++ {
+		vbCounts[i]++
+	}
+
+	return vbCounts
+}
+
+// weightedVbDistribution splits vbuckets across nodes in proportion to their weights,
+// using largest remainder to hand out leftover vbuckets. Falls back to even split
+// when weights aren't available.
+func weightedVbDistribution(numVbuckets int, nodes []string, nodeWeights map[string]float64) []int {
+	if nodeWeights == nil {
+		return evenVbDistribution(numVbuckets, len(nodes))
+	}
+
+	var totalWeight float64
+	for _, node := range nodes {
+		totalWeight += nodeWeights[node]
+	}
+
+	if totalWeight <= 0 {
+		return evenVbDistribution(numVbuckets, len(nodes))
+	}
+
+	vbCounts := make([]int, len(nodes))
+	remainders := make([]float64, len(nodes))
+	assigned := 0
+
+	for i, node := range nodes {
+		share := float64(numVbuckets) * nodeWeights[node] / totalWeight
+		vbCounts[i] = int(share)
+		remainders[i] = share - float64(vbCounts[i])
+		assigned += vbCounts[i]
+	}
+
+	// Ties are broken by node order, so that every producer ends up with the same plan
+	for ; assigned < numVbuckets; assigned++ {
+		next := 0
+		for i := range remainders {
+			if remainders[i] > remainders[next] {
+				next = i
+			}
+		}
+		vbCounts[next]++
+		remainders[next] = -1
+	}
+
+	return vbCounts
+}
+
+// getNodeWeights returns weight of each eventing node as per configured planner weighting,
+// using node capacity sampled by service manager during last topology change. Nodes
+// without a sample are weighed as the average of sampled ones.
+func (p *Producer) getNodeWeights(eventingNodeAddrs []string, addrUUIDMap map[string]string) (map[string]float64, error) {
+	logPrefix := "Producer::getNodeWeights"
+
+	// Weights are missing till first topology change after upgrade, hence lookup isn't retried
+	data, err := util.MetakvGet(metakvConfigNodeWeights)
+	if err != nil {
+		logging.Errorf("%s [%s:%d] Failed to lookup node weights from metakv, err: %v",
+			logPrefix, p.appName, p.LenRunningConsumers(), err)
+		return nil, err
+	}
+
+	if len(data) == 0 {
+		logging.Warnf("%s [%s:%d] Node weights not available, planning with equal weights",
+			logPrefix, p.appName, p.LenRunningConsumers())
+		return nil, nil
+	}
+
+	var uuidWeights map[string]*common.NodeWeight
+	err = json.Unmarshal(data, &uuidWeights)
+	if err != nil {
+		logging.Errorf("%s [%s:%d] Failed to unmarshal node weights received from metakv, err: %v",
+			logPrefix, p.appName, p.LenRunningConsumers(), err)
+		return nil, err
+	}
+
+	addrWeights := make(map[string]float64)
+	for uuid, addr := range addrUUIDMap {
+		weight, ok := uuidWeights[uuid]
+		if !ok {
+			continue
+		}
+
+		switch p.rebalanceConfig.PlannerWeighting {
+		case common.PlannerWeightingCPUCount:
+			addrWeights[addr] = float64(weight.CPUCount)
+		case common.PlannerWeightingThroughput:
+			addrWeights[addr] = weight.DcpEventsProcessedPSecPerVb
+		}
+	}
+
+	var sampled int
+	var totalWeight float64
+	for _, addr := range eventingNodeAddrs {
+		if weight, ok := addrWeights[addr]; ok && weight > 0 {
+			sampled++
+			totalWeight += weight
+		}
+	}
+
+	if sampled == 0 {
+		logging.Warnf("%s [%s:%d] No eventing node has %s weight sampled, planning with equal weights",
+			logPrefix, p.appName, p.LenRunningConsumers(), p.rebalanceConfig.PlannerWeighting)
+		return nil, nil
+	}
+
+	nodeWeights := make(map[string]float64)
+	for _, addr := range eventingNodeAddrs {
+		if weight, ok := addrWeights[addr]; ok && weight > 0 {
+			nodeWeights[addr] = weight
+		} else {
+			nodeWeights[addr] = totalWeight / float64(sampled)
+		}
+	}
+
+	logging.Infof("%s [%s:%d] Planner weighting: %s node weights: %rs",
+		logPrefix, p.appName, p.LenRunningConsumers(), p.rebalanceConfig.PlannerWeighting, fmt.Sprintf("%v", nodeWeights))
+
+	return nodeWeights, nil
+}
+
+// serverGroupVbDistribution splits vbuckets across server groups hosting eventing
+// nodes in proportion to the combined weight of their nodes, or to their node count
+// when weights aren't supplied, and then across nodes within each group likewise.
+// Nodes are returned ordered by group, so that every group owns a contiguous
+// vbucket range. Groups that have no surviving eventing node get no share, which
+// spreads work owned by a failed group over the remaining ones.
+func serverGroupVbDistribution(numVbuckets int, eventingNodeAddrs []string,
+	nodeGroups map[string]string, nodeWeights map[string]float64) ([]string, []int) {
+
+	groupNodes := make(map[string][]string)
+	for _, addr := range eventingNodeAddrs {
+		group := nodeGroups[addr]
+		groupNodes[group] = append(groupNodes[group], addr)
+	}
+
+	groups := make([]string, 0, len(groupNodes))
+	for group := range groupNodes {
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+
+	// Weighing groups by their nodes keeps nodes of smaller groups from being overloaded
+	groupWeights := make(map[string]float64, len(groups))
+	var totalWeight float64
+	for group, nodes := range groupNodes {
+		for _, node := range nodes {
+			if nodeWeights != nil {
+				groupWeights[group] += nodeWeights[node]
+			}
+		}
+		totalWeight += groupWeights[group]
+	}
+	if totalWeight <= 0 {
+		for group, nodes := range groupNodes {
+			groupWeights[group] = float64(len(nodes))
+		}
+	}
+
+	orderedAddrs := make([]string, 0, len(eventingNodeAddrs))
+	vbCountPerNode := make([]int, 0, len(eventingNodeAddrs))
+
+	for i, groupVbs := range weightedVbDistribution(numVbuckets, groups, groupWeights) {
+		nodes := groupNodes[groups[i]]
+		sort.Strings(nodes)
+
+		orderedAddrs = append(orderedAddrs, nodes...)
+		vbCountPerNode = append(vbCountPerNode, weightedVbDistribution(groupVbs, nodes, nodeWeights)...)
+	}
+
+	return orderedAddrs, vbCountPerNode
+}
+
 func (p *Producer) vbNodeWorkerMap() {
 	logPrefix := "Producer::vbNodeWorkerMap"
 
@@ -0,0 +1,230 @@
+package producer
+
+import (
+	"sort"
+	"time"
+
+	"github.com/couchbase/eventing/common"
+	"github.com/couchbase/eventing/logging"
+	"github.com/couchbase/eventing/util"
+)
+
+type workerLoad struct {
+	workerName string
+	backlog    int64
+	vbBacklog  map[uint16]int64
+}
+
+// workerLoads sorts workers by backlog in descending order
+type workerLoads []*workerLoad
+
+func (s workerLoads) Len() int      { return len(s) }
+func (s workerLoads) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s workerLoads) Less(i, j int) bool {
+	if s[i].backlog == s[j].backlog {
+		return s[i].workerName < s[j].workerName
+	}
+	return s[i].backlog > s[j].backlog
+}
+
+// vbsByBacklog sorts vbuckets by backlog in descending order
+type vbsByBacklog struct {
+	vbs     []uint16
+	backlog map[uint16]int64
+}
+
+func (s vbsByBacklog) Len() int      { return len(s.vbs) }
+func (s vbsByBacklog) Swap(i, j int) { s.vbs[i], s.vbs[j] = s.vbs[j], s.vbs[i] }
+func (s vbsByBacklog) Less(i, j int) bool {
+	if s.backlog[s.vbs[i]] == s.backlog[s.vbs[j]] {
+		return s.vbs[i] < s.vbs[j]
+	}
+	return s.backlog[s.vbs[i]] > s.backlog[s.vbs[j]]
+}
+
+// rebalanceWorkerVbs periodically shifts vbuckets from the most backlogged local worker
+// to the least backlogged one, as static round robin split of vbuckets across workers
+// doesn't account for hot vbuckets
+func (p *Producer) rebalanceWorkerVbs() {
+	logPrefix := "Producer::rebalanceWorkerVbs"
+
+	interval := time.Duration(p.rebalanceConfig.WorkerVbRebalanceInterval) * time.Millisecond
+	ticker := time.NewTicker(interval)
+
+	logging.Infof("%s [%s:%d] Started worker vbucket rebalancer, interval: %v",
+		logPrefix, p.appName, p.LenRunningConsumers(), interval)
+
+	for {
+		select {
+		case <-ticker.C:
+			p.balanceWorkerVbs()
+
+		case <-p.workerVbRebalanceStopCh:
+			ticker.Stop()
+			logging.Infof("%s [%s:%d] Stopped worker vbucket rebalancer",
+				logPrefix, p.appName, p.LenRunningConsumers())
+			return
+		}
+	}
+}
+
+func (p *Producer) getWorkerLoads() workerLoads {
+	loads := make(workerLoads, 0)
+
+	for _, consumer := range p.getConsumers() {
+		load := &workerLoad{
+			workerName: consumer.ConsumerName(),
+			vbBacklog:  make(map[uint16]int64),
+		}
+
+		for vb, remaining := range consumer.VbDcpEventsRemainingToProcess() {
+			if remaining > 0 {
+				load.vbBacklog[uint16(vb)] = remaining
+				load.backlog += remaining
+			}
+		}
+
+		// Events already handed over to eventing-consumer but yet to be processed
+		load.backlog += int64(consumer.GetEventProcessingStats()["agg_queue_size"])
+
+		loads = append(loads, load)
+	}
+
+	sort.Sort(loads)
+
+	return loads
+}
+
+// pickVbsToMove selects vbuckets from hot worker whose combined backlog covers
+// at most half the gap to cold worker. Hottest vbucket is moved only if that
+// doesn't simply make cold worker the new hot one.
+func pickVbsToMove(hot, cold *workerLoad, assignedVbs []uint16) []uint16 {
+	target := (hot.backlog - cold.backlog) / 2
+
+	vbs := make([]uint16, 0, len(assignedVbs))
+	for _, vb := range assignedVbs {
+		if hot.vbBacklog[vb] > 0 {
+			vbs = append(vbs, vb)
+		}
+	}
+
+	sort.Sort(vbsByBacklog{vbs, hot.vbBacklog})
+
+	var moved int64
+	toMove := make([]uint16, 0)
+	for _, vb := range vbs {
+		if len(toMove) == workerVbRebalanceMaxMoves {
+			break
+		}
+
+		if moved+hot.vbBacklog[vb] > target {
+			continue
+		}
+
+		toMove = append(toMove, vb)
+		moved += hot.vbBacklog[vb]
+	}
+
+	return toMove
+}
+
+func (p *Producer) balanceWorkerVbs() {
+	logPrefix := "Producer::balanceWorkerVbs"
+
+	if p.isPlannerRunning || p.isTerminateRunning {
+		return
+	}
+
+	for _, consumer := range p.getConsumers() {
+		if consumer.RebalanceStatus() {
+			logging.Tracef("%s [%s:%d] Consumer: %s has vbucket ownership change in progress, skipping",
+				logPrefix, p.appName, p.LenRunningConsumers(), consumer.ConsumerName())
+			return
+		}
+	}
+
+	loads := p.getWorkerLoads()
+	if len(loads) < 2 {
+		return
+	}
+
+	hot, cold := loads[0], loads[len(loads)-1]
+	if hot.backlog < workerVbRebalanceMinBacklog || hot.backlog < workerVbRebalanceImbalanceRatio*cold.backlog {
+		return
+	}
+
+	p.workerVbMapRWMutex.Lock()
+
+	toMove := pickVbsToMove(hot, cold, p.workerVbucketMap[hot.workerName])
+	if len(toMove) == 0 {
+		p.workerVbMapRWMutex.Unlock()
+		logging.Infof("%s [%s:%d] Worker: %s backlog: %d can't be spread further, its backlog is held by a single vbucket",
+			logPrefix, p.appName, p.LenRunningConsumers(), hot.workerName, hot.backlog)
+		return
+	}
+
+	moving := make(map[uint16]struct{})
+	for _, vb := range toMove {
+		moving[vb] = struct{}{}
+	}
+
+	retainedVbs := make([]uint16, 0)
+	for _, vb := range p.workerVbucketMap[hot.workerName] {
+		if _, ok := moving[vb]; !ok {
+			retainedVbs = append(retainedVbs, vb)
+		}
+	}
+	p.workerVbucketMap[hot.workerName] = retainedVbs
+
+	coldVbs := append(append([]uint16(nil), p.workerVbucketMap[cold.workerName]...), toMove...)
+	sort.Sort(util.Uint16Slice(coldVbs))
+	p.workerVbucketMap[cold.workerName] = coldVbs
+
+	workerVbucketMap := make(map[string][]uint16)
+	for workerName, assignedVbs := range p.workerVbucketMap {
+		workerVbucketMap[workerName] = assignedVbs
+	}
+
+	p.workerVbMapRWMutex.Unlock()
+
+	func() {
+		p.vbMappingRWMutex.Lock()
+		defer p.vbMappingRWMutex.Unlock()
+
+		for _, vb := range toMove {
+			if info, ok := p.vbMapping[vb]; ok {
+				info.assignedWorker = cold.workerName
+			}
+		}
+	}()
+
+	sort.Sort(util.Uint16Slice(toMove))
+	logging.Infof("%s [%s:%d] Moving vbs len: %d dump: %s from worker: %s backlog: %d to worker: %s backlog: %d",
+		logPrefix, p.appName, p.LenRunningConsumers(), len(toMove), util.Condense(toMove),
+		hot.workerName, hot.backlog, cold.workerName, cold.backlog)
+
+	var hotConsumer, coldConsumer common.EventingConsumer
+	for _, consumer := range p.getConsumers() {
+		consumer.WorkerVbMapUpdate(workerVbucketMap)
+
+		switch consumer.ConsumerName() {
+		case hot.workerName:
+			hotConsumer = consumer
+		case cold.workerName:
+			coldConsumer = consumer
+		}
+	}
+
+	if hotConsumer == nil || coldConsumer == nil {
+		return
+	}
+
+	// Only streams of moved vbuckets are restarted. Cold worker requests them once hot one has
+	// checkpointed them, so that it resumes from where hot one got to.
+	if err := hotConsumer.GiveUpVbs(toMove); err != nil {
+		logging.Errorf("%s [%s:%d] Worker: %s failed to give up vbs: %s, err: %v",
+			logPrefix, p.appName, p.LenRunningConsumers(), hot.workerName, util.Condense(toMove), err)
+		return
+	}
+	coldConsumer.TakeOverVbs(toMove)
+}
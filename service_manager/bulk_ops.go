@@ -0,0 +1,207 @@
+package servicemanager
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"sort"
+
+	"github.com/couchbase/cbauth"
+	"github.com/couchbase/eventing/logging"
+)
+
+// bulkOpTargets resolves functions a bulk operation applies to, either the listed
+// names in the given order or those in temp store matching the name pattern, out of
+// groups the request may manage
+func (m *ServiceMgr) bulkOpTargets(req *bulkOpRequest, creds cbauth.Creds) (names []string, info *runtimeInfo) {
+	info = &runtimeInfo{}
+	info.Code = m.statusCodes.errInvalidConfig.Code
+
+	if (len(req.Names) == 0) == (req.NamePattern == "") {
+		info.Info = "Either names or name_pattern must be specified"
+		return
+	}
+
+	names = make([]string, 0)
+	if req.NamePattern == "" {
+		seen := make(map[string]struct{})
+		for _, name := range req.Names {
+			if _, ok := seen[name]; ok {
+				continue
+			}
+			seen[name] = struct{}{}
+			names = append(names, name)
+		}
+
+		info.Code = m.statusCodes.ok.Code
+		return
+	}
+
+	if _, err := path.Match(req.NamePattern, ""); err != nil {
+		info.Info = fmt.Sprintf("Invalid name_pattern: %s, err: %v", req.NamePattern, err)
+		return
+	}
+
+	for _, app := range m.getTempStoreAll() {
+		if !isGroupAllowed(creds, app.Group) {
+			continue
+		}
+
+		if matched, _ := path.Match(req.NamePattern, app.Name); matched && app.Name != "" {
+			names = append(names, app.Name)
+		}
+	}
+	sort.Strings(names)
+
+	if len(names) == 0 {
+		info.Code = m.statusCodes.errAppNotFoundTs.Code
+		info.Info = fmt.Sprintf("No function matches name_pattern: %s", req.NamePattern)
+		return
+	}
+
+	info.Code = m.statusCodes.ok.Code
+	return
+}
+
+// bulkOpSettings returns settings to be applied to each function by the bulk operation,
+// and whether they change deployment or processing status
+func (m *ServiceMgr) bulkOpSettings(req *bulkOpRequest) (data []byte, lifeCycleOp bool, info *runtimeInfo) {
+	info = &runtimeInfo{}
+
+	switch req.Action {
+	case bulkActionDeploy:
+		data = []byte(`{"deployment_status":true,"processing_status":true}`)
+		lifeCycleOp = true
+
+	case bulkActionUndeploy:
+		data = []byte(`{"deployment_status":false,"processing_status":false}`)
+		lifeCycleOp = true
+
+	case bulkActionPause:
+		lifeCycleOp = true
+
+	case bulkActionChangeSettings:
+		if len(req.Settings) == 0 {
+			info.Code = m.statusCodes.errInvalidConfig.Code
+			info.Info = fmt.Sprintf("settings must be specified for action: %s", req.Action)
+			return
+		}
+
+		if info = m.validateSettings(req.Settings); info.Code != m.statusCodes.ok.Code {
+			return
+		}
+
+		var err error
+		data, err = json.Marshal(req.Settings)
+		if err != nil {
+			info.Code = m.statusCodes.errMarshalResp.Code
+			info.Info = fmt.Sprintf("failed to marshal settings, err: %v", err)
+			return
+		}
+
+		_, procStatExists := req.Settings["processing_status"]
+		_, depStatExists := req.Settings["deployment_status"]
+		lifeCycleOp = procStatExists || depStatExists
+
+	default:
+		info.Code = m.statusCodes.errInvalidConfig.Code
+		info.Info = fmt.Sprintf("Unsupported action: %s, expected one of %s, %s, %s or %s", req.Action,
+			bulkActionDeploy, bulkActionUndeploy, bulkActionPause, bulkActionChangeSettings)
+		return
+	}
+
+	info.Code = m.statusCodes.ok.Code
+	return
+}
+
+// bulkOp applies an action to many functions, checking for ongoing rebalance once for all
+// of them. In fail-fast mode, functions following the first failure are skipped.
+func (m *ServiceMgr) bulkOp(req *bulkOpRequest, creds cbauth.Creds) (results []bulkOpResult, info *runtimeInfo) {
+	logPrefix := "ServiceMgr::bulkOp"
+
+	info = &runtimeInfo{}
+
+	if req.Mode == "" {
+		req.Mode = bulkModeBestEffort
+	}
+
+	if req.Mode != bulkModeFailFast && req.Mode != bulkModeBestEffort {
+		info.Code = m.statusCodes.errInvalidConfig.Code
+		info.Info = fmt.Sprintf("Unsupported mode: %s, expected %s or %s", req.Mode, bulkModeFailFast, bulkModeBestEffort)
+		logging.Errorf("%s %s", logPrefix, info.Info)
+		return
+	}
+
+	data, lifeCycleOp, info := m.bulkOpSettings(req)
+	if info.Code != m.statusCodes.ok.Code {
+		logging.Errorf("%s %s", logPrefix, info.Info)
+		return
+	}
+
+	names, info := m.bulkOpTargets(req, creds)
+	if info.Code != m.statusCodes.ok.Code {
+		logging.Errorf("%s %s", logPrefix, info.Info)
+		return
+	}
+
+	if lifeCycleOp {
+		if info = m.checkLifeCycleOpsDuringRebalance(); info.Code != m.statusCodes.ok.Code {
+			return
+		}
+	}
+
+	logging.Infof("%s action: %s mode: %s functions: %v", logPrefix, req.Action, req.Mode, names)
+
+	failed := false
+	results = make([]bulkOpResult, 0, len(names))
+	for _, name := range names {
+		result := bulkOpResult{Name: name}
+
+		if failed && req.Mode == bulkModeFailFast {
+			result.runtimeInfo = &runtimeInfo{
+				Code: m.statusCodes.errBulkOpSkipped.Code,
+				Info: fmt.Sprintf("Function: %s skipped as an earlier function failed", name),
+			}
+			results = append(results, result)
+			continue
+		}
+
+		result.runtimeInfo = m.checkAppAccess(creds, name)
+		if result.Code == m.statusCodes.ok.Code {
+			if req.Action == bulkActionPause {
+				result.runtimeInfo = m.pauseAppImpl(name, true)
+			} else {
+				result.runtimeInfo = m.setSettingsImpl(name, data, true)
+			}
+		}
+
+		if result.Code != m.statusCodes.ok.Code {
+			logging.Errorf("%s Function: %s action: %s failed, %v", logPrefix, name, req.Action, result.Info)
+			failed = true
+		}
+		results = append(results, result)
+	}
+
+	info.Code = m.statusCodes.ok.Code
+	return
+}
+
+func (m *ServiceMgr) sendBulkOpResults(w http.ResponseWriter, results []bulkOpResult) {
+	response, err := json.Marshal(results)
+	if err != nil {
+		info := &runtimeInfo{}
+		info.Code = m.statusCodes.errMarshalResp.Code
+		info.Info = fmt.Sprintf("failed to marshal bulk operation results, err: %v", err)
+		m.sendErrorInfo(w, info)
+		return
+	}
+
+	infoList := make([]*runtimeInfo, 0, len(results))
+	for _, result := range results {
+		infoList = append(infoList, result.runtimeInfo)
+	}
+
+	w.WriteHeader(m.getListDisposition(infoList))
+	fmt.Fprintf(w, "%s", string(response))
+}
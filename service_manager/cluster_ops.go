@@ -6,6 +6,7 @@ import (
 	"net"
 
 	"github.com/couchbase/cbauth"
+	"github.com/couchbase/eventing/common"
 	"github.com/couchbase/eventing/logging"
 	"github.com/couchbase/eventing/util"
 )
@@ -76,6 +77,61 @@ var storeKeepNodesCallback = func(args ...interface{}) error {
 	return nil
 }
 
+// storeNodeWeightsCallback samples capacity of reachable eventing nodes and stores it next to
+// keepNodes, so that every producer plans with the same weights. Entries of unreachable nodes
+// are carried over from previous topology change.
+var storeNodeWeightsCallback = func(args ...interface{}) error {
+	logPrefix := "ServiceMgr::storeNodeWeightsCallback"
+
+	m := args[0].(*ServiceMgr)
+
+	err := getEventingNodesAddressesOpCallback(m)
+	if err != nil {
+		return err
+	}
+
+	nodeWeights := make(map[string]*common.NodeWeight)
+
+	data, err := util.MetakvGet(metakvConfigNodeWeights)
+	if err == nil && len(data) > 0 {
+		err = json.Unmarshal(data, &nodeWeights)
+		if err != nil {
+			logging.Warnf("%s Failed to unmarshal node weights from metakv, err: %v", logPrefix, err)
+		}
+	}
+
+	for _, nodeAddr := range m.eventingNodeAddrs {
+		addrUUIDMap, err := util.GetNodeUUIDs("/uuid", []string{nodeAddr})
+		if err != nil {
+			continue
+		}
+
+		weight, err := util.GetNodeWeight(nodeAddr)
+		if err != nil {
+			continue
+		}
+
+		for uuid := range addrUUIDMap {
+			nodeWeights[uuid] = weight
+		}
+	}
+
+	data, err = json.Marshal(&nodeWeights)
+	if err != nil {
+		logging.Errorf("%s Failed to marshal node weights, err: %v", logPrefix, err)
+		return err
+	}
+
+	err = util.MetakvSet(metakvConfigNodeWeights, data, nil)
+	if err != nil {
+		logging.Errorf("%s Failed to store node weights in metakv, err: %v", logPrefix, err)
+		return err
+	}
+
+	logging.Infof("%s Node weights: %s", logPrefix, string(data))
+	return nil
+}
+
 var stopRebalanceCallback = func(args ...interface{}) error {
 	logPrefix := "rebalancer::stopRebalanceCallback"
 
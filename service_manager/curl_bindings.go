@@ -0,0 +1,82 @@
+package servicemanager
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/couchbase/eventing/audit"
+	"github.com/couchbase/eventing/gen/auditevent"
+	"github.com/couchbase/eventing/logging"
+)
+
+// redactCurlCredentials blanks out secrets of curl bindings, for definitions leaving the cluster
+func redactCurlCredentials(cfg *depCfg) {
+	for i := range cfg.Curl {
+		binding := &cfg.Curl[i]
+		if binding.Password != "" {
+			binding.Password = redactedCredential
+		}
+		if binding.BearerKey != "" {
+			binding.BearerKey = redactedCredential
+		}
+	}
+}
+
+func sameCurlCredentials(a, b *curlBinding) bool {
+	return a.AuthType == b.AuthType && a.Username == b.Username &&
+		a.Password == b.Password && a.BearerKey == b.BearerKey
+}
+
+func hasCurlCredentials(b *curlBinding) bool {
+	return b.AuthType != "" && b.AuthType != curlAuthNone
+}
+
+// reconcileSecrets carries over stored secrets for curl bindings and secret constants saved
+// with redacted values, i.e. an exported function being imported back, and audit logs
+// bindings whose credentials got added, changed or removed
+func (m *ServiceMgr) reconcileSecrets(r *http.Request, app *application) {
+	logPrefix := "ServiceMgr::reconcileSecrets"
+
+	stored := make(map[string]curlBinding)
+	if storedApp, info := m.getTempStore(app.Name); info.Code == m.statusCodes.ok.Code {
+		for _, binding := range storedApp.DeploymentConfig.Curl {
+			stored[binding.Alias] = binding
+		}
+		app.DeploymentConfig.Constants = reconcileConstants(app.DeploymentConfig.Constants,
+			storedApp.DeploymentConfig.Constants)
+		reconcileSettings(app.Settings, storedApp.Settings)
+	}
+
+	changed := make([]string, 0)
+	for i := range app.DeploymentConfig.Curl {
+		binding := &app.DeploymentConfig.Curl[i]
+		prev, exists := stored[binding.Alias]
+
+		if exists && binding.Password == redactedCredential {
+			binding.Password = prev.Password
+		}
+		if exists && binding.BearerKey == redactedCredential {
+			binding.BearerKey = prev.BearerKey
+		}
+
+		if exists && !sameCurlCredentials(binding, &prev) || !exists && hasCurlCredentials(binding) {
+			changed = append(changed, binding.Alias)
+		}
+		delete(stored, binding.Alias)
+	}
+
+	for alias, binding := range stored {
+		if hasCurlCredentials(&binding) {
+			changed = append(changed, alias)
+		}
+	}
+
+	if len(changed) == 0 {
+		return
+	}
+
+	sort.Strings(changed)
+	logging.Infof("%s Function: %s curl credentials updated for aliases: %v", logPrefix, app.Name, changed)
+	audit.Log(auditevent.UpdateCurlCredentials, r, fmt.Sprintf("%s: %v", app.Name, changed))
+}
@@ -1,6 +1,7 @@
 package servicemanager
 
 import (
+	"encoding/json"
 	"sync"
 	"time"
 
@@ -10,18 +11,21 @@ import (
 )
 
 const (
-	metakvEventingPath       = "/eventing/"
-	metakvAppsPath           = metakvEventingPath + "apps/"
-	metakvAppSettingsPath    = metakvEventingPath + "appsettings/"     // function settings
-	metakvConfigKeepNodes    = metakvEventingPath + "config/keepNodes" // Store list of eventing keepNodes
-	metakvConfigPath         = metakvEventingPath + "settings/config"  // global settings
-	metakvRebalanceTokenPath = metakvEventingPath + "rebalanceToken/"
-	metakvRebalanceProgress  = metakvEventingPath + "rebalanceProgress/"
-	metakvAppsRetryPath      = metakvEventingPath + "retry/"
-	metakvTempAppsPath       = metakvEventingPath + "tempApps/"
-	metakvChecksumPath       = metakvEventingPath + "checksum/"
-	metakvTempChecksumPath   = metakvEventingPath + "tempchecksum/"
-	stopRebalance            = "stopRebalance"
+	metakvEventingPath        = "/eventing/"
+	metakvAppsPath            = metakvEventingPath + "apps/"
+	metakvAppSettingsPath     = metakvEventingPath + "appsettings/"       // function settings
+	metakvConfigKeepNodes     = metakvEventingPath + "config/keepNodes"   // Store list of eventing keepNodes
+	metakvConfigNodeWeights   = metakvEventingPath + "config/nodeWeights" // Store capacity of eventing nodes, used by weighted planner
+	metakvConfigPath          = metakvEventingPath + "settings/config"    // global settings
+	metakvRebalanceTokenPath  = metakvEventingPath + "rebalanceToken/"
+	metakvRebalanceProgress   = metakvEventingPath + "rebalanceProgress/"
+	metakvAppsRetryPath       = metakvEventingPath + "retry/"
+	metakvTempAppsPath        = metakvEventingPath + "tempApps/"
+	metakvChecksumPath        = metakvEventingPath + "checksum/"
+	metakvTempChecksumPath    = metakvEventingPath + "tempchecksum/"
+	metakvAppVersionsPath     = metakvEventingPath + "appversions/" // saved revisions of function definitions
+	metakvVersionChecksumPath = metakvEventingPath + "appversionchecksum/"
+	stopRebalance             = "stopRebalance"
 )
 
 const (
@@ -32,17 +36,43 @@ const (
 const (
 	// EventingPermissionManage for auditing
 	EventingPermissionManage = "cluster.eventing.functions!manage"
+
+	// EventingPermissionManageGroup is scoped to functions of a group. Eventing doesn't register
+	// permissions itself, ns_server has to define the parameterised group object and a role
+	// granting it, until then only EventingPermissionManage is effective
+	EventingPermissionManageGroup = "cluster.eventing.group[%s].functions!manage"
 )
 
 const (
 	headerKey                = "status"
 	maxApplicationNameLength = 100
+	maxGroupNameLength       = 100
 	maxAliasLength           = 20 // Technically, there isn't any limit on a JavaScript variable length.
 	maxPrefixLength          = 16
 
 	rebalanceStalenessCounter = 200
 )
 
+// Access modes of bucket bindings
+const (
+	bucketAccessRead      = "r"
+	bucketAccessReadWrite = "rw"
+)
+
+// Authentication schemes of curl bindings
+const (
+	curlAuthNone   = "no-auth"
+	curlAuthBasic  = "basic"
+	curlAuthDigest = "digest"
+	curlAuthBearer = "bearer"
+)
+
+// HTTP methods curl() supports
+var curlMethods = []string{"GET", "POST", "PUT", "DELETE"}
+
+// Replaces secrets in function definitions returned by REST endpoints
+const redactedCredential = "*****"
+
 const (
 	srcMapExt  = ".map.json"
 	srcCodeExt = ".js"
@@ -52,6 +82,31 @@ const (
 	maxHandlerSize = 128 * 1024
 )
 
+// Actions and failure handling modes of bulk function operations
+const (
+	bulkActionDeploy         = "deploy"
+	bulkActionUndeploy       = "undeploy"
+	bulkActionPause          = "pause"
+	bulkActionChangeSettings = "change-settings"
+
+	bulkModeFailFast   = "fail-fast"
+	bulkModeBestEffort = "best-effort"
+
+	// Reserved, as bulk operations are served under /api/v1/functions/bulk
+	bulkOpPathName = "bulk"
+)
+
+const (
+	defaultTimerListLimit = 100
+	maxTimerListLimit     = 1000
+)
+
+const (
+	defaultAppVersionHistory = 10 // Number of saved revisions retained per function
+
+	restorePauseTimeout = time.Duration(300) * time.Second
+)
+
 // ServiceMgr implements cbauth_service interface
 type ServiceMgr struct {
 	adminHTTPPort     string
@@ -138,6 +193,7 @@ type application struct {
 	AppHandlers      string                 `json:"appcode"`
 	DeploymentConfig depCfg                 `json:"depcfg"`
 	EventingVersion  string                 `json:"version"`
+	Group            string                 `json:"group,omitempty"`
 	HandlerUUID      uint32                 `json:"handleruuid"`
 	ID               int                    `json:"id"`
 	Name             string                 `json:"appname"`
@@ -145,13 +201,68 @@ type application struct {
 	UsingTimer       bool                   `json:"using_timer"`
 }
 
+// appVersion captures a saved revision of function definition
+type appVersion struct {
+	AppHandlers      string                 `json:"appcode"`
+	DeploymentConfig depCfg                 `json:"depcfg"`
+	HandlerUUID      uint32                 `json:"handleruuid"`
+	Revision         int                    `json:"revision"`
+	Settings         map[string]interface{} `json:"settings"`
+	Timestamp        string                 `json:"timestamp"`
+}
+
 type depCfg struct {
-	Buckets        []bucket `json:"buckets"`
-	MetadataBucket string   `json:"metadata_bucket"`
-	SourceBucket   string   `json:"source_bucket"`
+	Buckets        []bucket      `json:"buckets"`
+	Constants      []constant    `json:"constants,omitempty"`
+	Curl           []curlBinding `json:"curl,omitempty"`
+	DeadLetter     *deadLetter   `json:"dead_letter,omitempty"`
+	Filter         *filter       `json:"filter,omitempty"`
+	MetadataBucket string        `json:"metadata_bucket"`
+	SourceBucket   string        `json:"source_bucket"`
+}
+
+// curlBinding restricts requests made by handler through curl() to its base URL, with
+// credentials applied by eventing-consumer so that handler code never gets to see them
+type curlBinding struct {
+	Alias          string   `json:"alias"`
+	BaseURL        string   `json:"base_url"`
+	AuthType       string   `json:"auth_type"` // Possible values are no-auth, basic, digest, bearer
+	Username       string   `json:"username,omitempty"`
+	Password       secret   `json:"password,omitempty"`
+	BearerKey      secret   `json:"bearer_key,omitempty"`
+	AllowedMethods []string `json:"allowed_methods,omitempty"`
+}
+
+// constant is exposed to handler code as a read-only global named by alias. Values of
+// secret constants are encrypted at rest and masked in REST responses, like curl credentials
+type constant struct {
+	Alias  string          `json:"alias"`
+	Value  json.RawMessage `json:"value"`
+	Secret bool            `json:"secret,omitempty"`
+}
+
+// deadLetter captures where failed handler invocations get persisted
+type deadLetter struct {
+	BucketName string `json:"bucket_name"`
+	KeyPrefix  string `json:"key_prefix"`
+}
+
+// filter drops mutations that handler isn't interested in, before they reach eventing-consumer
+type filter struct {
+	KeyPrefix  string            `json:"key_prefix,omitempty"`
+	KeyRegex   string            `json:"key_regex,omitempty"`
+	Predicates []filterPredicate `json:"predicates,omitempty"`
+}
+
+type filterPredicate struct {
+	Field  string            `json:"field"`
+	Op     string            `json:"op"` // Possible values are equals, exists, in
+	Value  json.RawMessage   `json:"value,omitempty"`
+	Values []json.RawMessage `json:"values,omitempty"`
 }
 
 type bucket struct {
+	Access     string `json:"access"` // Possible values are r, rw
 	Alias      string `json:"alias"`
 	BucketName string `json:"bucket_name"`
 }
@@ -168,6 +279,7 @@ type stats struct {
 	ExecutionStats                  interface{} `json:"execution_stats,omitempty"`
 	FailureStats                    interface{} `json:"failure_stats,omitempty"`
 	FunctionName                    interface{} `json:"function_name"`
+	Group                           string      `json:"group,omitempty"`
 	GocbCredsRequestCounter         interface{} `json:"gocb_creds_request_counter,omitempty"`
 	InternalVbDistributionStats     interface{} `json:"internal_vb_distribution_stats,omitempty"`
 	LatencyPercentileStats          interface{} `json:"latency_percentile_stats,omitempty"`
@@ -179,6 +291,9 @@ type stats struct {
 	RebalanceStats                  interface{} `json:"rebalance_stats,omitempty"`
 	SeqsProcessed                   interface{} `json:"seqs_processed,omitempty"`
 	SpanBlobDump                    interface{} `json:"span_blob_dump,omitempty"`
+	TimerBacklogStats               interface{} `json:"timer_backlog_stats,omitempty"`
+	TimerLatenessPercentileStats    interface{} `json:"timer_lateness_percentile_stats,omitempty"`
+	TimerLatenessStats              interface{} `json:"timer_lateness_stats,omitempty"`
 	VbDcpEventsRemaining            interface{} `json:"dcp_event_backlog_per_vb,omitempty"`
 	VbDistributionStatsFromMetadata interface{} `json:"vb_distribution_stats_from_metadata,omitempty"`
 	VbSeqnoStats                    interface{} `json:"vb_seq_no_stats,omitempty"`
@@ -200,12 +315,26 @@ type retry struct {
 
 type appStatus struct {
 	Name             string `json:"name"`
+	Group            string `json:"group,omitempty"`
 	CompositeStatus  string `json:"composite_status"`
 	NumDeployedNodes int    `json:"num_deployed_nodes"`
 	DeploymentStatus bool   `json:"deployment_status"`
 	ProcessingStatus bool   `json:"processing_status"`
 }
 
+type bulkOpRequest struct {
+	Names       []string               `json:"names"`
+	NamePattern string                 `json:"name_pattern"` // Glob, as in path.Match
+	Action      string                 `json:"action"`
+	Settings    map[string]interface{} `json:"settings"` // Only for change-settings
+	Mode        string                 `json:"mode"`
+}
+
+type bulkOpResult struct {
+	Name string `json:"name"`
+	*runtimeInfo
+}
+
 type appStatusResponse struct {
 	Apps             []appStatus `json:"apps"`
 	NumEventingNodes int         `json:"num_eventing_nodes"`
@@ -0,0 +1,274 @@
+package servicemanager
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/couchbase/eventing/logging"
+)
+
+// functionGraph captures how deployed functions feed each other. There is an edge
+// from function A to function B when A writes to a bucket that B sources its mutations
+// from, so a cycle means mutations can cascade through functions indefinitely.
+type functionGraph struct {
+	Functions []functionGraphNode `json:"functions"`
+	Edges     []functionGraphEdge `json:"edges"`
+	Cycles    [][]string          `json:"cycles"`
+}
+
+type functionGraphNode struct {
+	Name         string   `json:"name"`
+	SourceBucket string   `json:"source_bucket"`
+	WriteBuckets []string `json:"write_buckets"`
+	Deployed     bool     `json:"deployed"`
+}
+
+type functionGraphEdge struct {
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Bucket string `json:"bucket"`
+}
+
+// writableBuckets returns buckets the function can mutate through its bucket bindings
+func writableBuckets(cfg *depCfg) []string {
+	seen := make(map[string]struct{})
+	buckets := make([]string, 0)
+
+	for i, b := range cfg.Buckets {
+		if bindingAccess(&cfg.Buckets[i], cfg.SourceBucket) != bucketAccessReadWrite {
+			continue
+		}
+
+		if _, ok := seen[b.BucketName]; ok {
+			continue
+		}
+		seen[b.BucketName] = struct{}{}
+		buckets = append(buckets, b.BucketName)
+	}
+
+	sort.Strings(buckets)
+	return buckets
+}
+
+type appsByName []application
+
+func (a appsByName) Len() int           { return len(a) }
+func (a appsByName) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a appsByName) Less(i, j int) bool { return a[i].Name < a[j].Name }
+
+func isDeployed(app *application) bool {
+	deployed, ok := app.Settings["deployment_status"].(bool)
+	return ok && deployed
+}
+
+// buildFunctionGraph builds graph of functions from the temp store. Undeployed functions
+// are left out unless includeUndeployed is set, as they can't take part in a cascade.
+// Function named by candidate replaces its stored definition, to validate it before deploy.
+func (m *ServiceMgr) buildFunctionGraph(candidate *application, includeUndeployed bool) *functionGraph {
+	apps := make([]application, 0)
+	for _, app := range m.getTempStoreAll() {
+		if app.Name == "" || (candidate != nil && app.Name == candidate.Name) {
+			continue
+		}
+
+		if includeUndeployed || isDeployed(&app) {
+			apps = append(apps, app)
+		}
+	}
+
+	if candidate != nil {
+		apps = append(apps, *candidate)
+	}
+
+	sort.Sort(appsByName(apps))
+
+	g := &functionGraph{
+		Functions: make([]functionGraphNode, 0, len(apps)),
+		Edges:     make([]functionGraphEdge, 0),
+		Cycles:    make([][]string, 0),
+	}
+
+	for i := range apps {
+		g.Functions = append(g.Functions, functionGraphNode{
+			Name:         apps[i].Name,
+			SourceBucket: apps[i].DeploymentConfig.SourceBucket,
+			WriteBuckets: writableBuckets(&apps[i].DeploymentConfig),
+			Deployed:     isDeployed(&apps[i]),
+		})
+	}
+
+	// Function writing to its own source bucket is restricted separately,
+	// hence self edges aren't considered part of a cascade
+	for _, from := range g.Functions {
+		for _, bucket := range from.WriteBuckets {
+			for _, to := range g.Functions {
+				if from.Name != to.Name && to.SourceBucket == bucket {
+					g.Edges = append(g.Edges, functionGraphEdge{From: from.Name, To: to.Name, Bucket: bucket})
+				}
+			}
+		}
+	}
+
+	g.Cycles = g.findCycles()
+	return g
+}
+
+func (g *functionGraph) adjacency() map[string][]functionGraphEdge {
+	adj := make(map[string][]functionGraphEdge)
+	for _, e := range g.Edges {
+		adj[e.From] = append(adj[e.From], e)
+	}
+	return adj
+}
+
+// findCycles returns strongly connected components having more than one function,
+// using Tarjan's algorithm. Functions within a component are sorted by name.
+func (g *functionGraph) findCycles() [][]string {
+	adj := g.adjacency()
+
+	index := 0
+	indices := make(map[string]int)
+	lowLink := make(map[string]int)
+	onStack := make(map[string]bool)
+	stack := make([]string, 0)
+	cycles := make([][]string, 0)
+
+	var connect func(name string)
+	connect = func(name string) {
+		indices[name] = index
+		lowLink[name] = index
+		index++
+		stack = append(stack, name)
+		onStack[name] = true
+
+		for _, e := range adj[name] {
+			if _, visited := indices[e.To]; !visited {
+				connect(e.To)
+				if lowLink[e.To] < lowLink[name] {
+					lowLink[name] = lowLink[e.To]
+				}
+			} else if onStack[e.To] && indices[e.To] < lowLink[name] {
+				lowLink[name] = indices[e.To]
+			}
+		}
+
+		if lowLink[name] != indices[name] {
+			return
+		}
+
+		component := make([]string, 0)
+		for {
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[top] = false
+			component = append(component, top)
+			if top == name {
+				break
+			}
+		}
+
+		if len(component) > 1 {
+			sort.Strings(component)
+			cycles = append(cycles, component)
+		}
+	}
+
+	for _, fn := range g.Functions {
+		if _, visited := indices[fn.Name]; !visited {
+			connect(fn.Name)
+		}
+	}
+
+	return cycles
+}
+
+// cyclePath returns a cycle starting and ending at given function, rendered as
+// "A -(bucket)-> B -(bucket)-> A", or empty string if there is none
+func (g *functionGraph) cyclePath(appName string) string {
+	adj := g.adjacency()
+	visited := make(map[string]bool)
+	path := make([]functionGraphEdge, 0)
+
+	var walk func(name string) bool
+	walk = func(name string) bool {
+		visited[name] = true
+		for _, e := range adj[name] {
+			path = append(path, e)
+			if e.To == appName {
+				return true
+			}
+			if !visited[e.To] && walk(e.To) {
+				return true
+			}
+			path = path[:len(path)-1]
+		}
+		return false
+	}
+
+	if !walk(appName) {
+		return ""
+	}
+
+	hops := []string{appName}
+	for _, e := range path {
+		hops = append(hops, fmt.Sprintf("-(%s)-> %s", e.Bucket, e.To))
+	}
+	return strings.Join(hops, " ")
+}
+
+// allowInterBucketRecursion reports if functions are allowed to form cycles through
+// buckets, as per global config. Cycles are only logged when allowed.
+func (m *ServiceMgr) allowInterBucketRecursion() bool {
+	logPrefix := "ServiceMgr::allowInterBucketRecursion"
+
+	config, info := m.getConfig()
+	if info.Code != m.statusCodes.ok.Code {
+		return false
+	}
+
+	val, exists := config["allow_interbucket_recursion"]
+	if !exists {
+		return false
+	}
+
+	allow, ok := val.(bool)
+	if !ok {
+		logging.Warnf("%s Supplied allow_interbucket_recursion value unexpected. Defaulting to false", logPrefix)
+		return false
+	}
+	return allow
+}
+
+// validateInterBucketRecursion rejects deployment of a function that would close a cycle
+// with already deployed functions
+func (m *ServiceMgr) validateInterBucketRecursion(appName string, deploymentConfig *depCfg) (info *runtimeInfo) {
+	logPrefix := "ServiceMgr::validateInterBucketRecursion"
+
+	info = &runtimeInfo{}
+
+	candidate := &application{
+		Name:             appName,
+		DeploymentConfig: *deploymentConfig,
+		Settings:         map[string]interface{}{"deployment_status": true},
+	}
+
+	path := m.buildFunctionGraph(candidate, false).cyclePath(appName)
+	if path == "" {
+		info.Code = m.statusCodes.ok.Code
+		return
+	}
+
+	if m.allowInterBucketRecursion() {
+		logging.Warnf("%s Function: %s mutations would cascade through deployed functions: %s",
+			logPrefix, appName, path)
+		info.Code = m.statusCodes.ok.Code
+		return
+	}
+
+	info.Code = m.statusCodes.errBucketRecursion.Code
+	info.Info = fmt.Sprintf("Function: %s would cause mutations to cascade indefinitely through deployed functions: %s",
+		appName, path)
+	logging.Errorf("%s %s", logPrefix, info.Info)
+	return
+}
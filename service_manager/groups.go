@@ -0,0 +1,161 @@
+package servicemanager
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+
+	"github.com/couchbase/cbauth"
+	"github.com/couchbase/eventing/logging"
+)
+
+// groupPermission returns permission needed to manage functions of the group. Functions
+// outside of any group need EventingPermissionManage, which grants access to all groups.
+func groupPermission(group string) string {
+	if group == "" {
+		return EventingPermissionManage
+	}
+	return fmt.Sprintf(EventingPermissionManageGroup, group)
+}
+
+func isGroupAllowed(creds cbauth.Creds, group string) bool {
+	if allowed, err := creds.IsAllowed(EventingPermissionManage); err == nil && allowed {
+		return true
+	}
+
+	if group == "" {
+		return false
+	}
+
+	allowed, err := creds.IsAllowed(groupPermission(group))
+	return err == nil && allowed
+}
+
+// authenticate returns credentials of the request, to be authorized against groups
+// of functions it operates upon
+func (m *ServiceMgr) authenticate(w http.ResponseWriter, r *http.Request) (cbauth.Creds, bool) {
+	logPrefix := "ServiceMgr::authenticate"
+
+	creds, err := cbauth.AuthWebCreds(r)
+	if err != nil || creds == nil {
+		logging.Warnf("%s Cannot authenticate request to %rs, err: %v creds: %ru", logPrefix, r.URL, err, creds)
+		w.WriteHeader(http.StatusUnauthorized)
+		return nil, false
+	}
+	return creds, true
+}
+
+func (m *ServiceMgr) checkGroupAccess(creds cbauth.Creds, group string) (info *runtimeInfo) {
+	info = &runtimeInfo{}
+
+	if !isGroupAllowed(creds, group) {
+		info.Code = m.statusCodes.errForbidden.Code
+		if group == "" {
+			info.Info = "Not permitted to manage functions outside of groups"
+		} else {
+			info.Info = fmt.Sprintf("Not permitted to manage functions of group: %s", group)
+		}
+		return
+	}
+
+	info.Code = m.statusCodes.ok.Code
+	return
+}
+
+// checkAppAccess authorizes request to operate upon a function, based on group of its
+// stored definition. Functions yet to be created are treated as outside of any group.
+func (m *ServiceMgr) checkAppAccess(creds cbauth.Creds, appName string) (info *runtimeInfo) {
+	group := ""
+	if app, tInfo := m.getTempStore(appName); tInfo.Code == m.statusCodes.ok.Code {
+		group = app.Group
+	}
+
+	if info = m.checkGroupAccess(creds, group); info.Code != m.statusCodes.ok.Code {
+		info.Info = fmt.Sprintf("Function: %s %v", appName, info.Info)
+	}
+	return
+}
+
+func (m *ServiceMgr) appExists(appName string) bool {
+	_, info := m.getTempStore(appName)
+	return info.Code == m.statusCodes.ok.Code
+}
+
+// checkAppSaveAccess authorizes saving a function definition, which needs access to the
+// group it's stored in, if any, as well as the one it's being saved to
+func (m *ServiceMgr) checkAppSaveAccess(creds cbauth.Creds, app *application) (info *runtimeInfo) {
+	if stored, tInfo := m.getTempStore(app.Name); tInfo.Code == m.statusCodes.ok.Code {
+		if info = m.checkGroupAccess(creds, stored.Group); info.Code != m.statusCodes.ok.Code {
+			info.Info = fmt.Sprintf("Function: %s %v", app.Name, info.Info)
+			return
+		}
+	}
+
+	if info = m.checkGroupAccess(creds, app.Group); info.Code != m.statusCodes.ok.Code {
+		info.Info = fmt.Sprintf("Function: %s %v", app.Name, info.Info)
+	}
+	return
+}
+
+// validateAppAuth authorizes requests to endpoints operating upon a single function
+func (m *ServiceMgr) validateAppAuth(w http.ResponseWriter, r *http.Request, appName string) bool {
+	logPrefix := "ServiceMgr::validateAppAuth"
+
+	creds, ok := m.authenticate(w, r)
+	if !ok {
+		return false
+	}
+
+	if info := m.checkAppAccess(creds, appName); info.Code != m.statusCodes.ok.Code {
+		logging.Warnf("%s Cannot authorize request to %rs, %v", logPrefix, r.URL, info.Info)
+		w.WriteHeader(http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// appFilter selects functions a request is to be served for
+type appFilter func(app *application) bool
+
+// requestAppFilter selects functions the request may manage, further narrowed down to
+// the group passed as "group" query parameter, if any
+func (m *ServiceMgr) requestAppFilter(r *http.Request, creds cbauth.Creds) appFilter {
+	params := r.URL.Query()
+	_, groupRequested := params["group"]
+	group := params.Get("group")
+
+	allowed := make(map[string]bool)
+	return func(app *application) bool {
+		if groupRequested && app.Group != group {
+			return false
+		}
+
+		if _, ok := allowed[app.Group]; !ok {
+			allowed[app.Group] = isGroupAllowed(creds, app.Group)
+		}
+		return allowed[app.Group]
+	}
+}
+
+func (m *ServiceMgr) validateGroupName(group string) (info *runtimeInfo) {
+	info = &runtimeInfo{}
+
+	if group == "" {
+		info.Code = m.statusCodes.ok.Code
+		return
+	}
+
+	if info = m.validateName(group, "Group", maxGroupNameLength); info.Code != m.statusCodes.ok.Code {
+		return
+	}
+
+	groupNameRegex := regexp.MustCompile("^[a-zA-Z0-9][a-zA-Z0-9_-]*$")
+	if !groupNameRegex.MatchString(group) {
+		info.Code = m.statusCodes.errInvalidConfig.Code
+		info.Info = "Group name can only contain characters in range A-Z, a-z, 0-9 and underscore, hyphen"
+		return
+	}
+
+	info.Code = m.statusCodes.ok.Code
+	return
+}
@@ -87,13 +87,14 @@ func (m *ServiceMgr) getNodeVersion(w http.ResponseWriter, r *http.Request) {
 
 func (m *ServiceMgr) deletePrimaryStoreHandler(w http.ResponseWriter, r *http.Request) {
 	logPrefix := "ServiceMgr::deletePrimaryStoreHandler"
-	if !m.validateAuth(w, r, EventingPermissionManage) {
-		return
-	}
 
 	values := r.URL.Query()
 	appName := values["name"][0]
 
+	if !m.validateAppAuth(w, r, appName) {
+		return
+	}
+
 	logging.Infof("%s Function: %s deleting from primary store", logPrefix, appName)
 	audit.Log(auditevent.DeleteFunction, r, appName)
 	m.deletePrimaryStore(appName)
@@ -153,6 +154,11 @@ func (m *ServiceMgr) deletePrimaryStore(appName string) (info *runtimeInfo) {
 		return
 	}
 
+	// Revisions would otherwise be listed for a later function of the same name
+	if err = deleteAppVersions(appName); err != nil {
+		logging.Errorf("%s Function: %s failed to delete revision history, err: %v", logPrefix, appName, err)
+	}
+
 	// TODO : This must be changed to app not deployed / found
 	info.Code = m.statusCodes.ok.Code
 	info.Info = fmt.Sprintf("Function: %s deleting in the background", appName)
@@ -161,13 +167,13 @@ func (m *ServiceMgr) deletePrimaryStore(appName string) (info *runtimeInfo) {
 }
 
 func (m *ServiceMgr) deleteTempStoreHandler(w http.ResponseWriter, r *http.Request) {
-	if !m.validateAuth(w, r, EventingPermissionManage) {
-		return
-	}
-
 	values := r.URL.Query()
 	appName := values["name"][0]
 
+	if !m.validateAppAuth(w, r, appName) {
+		return
+	}
+
 	audit.Log(auditevent.DeleteDrafts, r, appName)
 
 	m.deleteTempStore(appName)
@@ -639,7 +645,7 @@ func (m *ServiceMgr) getRebalanceProgress(w http.ResponseWriter, r *http.Request
 
 	if progress.VbsRemainingToShuffle == 0 && progress.VbsOwnedPerPlan == 0 && !m.statsWritten {
 		// Picking up subset of the stats
-		statsList := m.populateStats(false)
+		statsList := m.populateStats(false, nil)
 		data, err := json.Marshal(statsList)
 		if err != nil {
 			logging.Errorf("%s failed to unmarshal stats, err: %v", logPrefix, err)
@@ -863,13 +869,14 @@ func (m *ServiceMgr) getSeqsProcessed(w http.ResponseWriter, r *http.Request) {
 
 func (m *ServiceMgr) setSettingsHandler(w http.ResponseWriter, r *http.Request) {
 	logPrefix := "ServiceMgr::setSettingsHandler"
-	if !m.validateAuth(w, r, EventingPermissionManage) {
-		return
-	}
 
 	params := r.URL.Query()
 	appName := params["name"][0]
 
+	if !m.validateAppAuth(w, r, appName) {
+		return
+	}
+
 	audit.Log(auditevent.SetSettings, r, appName)
 	data, err := ioutil.ReadAll(r.Body)
 	if err != nil {
@@ -916,6 +923,12 @@ func (m *ServiceMgr) getSettings(appName string) (*map[string]interface{}, *runt
 }
 
 func (m *ServiceMgr) setSettings(appName string, data []byte) (info *runtimeInfo) {
+	return m.setSettingsImpl(appName, data, false)
+}
+
+// setSettingsImpl skips checking for ongoing rebalance if rebalanceChecked is set,
+// so that bulk operations check it once rather than per function
+func (m *ServiceMgr) setSettingsImpl(appName string, data []byte, rebalanceChecked bool) (info *runtimeInfo) {
 	logPrefix := "ServiceMgr::setSettings"
 
 	info = &runtimeInfo{}
@@ -933,7 +946,7 @@ func (m *ServiceMgr) setSettings(appName string, data []byte) (info *runtimeInfo
 	_, procStatExists := settings["processing_status"]
 	_, depStatExists := settings["deployment_status"]
 
-	if procStatExists || depStatExists {
+	if (procStatExists || depStatExists) && !rebalanceChecked {
 		if lifeCycleOpsInfo := m.checkLifeCycleOpsDuringRebalance(); lifeCycleOpsInfo.Code != m.statusCodes.ok.Code {
 			info.Code = lifeCycleOpsInfo.Code
 			info.Info = lifeCycleOpsInfo.Info
@@ -951,6 +964,26 @@ func (m *ServiceMgr) setSettings(appName string, data []byte) (info *runtimeInfo
 		app.Settings[setting] = settings[setting]
 	}
 
+	// Constants are hot-reloaded by deployed workers, without restarting their DCP streams
+	if val, ok := settings["constants"]; ok {
+		constants, err := constantsFromSettings(val)
+		if err != nil {
+			info.Code = m.statusCodes.errInvalidConfig.Code
+			info.Info = fmt.Sprintf("Function: %s constants must be a list of alias and value pairs, err: %v", appName, err)
+			logging.Errorf("%s %s", logPrefix, info.Info)
+			return
+		}
+
+		if info = m.validateConstants(constants, &app.DeploymentConfig); info.Code != m.statusCodes.ok.Code {
+			return
+		}
+
+		// Masked secret constants keep their stored values
+		constants = reconcileConstants(constants, app.DeploymentConfig.Constants)
+		app.DeploymentConfig.Constants = constants
+		app.Settings["constants"] = constants
+	}
+
 	// State validation - app must be in deployed state
 	processingStatus, pOk := app.Settings["processing_status"].(bool)
 	deploymentStatus, dOk := app.Settings["deployment_status"].(bool)
@@ -969,6 +1002,13 @@ func (m *ServiceMgr) setSettings(appName string, data []byte) (info *runtimeInfo
 				return
 			}
 		}
+
+		// Check if deployment would make mutations cascade through functions
+		if _, ok := deployedApps[appName]; !ok && deploymentStatus {
+			if info = m.validateDeploymentConfig(appName, &app.DeploymentConfig, true); info.Code != m.statusCodes.ok.Code {
+				return
+			}
+		}
 	} else {
 		info.Code = m.statusCodes.errStatusesNotFound.Code
 		info.Info = fmt.Sprintf("Function: %s missing processing or deployment statuses or both", appName)
@@ -976,6 +1016,14 @@ func (m *ServiceMgr) setSettings(appName string, data []byte) (info *runtimeInfo
 		return
 	}
 
+	app, err = sealSecrets(app)
+	if err != nil {
+		info.Code = m.statusCodes.errSetSettingsPs.Code
+		info.Info = fmt.Sprintf("Function: %s failed to encrypt secrets, err: %v", appName, err)
+		logging.Errorf("%s %s", logPrefix, info.Info)
+		return
+	}
+
 	data, err = json.Marshal(app.Settings)
 	if err != nil {
 		info.Code = m.statusCodes.errMarshalResp.Code
@@ -1005,10 +1053,165 @@ func (m *ServiceMgr) setSettings(appName string, data []byte) (info *runtimeInfo
 	return
 }
 
+// pauseApp stops event processing of a deployed function while retaining its
+// checkpoints and timer spans in metadata bucket
+func (m *ServiceMgr) pauseApp(appName string) (info *runtimeInfo) {
+	return m.pauseAppImpl(appName, false)
+}
+
+func (m *ServiceMgr) pauseAppImpl(appName string, rebalanceChecked bool) (info *runtimeInfo) {
+	logPrefix := "ServiceMgr::pauseApp"
+
+	app, info := m.getTempStore(appName)
+	if info.Code != m.statusCodes.ok.Code {
+		return
+	}
+
+	deploymentStatus, _ := app.Settings["deployment_status"].(bool)
+	processingStatus, _ := app.Settings["processing_status"].(bool)
+	if !deploymentStatus || !processingStatus {
+		info.Code = m.statusCodes.errAppNotDeployed.Code
+		info.Info = fmt.Sprintf("Function: %s is not processing mutations, only deployed functions can be paused", appName)
+		logging.Errorf("%s %s", logPrefix, info.Info)
+		return
+	}
+
+	if info = m.setSettingsImpl(appName, []byte(`{"deployment_status":true,"processing_status":false}`), rebalanceChecked); info.Code != m.statusCodes.ok.Code {
+		return
+	}
+
+	info.Info = fmt.Sprintf("Function: %s pausing", appName)
+	logging.Infof("%s %s", logPrefix, info.Info)
+	return
+}
+
+// resumeApp restarts event processing of a paused function from its last checkpoint
+func (m *ServiceMgr) resumeApp(appName string) (info *runtimeInfo) {
+	logPrefix := "ServiceMgr::resumeApp"
+
+	app, info := m.getTempStore(appName)
+	if info.Code != m.statusCodes.ok.Code {
+		return
+	}
+
+	deploymentStatus, _ := app.Settings["deployment_status"].(bool)
+	processingStatus, _ := app.Settings["processing_status"].(bool)
+	if !deploymentStatus || processingStatus {
+		info.Code = m.statusCodes.errAppNotPaused.Code
+		info.Info = fmt.Sprintf("Function: %s is not paused", appName)
+		logging.Errorf("%s %s", logPrefix, info.Info)
+		return
+	}
+
+	if info = m.setSettings(appName, []byte(`{"deployment_status":true,"processing_status":true}`)); info.Code != m.statusCodes.ok.Code {
+		return
+	}
+
+	info.Info = fmt.Sprintf("Function: %s resuming", appName)
+	logging.Infof("%s %s", logPrefix, info.Info)
+	return
+}
+
+// replayDeadLetters re-triggers handler execution for events captured in dead letter bucket
+func (m *ServiceMgr) replayDeadLetters(appName string) (stats *common.DeadLetterReplayStats, info *runtimeInfo) {
+	logPrefix := "ServiceMgr::replayDeadLetters"
+
+	app, info := m.getTempStore(appName)
+	if info.Code != m.statusCodes.ok.Code {
+		return
+	}
+
+	if app.DeploymentConfig.DeadLetter == nil {
+		info.Code = m.statusCodes.errInvalidConfig.Code
+		info.Info = fmt.Sprintf("Function: %s doesn't have dead letter bucket configured", appName)
+		logging.Errorf("%s %s", logPrefix, info.Info)
+		return
+	}
+
+	if m.superSup.GetAppState(appName) != common.AppStateEnabled {
+		info.Code = m.statusCodes.errAppNotDeployed.Code
+		info.Info = fmt.Sprintf("Function: %s is not processing mutations, dead letters can only be replayed through deployed function", appName)
+		logging.Errorf("%s %s", logPrefix, info.Info)
+		return
+	}
+
+	stats, err := m.superSup.ReplayDeadLetters(appName)
+	if err != nil {
+		info.Code = m.statusCodes.errDeadLetterReplay.Code
+		info.Info = fmt.Sprintf("Function: %s failed to replay dead letters, err: %v", appName, err)
+		logging.Errorf("%s %s", logPrefix, info.Info)
+		return
+	}
+
+	info.Code = m.statusCodes.ok.Code
+	return
+}
+
+// testApp runs saved handler code against the supplied document in a sandboxed worker
+func (m *ServiceMgr) testApp(appName string, req *common.HandlerTestRequest) (result *common.HandlerTestResult, info *runtimeInfo) {
+	logPrefix := "ServiceMgr::testApp"
+
+	info = &runtimeInfo{}
+
+	if req.Op != common.HandlerTestOpUpdate && req.Op != common.HandlerTestOpDelete && req.Op != common.HandlerTestOpExpire {
+		info.Code = m.statusCodes.errInvalidConfig.Code
+		info.Info = fmt.Sprintf("Function: %s op should be one of %s, %s or %s, received: %s",
+			appName, common.HandlerTestOpUpdate, common.HandlerTestOpDelete, common.HandlerTestOpExpire, req.Op)
+		logging.Errorf("%s %s", logPrefix, info.Info)
+		return
+	}
+
+	if req.Key == "" {
+		info.Code = m.statusCodes.errInvalidConfig.Code
+		info.Info = fmt.Sprintf("Function: %s key can't be empty", appName)
+		logging.Errorf("%s %s", logPrefix, info.Info)
+		return
+	}
+
+	if req.Vbucket != nil && int(*req.Vbucket) >= util.GetMaxVbuckets() {
+		info.Code = m.statusCodes.errInvalidConfig.Code
+		info.Info = fmt.Sprintf("Function: %s vb should be less than %d, received: %d",
+			appName, util.GetMaxVbuckets(), *req.Vbucket)
+		logging.Errorf("%s %s", logPrefix, info.Info)
+		return
+	}
+
+	var doc interface{}
+	if req.Op == common.HandlerTestOpUpdate && json.Unmarshal(req.Value, &doc) != nil {
+		info.Code = m.statusCodes.errInvalidConfig.Code
+		info.Info = fmt.Sprintf("Function: %s value should be a valid JSON document for %s", appName, req.Op)
+		logging.Errorf("%s %s", logPrefix, info.Info)
+		return
+	}
+
+	app, info := m.getTempStore(appName)
+	if info.Code != m.statusCodes.ok.Code {
+		return
+	}
+
+	appContent := m.encodeAppPayload(&app)
+	handlerHeaders := util.ToStringArray(app.Settings["handler_headers"])
+	handlerFooters := util.ToStringArray(app.Settings["handler_footers"])
+
+	c := &consumer.Consumer{}
+	result, err := c.SpawnTestWorker(app.AppHandlers, string(appContent), app.Name, m.adminHTTPPort,
+		handlerHeaders, handlerFooters, req)
+	if err != nil {
+		info.Code = m.statusCodes.errHandlerTestRun.Code
+		info.Info = fmt.Sprintf("Function: %s test run failed, err: %v", appName, err)
+		logging.Errorf("%s %s", logPrefix, info.Info)
+		return
+	}
+
+	info.Code = m.statusCodes.ok.Code
+	return
+}
+
 func (m *ServiceMgr) getPrimaryStoreHandler(w http.ResponseWriter, r *http.Request) {
 	logPrefix := "ServiceMgr::getPrimaryStoreHandler"
 
-	if !m.validateAuth(w, r, EventingPermissionManage) {
+	creds, ok := m.authenticate(w, r)
+	if !ok {
 		return
 	}
 
@@ -1016,9 +1219,10 @@ func (m *ServiceMgr) getPrimaryStoreHandler(w http.ResponseWriter, r *http.Reque
 	audit.Log(auditevent.FetchFunctions, r, nil)
 
 	appList := util.ListChildren(metakvAppsPath)
-	respData := make([]application, len(appList))
+	respData := make([]application, 0, len(appList))
+	filter := m.requestAppFilter(r, creds)
 
-	for index, appName := range appList {
+	for _, appName := range appList {
 		data, err := util.ReadAppContent(metakvAppsPath, metakvChecksumPath, appName)
 		if err == nil {
 
@@ -1028,6 +1232,11 @@ func (m *ServiceMgr) getPrimaryStoreHandler(w http.ResponseWriter, r *http.Reque
 			app.AppHandlers = string(config.AppCode())
 			app.Name = string(config.AppName())
 			app.ID = int(config.Id())
+			app.Group = string(config.Group())
+
+			if !filter(app) {
+				continue
+			}
 
 			d := new(cfg.DepCfg)
 			depcfg := new(depCfg)
@@ -1036,12 +1245,24 @@ func (m *ServiceMgr) getPrimaryStoreHandler(w http.ResponseWriter, r *http.Reque
 			depcfg.MetadataBucket = string(dcfg.MetadataBucket())
 			depcfg.SourceBucket = string(dcfg.SourceBucket())
 
+			if dl := dcfg.DeadLetter(new(cfg.DeadLetter)); dl != nil {
+				depcfg.DeadLetter = &deadLetter{
+					BucketName: string(dl.BucketName()),
+					KeyPrefix:  string(dl.KeyPrefix()),
+				}
+			}
+
+			if f := dcfg.Filter(new(cfg.Filter)); f != nil {
+				depcfg.Filter = decodeFilter(f)
+			}
+
 			var buckets []bucket
 			b := new(cfg.Bucket)
 			for i := 0; i < dcfg.BucketsLength(); i++ {
 
 				if dcfg.Buckets(b, i) {
 					newBucket := bucket{
+						Access:     string(b.Access()),
 						Alias:      string(b.Alias()),
 						BucketName: string(b.BucketName()),
 					}
@@ -1049,6 +1270,13 @@ func (m *ServiceMgr) getPrimaryStoreHandler(w http.ResponseWriter, r *http.Reque
 				}
 			}
 
+			c := new(cfg.Curl)
+			for i := 0; i < dcfg.CurlLength(); i++ {
+				if dcfg.Curl(c, i) {
+					depcfg.Curl = append(depcfg.Curl, decodeCurlBinding(c))
+				}
+			}
+
 			settingsPath := metakvAppSettingsPath + appName
 			sData, sErr := util.MetakvGet(settingsPath)
 			if sErr == nil {
@@ -1065,8 +1293,9 @@ func (m *ServiceMgr) getPrimaryStoreHandler(w http.ResponseWriter, r *http.Reque
 
 			depcfg.Buckets = buckets
 			app.DeploymentConfig = *depcfg
+			redactSecrets(app)
 
-			respData[index] = *app
+			respData = append(respData, *app)
 		}
 	}
 
@@ -1085,8 +1314,8 @@ func (m *ServiceMgr) getPrimaryStoreHandler(w http.ResponseWriter, r *http.Reque
 func (m *ServiceMgr) getTempStoreHandler(w http.ResponseWriter, r *http.Request) {
 	logPrefix := "ServiceMgr::getTempStoreHandler"
 
-	if !m.validateAuth(w, r, EventingPermissionManage) {
-		w.WriteHeader(http.StatusUnauthorized)
+	creds, ok := m.authenticate(w, r)
+	if !ok {
 		fmt.Fprintln(w, `{"error":"Request not authorized"}`)
 		return
 	}
@@ -1096,7 +1325,14 @@ func (m *ServiceMgr) getTempStoreHandler(w http.ResponseWriter, r *http.Request)
 	// cluster it will log lot of this message.
 	logging.Tracef("%s fetching function draft definitions", logPrefix)
 	audit.Log(auditevent.FetchDrafts, r, nil)
-	applications := m.getTempStoreAll()
+	applications := make([]application, 0)
+	filter := m.requestAppFilter(r, creds)
+	for _, app := range m.getTempStoreAll() {
+		if app.Name != "" && filter(&app) {
+			redactSecrets(&app)
+			applications = append(applications, app)
+		}
+	}
 
 	data, err := json.Marshal(applications)
 	if err != nil {
@@ -1170,7 +1406,8 @@ func (m *ServiceMgr) getTempStoreAll() []application {
 
 func (m *ServiceMgr) saveTempStoreHandler(w http.ResponseWriter, r *http.Request) {
 	logPrefix := "ServiceMgr::saveTempStoreHandler"
-	if !m.validateAuth(w, r, EventingPermissionManage) {
+	creds, ok := m.authenticate(w, r)
+	if !ok {
 		return
 	}
 
@@ -1203,6 +1440,12 @@ func (m *ServiceMgr) saveTempStoreHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	if info := m.checkAppSaveAccess(creds, &app); info.Code != m.statusCodes.ok.Code {
+		m.sendErrorInfo(w, info)
+		return
+	}
+
+	m.reconcileSecrets(r, &app)
 	if info := m.validateApplication(&app); info.Code != m.statusCodes.ok.Code {
 		m.sendErrorInfo(w, info)
 		return
@@ -1218,6 +1461,14 @@ func (m *ServiceMgr) saveTempStore(app application) (info *runtimeInfo) {
 	info = &runtimeInfo{}
 	appName := app.Name
 
+	app, err := sealSecrets(app)
+	if err != nil {
+		info.Code = m.statusCodes.errSaveAppTs.Code
+		info.Info = fmt.Sprintf("Function: %s failed to encrypt secrets, err: %v", appName, err)
+		logging.Errorf("%s %s", logPrefix, info.Info)
+		return
+	}
+
 	data, err := json.Marshal(app)
 	if err != nil {
 		info.Code = m.statusCodes.errMarshalResp.Code
@@ -1251,7 +1502,8 @@ func (m *ServiceMgr) saveTempStore(app application) (info *runtimeInfo) {
 
 func (m *ServiceMgr) savePrimaryStoreHandler(w http.ResponseWriter, r *http.Request) {
 	logPrefix := "ServiceMgr::savePrimaryStoreHandler"
-	if !m.validateAuth(w, r, EventingPermissionManage) {
+	creds, ok := m.authenticate(w, r)
+	if !ok {
 		return
 	}
 
@@ -1279,6 +1531,12 @@ func (m *ServiceMgr) savePrimaryStoreHandler(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
+	if info := m.checkAppSaveAccess(creds, &app); info.Code != m.statusCodes.ok.Code {
+		m.sendErrorInfo(w, info)
+		return
+	}
+
+	m.reconcileSecrets(r, &app)
 	if info := m.validateApplication(&app); info.Code != m.statusCodes.ok.Code {
 		m.sendErrorInfo(w, info)
 		return
@@ -1322,10 +1580,12 @@ func (m *ServiceMgr) encodeAppPayload(app *application) []byte {
 	for i := 0; i < len(app.DeploymentConfig.Buckets); i++ {
 		alias := builder.CreateString(app.DeploymentConfig.Buckets[i].Alias)
 		bName := builder.CreateString(app.DeploymentConfig.Buckets[i].BucketName)
+		access := builder.CreateString(bindingAccess(&app.DeploymentConfig.Buckets[i], app.DeploymentConfig.SourceBucket))
 
 		cfg.BucketStart(builder)
 		cfg.BucketAddAlias(builder, alias)
 		cfg.BucketAddBucketName(builder, bName)
+		cfg.BucketAddAccess(builder, access)
 		csBucket := cfg.BucketEnd(builder)
 
 		bNames = append(bNames, csBucket)
@@ -1340,14 +1600,55 @@ func (m *ServiceMgr) encodeAppPayload(app *application) []byte {
 	metaBucket := builder.CreateString(app.DeploymentConfig.MetadataBucket)
 	sourceBucket := builder.CreateString(app.DeploymentConfig.SourceBucket)
 
+	// Constants were validated to be JSON, hence encoding can't fail. Secret ones are masked,
+	// deployed workers get their values from settings through eventing-producer
+	constantsJSON, _ := util.ConstantsToJSON(redactConstants(app.DeploymentConfig.Constants))
+	constants := builder.CreateString(constantsJSON)
+
+	var dlCfg flatbuffers.UOffsetT
+	if app.DeploymentConfig.DeadLetter != nil {
+		dlBucket := builder.CreateString(app.DeploymentConfig.DeadLetter.BucketName)
+		dlKeyPrefix := builder.CreateString(app.DeploymentConfig.DeadLetter.KeyPrefix)
+
+		cfg.DeadLetterStart(builder)
+		cfg.DeadLetterAddBucketName(builder, dlBucket)
+		cfg.DeadLetterAddKeyPrefix(builder, dlKeyPrefix)
+		dlCfg = cfg.DeadLetterEnd(builder)
+	}
+
+	var filterCfg flatbuffers.UOffsetT
+	if app.DeploymentConfig.Filter != nil {
+		filterCfg = encodeFilter(builder, app.DeploymentConfig.Filter)
+	}
+
+	curlBindings := make([]flatbuffers.UOffsetT, 0, len(app.DeploymentConfig.Curl))
+	for i := range app.DeploymentConfig.Curl {
+		curlBindings = append(curlBindings, encodeCurlBinding(builder, &app.DeploymentConfig.Curl[i]))
+	}
+
+	cfg.DepCfgStartCurlVector(builder, len(curlBindings))
+	for i := len(curlBindings) - 1; i >= 0; i-- {
+		builder.PrependUOffsetT(curlBindings[i])
+	}
+	curlVector := builder.EndVector(len(curlBindings))
+
 	cfg.DepCfgStart(builder)
 	cfg.DepCfgAddBuckets(builder, buckets)
 	cfg.DepCfgAddMetadataBucket(builder, metaBucket)
 	cfg.DepCfgAddSourceBucket(builder, sourceBucket)
+	cfg.DepCfgAddConstants(builder, constants)
+	cfg.DepCfgAddCurl(builder, curlVector)
+	if app.DeploymentConfig.DeadLetter != nil {
+		cfg.DepCfgAddDeadLetter(builder, dlCfg)
+	}
+	if app.DeploymentConfig.Filter != nil {
+		cfg.DepCfgAddFilter(builder, filterCfg)
+	}
 	depcfg := cfg.DepCfgEnd(builder)
 
 	appCode := builder.CreateString(app.AppHandlers)
 	aName := builder.CreateString(app.Name)
+	group := builder.CreateString(app.Group)
 
 	cfg.ConfigStart(builder)
 	cfg.ConfigAddId(builder, uint32(app.ID))
@@ -1355,6 +1656,7 @@ func (m *ServiceMgr) encodeAppPayload(app *application) []byte {
 	cfg.ConfigAddAppName(builder, aName)
 	cfg.ConfigAddDepCfg(builder, depcfg)
 	cfg.ConfigAddHandlerUUID(builder, app.HandlerUUID)
+	cfg.ConfigAddGroup(builder, group)
 
 	udtp := byte(0x0)
 	if app.UsingTimer {
@@ -1368,8 +1670,138 @@ func (m *ServiceMgr) encodeAppPayload(app *application) []byte {
 	return builder.FinishedBytes()
 }
 
+func encodeFilter(builder *flatbuffers.Builder, f *filter) flatbuffers.UOffsetT {
+	predicates := make([]flatbuffers.UOffsetT, 0, len(f.Predicates))
+	for _, predicate := range f.Predicates {
+		// Value of equals predicate is stored as single element list
+		rawValues := predicate.Values
+		if predicate.Op == common.FilterOpEquals {
+			rawValues = []json.RawMessage{predicate.Value}
+		}
+
+		values := make([]flatbuffers.UOffsetT, 0, len(rawValues))
+		for _, value := range rawValues {
+			values = append(values, builder.CreateString(string(value)))
+		}
+
+		cfg.FilterPredicateStartValuesVector(builder, len(values))
+		for i := len(values) - 1; i >= 0; i-- {
+			builder.PrependUOffsetT(values[i])
+		}
+		valuesVector := builder.EndVector(len(values))
+
+		field := builder.CreateString(predicate.Field)
+		op := builder.CreateString(predicate.Op)
+
+		cfg.FilterPredicateStart(builder)
+		cfg.FilterPredicateAddField(builder, field)
+		cfg.FilterPredicateAddOp(builder, op)
+		cfg.FilterPredicateAddValues(builder, valuesVector)
+		predicates = append(predicates, cfg.FilterPredicateEnd(builder))
+	}
+
+	cfg.FilterStartPredicatesVector(builder, len(predicates))
+	for i := len(predicates) - 1; i >= 0; i-- {
+		builder.PrependUOffsetT(predicates[i])
+	}
+	predicatesVector := builder.EndVector(len(predicates))
+
+	keyPrefix := builder.CreateString(f.KeyPrefix)
+	keyRegex := builder.CreateString(f.KeyRegex)
+
+	cfg.FilterStart(builder)
+	cfg.FilterAddKeyPrefix(builder, keyPrefix)
+	cfg.FilterAddKeyRegex(builder, keyRegex)
+	cfg.FilterAddPredicates(builder, predicatesVector)
+	return cfg.FilterEnd(builder)
+}
+
+func encodeCurlBinding(builder *flatbuffers.Builder, binding *curlBinding) flatbuffers.UOffsetT {
+	methods := make([]flatbuffers.UOffsetT, 0, len(binding.AllowedMethods))
+	for _, method := range binding.AllowedMethods {
+		methods = append(methods, builder.CreateString(method))
+	}
+
+	cfg.CurlStartAllowedMethodsVector(builder, len(methods))
+	for i := len(methods) - 1; i >= 0; i-- {
+		builder.PrependUOffsetT(methods[i])
+	}
+	methodsVector := builder.EndVector(len(methods))
+
+	alias := builder.CreateString(binding.Alias)
+	baseURL := builder.CreateString(binding.BaseURL)
+	authType := builder.CreateString(binding.AuthType)
+	username := builder.CreateString(binding.Username)
+	password := builder.CreateString(string(binding.Password))
+	bearerKey := builder.CreateString(string(binding.BearerKey))
+
+	cfg.CurlStart(builder)
+	cfg.CurlAddAlias(builder, alias)
+	cfg.CurlAddBaseUrl(builder, baseURL)
+	cfg.CurlAddAuthType(builder, authType)
+	cfg.CurlAddUsername(builder, username)
+	cfg.CurlAddPassword(builder, password)
+	cfg.CurlAddBearerKey(builder, bearerKey)
+	cfg.CurlAddAllowedMethods(builder, methodsVector)
+	return cfg.CurlEnd(builder)
+}
+
+func decodeCurlBinding(c *cfg.Curl) curlBinding {
+	binding := curlBinding{
+		Alias:     string(c.Alias()),
+		BaseURL:   string(c.BaseUrl()),
+		AuthType:  string(c.AuthType()),
+		Username:  string(c.Username()),
+		Password:  secret(c.Password()),
+		BearerKey: secret(c.BearerKey()),
+	}
+
+	for i := 0; i < c.AllowedMethodsLength(); i++ {
+		binding.AllowedMethods = append(binding.AllowedMethods, string(c.AllowedMethods(i)))
+	}
+	return binding
+}
+
+func decodeFilter(f *cfg.Filter) *filter {
+	decoded := &filter{
+		KeyPrefix: string(f.KeyPrefix()),
+		KeyRegex:  string(f.KeyRegex()),
+	}
+
+	predicate := new(cfg.FilterPredicate)
+	for i := 0; i < f.PredicatesLength(); i++ {
+		if !f.Predicates(predicate, i) {
+			continue
+		}
+
+		fp := filterPredicate{
+			Field: string(predicate.Field()),
+			Op:    string(predicate.Op()),
+		}
+
+		for j := 0; j < predicate.ValuesLength(); j++ {
+			value := json.RawMessage(string(predicate.Values(j)))
+			if fp.Op == common.FilterOpEquals {
+				fp.Value = value
+			} else {
+				fp.Values = append(fp.Values, value)
+			}
+		}
+
+		decoded.Predicates = append(decoded.Predicates, fp)
+	}
+
+	return decoded
+}
+
 // Saves application to metakv and returns appropriate success/error code
 func (m *ServiceMgr) savePrimaryStore(app application) (info *runtimeInfo) {
+	return m.savePrimaryStoreImpl(app, false)
+}
+
+// savePrimaryStoreImpl saves application, which may replace a paused function if
+// keepPaused is set, as it resumes with whatever definition is stored
+func (m *ServiceMgr) savePrimaryStoreImpl(app application, keepPaused bool) (info *runtimeInfo) {
 	logPrefix := "ServiceMgr::savePrimaryStore"
 
 	info = &runtimeInfo{}
@@ -1381,7 +1813,7 @@ func (m *ServiceMgr) savePrimaryStore(app application) (info *runtimeInfo) {
 		return
 	}
 
-	if m.checkIfDeployed(app.Name) {
+	if m.checkIfDeployed(app.Name) && !(keepPaused && m.superSup.GetAppState(app.Name) == common.AppStateDisabled) {
 		info.Code = m.statusCodes.errAppDeployed.Code
 		info.Info = fmt.Sprintf("Function: %s another function with same name is already deployed, skipping save request", app.Name)
 		logging.Errorf("%s %s", logPrefix, info.Info)
@@ -1396,6 +1828,15 @@ func (m *ServiceMgr) savePrimaryStore(app application) (info *runtimeInfo) {
 		return
 	}
 
+	// Secrets stay encrypted in primary store as well, producer decrypts them for eventing-consumer
+	app, err := sealSecrets(app)
+	if err != nil {
+		info.Code = m.statusCodes.errSaveAppPs.Code
+		info.Info = fmt.Sprintf("Function: %s failed to encrypt secrets, err: %v", app.Name, err)
+		logging.Errorf("%s %s", logPrefix, info.Info)
+		return
+	}
+
 	appContent := m.encodeAppPayload(&app)
 
 	if len(appContent) > maxHandlerSize {
@@ -1437,6 +1878,13 @@ func (m *ServiceMgr) savePrimaryStore(app application) (info *runtimeInfo) {
 	settingsPath := metakvAppSettingsPath + app.Name
 	settings := app.Settings
 
+	// Constants are mirrored in settings, which deployed workers watch for changes
+	if len(app.DeploymentConfig.Constants) > 0 {
+		settings["constants"] = app.DeploymentConfig.Constants
+	} else {
+		delete(settings, "constants")
+	}
+
 	mData, mErr := json.Marshal(&settings)
 	if mErr != nil {
 		info.Code = m.statusCodes.errMarshalResp.Code
@@ -1472,6 +1920,11 @@ func (m *ServiceMgr) savePrimaryStore(app application) (info *runtimeInfo) {
 	var wInfo warningsInfo
 	wInfo.Status = "Stored function config in metakv"
 
+	if err = m.saveAppVersion(&app); err != nil {
+		logging.Errorf("%s Function: %s failed to save revision history, err: %v", logPrefix, app.Name, err)
+		wInfo.Warnings = append(wInfo.Warnings, fmt.Sprintf("Function '%s' revision could not be recorded in history", app.Name))
+	}
+
 	switch strings.ToLower(compilationInfo.Level) {
 	case "dp":
 		msg := fmt.Sprintf("Function '%s' uses Developer Preview features. Do not use in production environments", app.Name)
@@ -1776,17 +2229,292 @@ func (m *ServiceMgr) functionsHandler(w http.ResponseWriter, r *http.Request) {
 	logPrefix := "ServiceMgr::functionsHandler"
 
 	w.Header().Set("Content-Type", "application/json")
-	if !m.validateAuth(w, r, EventingPermissionManage) {
+	creds, ok := m.authenticate(w, r)
+	if !ok {
 		fmt.Fprintln(w, `{"error":"Request not authorized"}`)
 		return
 	}
 
 	functions := regexp.MustCompile("^/api/v1/functions/?$")
+	functionsAny := regexp.MustCompile("^/api/v1/functions/([^/]+)(/.*)?$")
 	functionsName := regexp.MustCompile("^/api/v1/functions/(.+[^/])/?$") // Match is agnostic of trailing '/'
 	functionsNameSettings := regexp.MustCompile("^/api/v1/functions/(.+[^/])/settings/?$")
 	functionsNameRetry := regexp.MustCompile("^/api/v1/functions/(.+[^/])/retry/?$")
+	functionsNamePause := regexp.MustCompile("^/api/v1/functions/(.+[^/])/pause/?$")
+	functionsNameResume := regexp.MustCompile("^/api/v1/functions/(.+[^/])/resume/?$")
+	functionsNameVersions := regexp.MustCompile("^/api/v1/functions/(.+[^/])/versions/?$")
+	functionsNameRestore := regexp.MustCompile("^/api/v1/functions/(.+[^/])/versions/([0-9]+)/restore/?$")
+	functionsNameReplay := regexp.MustCompile("^/api/v1/functions/(.+[^/])/deadletter/replay/?$")
+	functionsNameTest := regexp.MustCompile("^/api/v1/functions/(.+[^/])/test/?$")
+	functionsNameTimers := regexp.MustCompile("^/api/v1/functions/(.+[^/])/timers/?$")
+	functionsGraph := regexp.MustCompile("^/api/v1/functions/graph/?$")
+	functionsBulk := regexp.MustCompile("^/api/v1/functions/" + bulkOpPathName + "/?$")
+
+	// Only a POST naming an action is taken for a bulk operation, other requests fall through,
+	// so that a function named "bulk" saved before the name got reserved stays manageable
+	var bulkReq *bulkOpRequest
+	if functionsBulk.MatchString(r.URL.Path) && r.Method == "POST" {
+		data, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			info := &runtimeInfo{}
+			info.Code = m.statusCodes.errReadReq.Code
+			info.Info = fmt.Sprintf("failed to read request body, err: %v", err)
+			logging.Errorf("%s %s", logPrefix, info.Info)
+			m.sendErrorInfo(w, info)
+			return
+		}
+
+		var req bulkOpRequest
+		if err = json.Unmarshal(data, &req); err == nil && req.Action != "" {
+			bulkReq = &req
+		}
+		r.Body = ioutil.NopCloser(bytes.NewReader(data))
+	}
+
+	// Endpoints of a function are authorized on its group, graph needs access to all functions.
+	// Creating a function is authorized on the group in its definition, by the handler below
+	if match := functionsAny.FindStringSubmatch(r.URL.Path); len(match) != 0 {
+		info := &runtimeInfo{}
+		switch {
+		case bulkReq != nil:
+			info.Code = m.statusCodes.ok.Code
+		case functionsGraph.MatchString(r.URL.Path) && r.Method == "GET":
+			info = m.checkGroupAccess(creds, "")
+		case r.Method == "POST" && (match[2] == "" || match[2] == "/") && !m.appExists(match[1]):
+			info.Code = m.statusCodes.ok.Code
+		default:
+			info = m.checkAppAccess(creds, match[1])
+		}
+
+		if info.Code != m.statusCodes.ok.Code {
+			logging.Warnf("%s Cannot authorize request to %rs, %v", logPrefix, r.URL, info.Info)
+			m.sendErrorInfo(w, info)
+			return
+		}
+	}
+
+	if bulkReq != nil {
+		audit.Log(auditevent.BulkFunctionOperation, r, fmt.Sprintf("%s names: %v pattern: %s",
+			bulkReq.Action, bulkReq.Names, bulkReq.NamePattern))
+
+		results, info := m.bulkOp(bulkReq, creds)
+		if info.Code != m.statusCodes.ok.Code {
+			m.sendErrorInfo(w, info)
+			return
+		}
+
+		m.sendBulkOpResults(w, results)
+	} else if match := functionsGraph.FindStringSubmatch(r.URL.Path); len(match) != 0 && r.Method == "GET" {
+		// Only GET is served for graph, other methods fall through so that a function named "graph" stays manageable
+		audit.Log(auditevent.FetchFunctionGraph, r, nil)
+
+		includeUndeployed := r.URL.Query().Get("include_undeployed") == "true"
+		graph := m.buildFunctionGraph(nil, includeUndeployed)
+
+		response, err := json.Marshal(graph)
+		if err != nil {
+			info := &runtimeInfo{}
+			info.Code = m.statusCodes.errMarshalResp.Code
+			info.Info = fmt.Sprintf("failed to marshal function graph, err : %v", err)
+			logging.Errorf("%s %s", logPrefix, info.Info)
+			m.sendErrorInfo(w, info)
+			return
+		}
+
+		w.Header().Add(headerKey, strconv.Itoa(m.statusCodes.ok.Code))
+		fmt.Fprintf(w, "%s", string(response))
+	} else if match := functionsNameTest.FindStringSubmatch(r.URL.Path); len(match) != 0 {
+		appName := match[1]
+		info := &runtimeInfo{}
+
+		if r.Method != "POST" {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		audit.Log(auditevent.TestFunction, r, appName)
+
+		data, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			info.Code = m.statusCodes.errReadReq.Code
+			info.Info = fmt.Sprintf("failed to read request body, err: %v", err)
+			logging.Errorf("%s %s", logPrefix, info.Info)
+			m.sendErrorInfo(w, info)
+			return
+		}
+
+		var req common.HandlerTestRequest
+		err = json.Unmarshal(data, &req)
+		if err != nil {
+			info.Code = m.statusCodes.errUnmarshalPld.Code
+			info.Info = fmt.Sprintf("failed to unmarshal test request, err: %v", err)
+			logging.Errorf("%s %s", logPrefix, info.Info)
+			m.sendErrorInfo(w, info)
+			return
+		}
+
+		result, info := m.testApp(appName, &req)
+		if info.Code != m.statusCodes.ok.Code {
+			m.sendErrorInfo(w, info)
+			return
+		}
+
+		response, err := json.Marshal(result)
+		if err != nil {
+			info.Code = m.statusCodes.errMarshalResp.Code
+			info.Info = fmt.Sprintf("failed to marshal test result, err : %v", err)
+			logging.Errorf("%s %s", logPrefix, info.Info)
+			m.sendErrorInfo(w, info)
+			return
+		}
+
+		w.Header().Add(headerKey, strconv.Itoa(m.statusCodes.ok.Code))
+		fmt.Fprintf(w, "%s", string(response))
+	} else if match := functionsNameReplay.FindStringSubmatch(r.URL.Path); len(match) != 0 {
+		appName := match[1]
+
+		if r.Method != "POST" {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		audit.Log(auditevent.ReplayDeadLetters, r, appName)
+
+		stats, info := m.replayDeadLetters(appName)
+		if info.Code != m.statusCodes.ok.Code {
+			m.sendErrorInfo(w, info)
+			return
+		}
+
+		response, err := json.Marshal(stats)
+		if err != nil {
+			info.Code = m.statusCodes.errMarshalResp.Code
+			info.Info = fmt.Sprintf("failed to marshal dead letter replay stats, err : %v", err)
+			logging.Errorf("%s %s", logPrefix, info.Info)
+			m.sendErrorInfo(w, info)
+			return
+		}
+
+		w.Header().Add(headerKey, strconv.Itoa(m.statusCodes.ok.Code))
+		fmt.Fprintf(w, "%s", string(response))
+	} else if match := functionsNameTimers.FindStringSubmatch(r.URL.Path); len(match) != 0 {
+		appName := match[1]
+
+		var response []byte
+		var err error
+
+		switch r.Method {
+		case "GET":
+			audit.Log(auditevent.ListTimers, r, appName)
+
+			records, info := m.listTimers(appName, r.URL.Query())
+			if info.Code != m.statusCodes.ok.Code {
+				m.sendErrorInfo(w, info)
+				return
+			}
+			response, err = json.Marshal(records)
+
+		case "DELETE":
+			audit.Log(auditevent.CancelTimers, r, appName)
+
+			cancelled, info := m.cancelTimers(appName, r.URL.Query())
+			if info.Code != m.statusCodes.ok.Code {
+				m.sendErrorInfo(w, info)
+				return
+			}
+			response, err = json.Marshal(map[string]int{"cancelled": cancelled})
+
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		if err != nil {
+			info := &runtimeInfo{}
+			info.Code = m.statusCodes.errMarshalResp.Code
+			info.Info = fmt.Sprintf("failed to marshal timers response, err : %v", err)
+			logging.Errorf("%s %s", logPrefix, info.Info)
+			m.sendErrorInfo(w, info)
+			return
+		}
+
+		w.Header().Add(headerKey, strconv.Itoa(m.statusCodes.ok.Code))
+		fmt.Fprintf(w, "%s", string(response))
+	} else if match := functionsNameRestore.FindStringSubmatch(r.URL.Path); len(match) != 0 {
+		appName := match[1]
+		info := &runtimeInfo{}
+
+		if r.Method != "POST" {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		rev, err := strconv.Atoi(match[2])
+		if err != nil {
+			info.Code = m.statusCodes.errInvalidConfig.Code
+			info.Info = fmt.Sprintf("invalid revision: %s, err: %v", match[2], err)
+			logging.Errorf("%s %s", logPrefix, info.Info)
+			m.sendErrorInfo(w, info)
+			return
+		}
+
+		audit.Log(auditevent.RestoreFunctionVersion, r, appName)
+		m.sendRuntimeInfo(w, m.restoreAppVersion(r, appName, rev))
+	} else if match := functionsNameVersions.FindStringSubmatch(r.URL.Path); len(match) != 0 {
+		appName := match[1]
+
+		if r.Method != "GET" {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		audit.Log(auditevent.FetchFunctionVersions, r, appName)
+
+		versions, info := m.getAppVersions(appName)
+		if info.Code != m.statusCodes.ok.Code {
+			m.sendErrorInfo(w, info)
+			return
+		}
+
+		for i := range versions {
+			redactDepCfg(&versions[i].DeploymentConfig)
+			redactSettings(versions[i].Settings)
+		}
+
+		response, err := json.Marshal(versions)
+		if err != nil {
+			info.Code = m.statusCodes.errMarshalResp.Code
+			info.Info = fmt.Sprintf("failed to marshal function versions, err : %v", err)
+			logging.Errorf("%s %s", logPrefix, info.Info)
+			m.sendErrorInfo(w, info)
+			return
+		}
+
+		w.Header().Add(headerKey, strconv.Itoa(m.statusCodes.ok.Code))
+		fmt.Fprintf(w, "%s", string(response))
+	} else if match := functionsNamePause.FindStringSubmatch(r.URL.Path); len(match) != 0 {
+		appName := match[1]
+
+		if r.Method != "POST" {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
 
-	if match := functionsNameRetry.FindStringSubmatch(r.URL.Path); len(match) != 0 {
+		audit.Log(auditevent.PauseFunction, r, appName)
+
+		m.sendRuntimeInfo(w, m.pauseApp(appName))
+	} else if match := functionsNameResume.FindStringSubmatch(r.URL.Path); len(match) != 0 {
+		appName := match[1]
+
+		if r.Method != "POST" {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		audit.Log(auditevent.ResumeFunction, r, appName)
+
+		m.sendRuntimeInfo(w, m.resumeApp(appName))
+	} else if match := functionsNameRetry.FindStringSubmatch(r.URL.Path); len(match) != 0 {
 		appName := match[1]
 		info := &runtimeInfo{}
 
@@ -1832,6 +2560,8 @@ func (m *ServiceMgr) functionsHandler(w http.ResponseWriter, r *http.Request) {
 				return
 			}
 
+			redactSettings(*settings)
+
 			response, err := json.Marshal(settings)
 			if err != nil {
 				info.Code = m.statusCodes.errMarshalResp.Code
@@ -1891,6 +2621,7 @@ func (m *ServiceMgr) functionsHandler(w http.ResponseWriter, r *http.Request) {
 				return
 			}
 
+			redactSecrets(&app)
 			response, err := json.Marshal(app)
 			if err != nil {
 				info.Code = m.statusCodes.errMarshalResp.Code
@@ -1912,6 +2643,12 @@ func (m *ServiceMgr) functionsHandler(w http.ResponseWriter, r *http.Request) {
 				return
 			}
 
+			if info = m.checkAppSaveAccess(creds, &app); info.Code != m.statusCodes.ok.Code {
+				m.sendErrorInfo(w, info)
+				return
+			}
+
+			m.reconcileSecrets(r, &app)
 			if info = m.validateApplication(&app); info.Code != m.statusCodes.ok.Code {
 				m.sendErrorInfo(w, info)
 				return
@@ -1985,12 +2722,18 @@ func (m *ServiceMgr) functionsHandler(w http.ResponseWriter, r *http.Request) {
 				return
 			}
 
-			infoList := m.createApplications(r, appList, false)
+			infoList := m.createApplications(r, appList, false, creds)
 			m.sendRuntimeInfoList(w, infoList)
 
 		case "DELETE":
 			infoList := []*runtimeInfo{}
+			filter := m.requestAppFilter(r, creds)
 			for _, app := range m.getTempStoreAll() {
+				// Only functions the request may manage are deleted
+				if app.Name == "" || !filter(&app) {
+					continue
+				}
+
 				audit.Log(auditevent.DeleteFunction, r, app.Name)
 				info := m.deletePrimaryStore(app.Name)
 				// Delete the application from temp store only if app does not exist in primary store
@@ -2033,7 +2776,8 @@ func (m *ServiceMgr) notifyRetryToAllProducers(appName string, r *retry) (info *
 
 func (m *ServiceMgr) statusHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
-	if !m.validateAuth(w, r, EventingPermissionManage) {
+	creds, ok := m.authenticate(w, r)
+	if !ok {
 		fmt.Fprintln(w, `{"error":"Request not authorized"}`)
 		return
 	}
@@ -2044,7 +2788,7 @@ func (m *ServiceMgr) statusHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	audit.Log(auditevent.ListDeployed, r, nil)
-	response, info := m.statusHandlerImpl()
+	response, info := m.statusHandlerImpl(m.requestAppFilter(r, creds))
 	if info.Code != m.statusCodes.ok.Code {
 		m.sendErrorInfo(w, info)
 		return
@@ -2062,7 +2806,7 @@ func (m *ServiceMgr) statusHandler(w http.ResponseWriter, r *http.Request) {
 	fmt.Fprintf(w, "%s", string(data))
 }
 
-func (m *ServiceMgr) statusHandlerImpl() (response appStatusResponse, info *runtimeInfo) {
+func (m *ServiceMgr) statusHandlerImpl(filter appFilter) (response appStatusResponse, info *runtimeInfo) {
 	appDeployedNodesCounter, numEventingNodes, info := m.getAppList()
 	if info.Code != m.statusCodes.ok.Code {
 		return
@@ -2070,8 +2814,13 @@ func (m *ServiceMgr) statusHandlerImpl() (response appStatusResponse, info *runt
 
 	response.NumEventingNodes = numEventingNodes
 	for _, app := range m.getTempStoreAll() {
+		if !filter(&app) {
+			continue
+		}
+
 		status := appStatus{
 			Name:             app.Name,
+			Group:            app.Group,
 			DeploymentStatus: app.Settings["deployment_status"].(bool),
 			ProcessingStatus: app.Settings["processing_status"].(bool),
 		}
@@ -2101,6 +2850,11 @@ func determineStatus(status appStatus, numEventingNodes int) string {
 		return "undeploying"
 	}
 
+	// Paused functions stay deployed with their checkpoints intact, but don't process mutations
+	if status.DeploymentStatus && !status.ProcessingStatus {
+		return "paused"
+	}
+
 	logging.Errorf("%s Function: %s inconsistent deployment state %v",
 		logPrefix, status.Name, status)
 	return "invalid"
@@ -2108,7 +2862,8 @@ func determineStatus(status appStatus, numEventingNodes int) string {
 
 func (m *ServiceMgr) statsHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
-	if !m.validateAuth(w, r, EventingPermissionManage) {
+	creds, ok := m.authenticate(w, r)
+	if !ok {
 		fmt.Fprintln(w, `{"error":"Request not authorized"}`)
 		return
 	}
@@ -2120,7 +2875,7 @@ func (m *ServiceMgr) statsHandler(w http.ResponseWriter, r *http.Request) {
 			fullStats = typeParam == "full"
 		}
 
-		statsList := m.populateStats(fullStats)
+		statsList := m.populateStats(fullStats, m.requestAppFilter(r, creds))
 
 		response, err := json.Marshal(statsList)
 		if err != nil {
@@ -2166,9 +2921,14 @@ func percentileN(latencyStats map[string]uint64, p int) int {
 	return 0
 }
 
-func (m *ServiceMgr) populateStats(fullStats bool) []stats {
+// populateStats returns stats of deployed functions selected by filter, or of all when it's nil
+func (m *ServiceMgr) populateStats(fullStats bool, filter appFilter) []stats {
 	statsList := make([]stats, 0)
 	for _, app := range m.getTempStoreAll() {
+		if filter != nil && !filter(&app) {
+			continue
+		}
+
 		if m.checkIfDeployed(app.Name) {
 			stats := stats{}
 			stats.EventProcessingStats = m.superSup.GetEventProcessingStats(app.Name)
@@ -2176,6 +2936,7 @@ func (m *ServiceMgr) populateStats(fullStats bool) []stats {
 			stats.ExecutionStats = m.superSup.GetExecutionStats(app.Name)
 			stats.FailureStats = m.superSup.GetFailureStats(app.Name)
 			stats.FunctionName = app.Name
+			stats.Group = app.Group
 			stats.GocbCredsRequestCounter = util.GocbCredsRequestCounter
 			stats.InternalVbDistributionStats = m.superSup.InternalVbDistributionStats(app.Name)
 			stats.LcbCredsRequestCounter = m.lcbCredsCounter
@@ -2195,6 +2956,19 @@ func (m *ServiceMgr) populateStats(fullStats bool) []stats {
 			ls["100"] = percentileN(latencyStats, 100)
 			stats.LatencyPercentileStats = ls
 
+			if usingTimer, ok := app.Settings["using_timer"].(bool); ok && usingTimer {
+				latenessStats := m.superSup.GetTimerLatenessStats(app.Name)
+				tls := make(map[string]int)
+				for _, p := range []int{50, 80, 90, 95, 99, 100} {
+					tls[strconv.Itoa(p)] = percentileN(latenessStats, p)
+				}
+				stats.TimerLatenessPercentileStats = tls
+
+				if debugStats, err := m.superSup.TimerDebugStats(app.Name); err == nil {
+					stats.TimerBacklogStats = summarizeTimerBacklog(debugStats)
+				}
+			}
+
 			if m.rebalancer != nil {
 				rebalanceStats := make(map[string]interface{})
 				rebalanceStats["is_leader"] = true
@@ -2215,6 +2989,7 @@ func (m *ServiceMgr) populateStats(fullStats bool) []stats {
 				}
 
 				stats.LatencyStats = m.superSup.GetLatencyStats(app.Name)
+				stats.TimerLatenessStats = m.superSup.GetTimerLatenessStats(app.Name)
 				stats.SeqsProcessed = m.superSup.GetSeqsProcessed(app.Name)
 				spanBlobDump, err := m.superSup.SpanBlobDump(app.Name)
 				if err == nil {
@@ -2253,12 +3028,14 @@ func (m *ServiceMgr) cleanupEventing(w http.ResponseWriter, r *http.Request) {
 	util.Retry(util.NewFixedBackoff(time.Second), nil, cleanupEventingMetaKvPath, metakvAppsPath)
 	util.Retry(util.NewFixedBackoff(time.Second), nil, cleanupEventingMetaKvPath, metakvTempAppsPath)
 	util.Retry(util.NewFixedBackoff(time.Second), nil, cleanupEventingMetaKvPath, metakvAppSettingsPath)
+	util.Retry(util.NewFixedBackoff(time.Second), nil, cleanupEventingMetaKvPath, metakvAppVersionsPath)
+	util.Retry(util.NewFixedBackoff(time.Second), nil, cleanupEventingMetaKvPath, metakvVersionChecksumPath)
 }
 
 func (m *ServiceMgr) exportHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
-	if !m.validateAuth(w, r, EventingPermissionManage) {
-		w.WriteHeader(http.StatusUnauthorized)
+	creds, ok := m.authenticate(w, r)
+	if !ok {
 		fmt.Fprintln(w, `{"error":"Request not authorized"}`)
 		return
 	}
@@ -2270,10 +3047,18 @@ func (m *ServiceMgr) exportHandler(w http.ResponseWriter, r *http.Request) {
 
 	audit.Log(auditevent.ExportFunctions, r, nil)
 
-	apps := m.getTempStoreAll()
-	for _, app := range apps {
+	apps := make([]application, 0)
+	filter := m.requestAppFilter(r, creds)
+	for _, app := range m.getTempStoreAll() {
+		if app.Name != "" && filter(&app) {
+			apps = append(apps, app)
+		}
+	}
+
+	for i, app := range apps {
 		app.Settings["deployment_status"] = false
 		app.Settings["processing_status"] = false
+		redactSecrets(&apps[i])
 	}
 
 	data, err := json.Marshal(apps)
@@ -2290,8 +3075,8 @@ func (m *ServiceMgr) exportHandler(w http.ResponseWriter, r *http.Request) {
 
 func (m *ServiceMgr) importHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
-	if !m.validateAuth(w, r, EventingPermissionManage) {
-		w.WriteHeader(http.StatusUnauthorized)
+	creds, ok := m.authenticate(w, r)
+	if !ok {
 		fmt.Fprintln(w, `{"error":"Request not authorized"}`)
 		return
 	}
@@ -2309,11 +3094,13 @@ func (m *ServiceMgr) importHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	infoList := m.createApplications(r, appList, true)
+	infoList := m.createApplications(r, appList, true, creds)
 	m.sendRuntimeInfoList(w, infoList)
 }
 
-func (m *ServiceMgr) createApplications(r *http.Request, appList *[]application, undeploy bool) (infoList []*runtimeInfo) {
+// createApplications saves the given functions, each authorized against creds unless
+// they are nil, i.e. caller has authorized the request already
+func (m *ServiceMgr) createApplications(r *http.Request, appList *[]application, undeploy bool, creds cbauth.Creds) (infoList []*runtimeInfo) {
 	logPrefix := "ServiceMgr::createApplications"
 
 	infoList = []*runtimeInfo{}
@@ -2321,6 +3108,15 @@ func (m *ServiceMgr) createApplications(r *http.Request, appList *[]application,
 	for _, app := range *appList {
 		audit.Log(auditevent.CreateFunction, r, app.Name)
 
+		if creds != nil {
+			if infoVal := m.checkAppSaveAccess(creds, &app); infoVal.Code != m.statusCodes.ok.Code {
+				logging.Warnf("%s %v", logPrefix, infoVal.Info)
+				infoList = append(infoList, infoVal)
+				continue
+			}
+		}
+
+		m.reconcileSecrets(r, &app)
 		if infoVal := m.validateApplication(&app); infoVal.Code != m.statusCodes.ok.Code {
 			logging.Warnf("%s Validating %ru failed: %v", logPrefix, app, infoVal)
 			infoList = append(infoList, infoVal)
@@ -2384,6 +3180,25 @@ func (m *ServiceMgr) getCpuCount(w http.ResponseWriter, r *http.Request) {
 	fmt.Fprintf(w, "%v\n", util.CPUCount(false))
 }
 
+// getDcpEventsProcessedPSecPerVb reports dcp events processed per second per owned vbucket, averaged
+// over functions running on the node and over the last few minutes
+func (m *ServiceMgr) getDcpEventsProcessedPSecPerVb(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if !m.validateAuth(w, r, EventingPermissionManage) {
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprintln(w, `{"error":"Request not authorized"}`)
+		return
+	}
+
+	if r.Method != "GET" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Add(headerKey, strconv.Itoa(m.statusCodes.ok.Code))
+	fmt.Fprintf(w, "%v\n", m.superSup.GetDcpEventsProcessedPSecPerVb())
+}
+
 func (m *ServiceMgr) getWorkerCount(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	if !m.validateAuth(w, r, EventingPermissionManage) {
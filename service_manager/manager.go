@@ -100,6 +100,7 @@ func (m *ServiceMgr) initService() {
 	http.HandleFunc("/getConsumerPids", m.getEventingConsumerPids)
 	http.HandleFunc("/getCpuCount", m.getCpuCount)
 	http.HandleFunc("/getCreds", m.getCreds)
+	http.HandleFunc("/getDcpEventsProcessedPSecPerVb", m.getDcpEventsProcessedPSecPerVb)
 	http.HandleFunc("/getDcpEventsRemaining", m.getDcpEventsRemaining)
 	http.HandleFunc("/getDebuggerUrl/", m.getDebuggerURL)
 	http.HandleFunc("/getDeployedApps", m.getDeployedApps)
@@ -141,6 +142,7 @@ func (m *ServiceMgr) initService() {
 	http.HandleFunc("/api/v1/export/", m.exportHandler)
 	http.HandleFunc("/api/v1/import", m.importHandler)
 	http.HandleFunc("/api/v1/import/", m.importHandler)
+	http.HandleFunc("/metrics", m.metricsHandler)
 
 	go func() {
 		addr := net.JoinHostPort("", m.adminHTTPPort)
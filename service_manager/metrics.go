@@ -0,0 +1,250 @@
+package servicemanager
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	metricsNamespace   = "eventing"
+	metricsContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+	metricTypeCounter   = "counter"
+	metricTypeGauge     = "gauge"
+	metricTypeHistogram = "histogram"
+
+	// Latency histogram bins reported by eventing-consumer are in microseconds, keyed by
+	// i*HIST_WIDTH of v8worker.h. Bin i holds samples below HIST_FROM+i*HIST_WIDTH, down to
+	// the upper edge of bin i-1, and the last bin holds samples of HIST_TILL and beyond.
+	latencyBinsPerSecond = 1000 * 1000
+	latencyBinOffset     = 100              // HIST_FROM
+	latencyOverflowBin   = 10 * 1000 * 1000 // Key of the last bin
+
+	// Timer lateness histogram bins reported by eventing-consumer are in milliseconds, keyed
+	// by their upper edge, except for the last one which holds lateness beyond it as well
+	timerLatenessBinsPerSecond = 1000
+	timerLatenessOverflowBin   = 60 * 60 * 1000
+)
+
+var (
+	invalidMetricNameChars = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+	// Execution stats that report current levels rather than monotonically increasing counts
+	gaugeExecutionStats = map[string]bool{
+		"agg_queue_memory":    true,
+		"agg_queue_size":      true,
+		"feedback_queue_size": true,
+	}
+
+	labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+)
+
+// histogramLayout describes how bin keys of a histogram reported by eventing-consumer map
+// to their upper edges
+type histogramLayout struct {
+	binsPerSecond float64
+	offset        int // Added to bin key for upper edge of the bin
+	overflowBin   int // Key of the bin with samples beyond the last finite edge
+}
+
+var (
+	latencyLayout       = histogramLayout{latencyBinsPerSecond, latencyBinOffset, latencyOverflowBin}
+	timerLatenessLayout = histogramLayout{timerLatenessBinsPerSecond, 0, timerLatenessOverflowBin}
+)
+
+type metricSample struct {
+	suffix string
+	labels string
+	value  float64
+}
+
+type metricFamily struct {
+	help    string
+	kind    string
+	samples []metricSample
+}
+
+// metricsRegistry groups samples by metric family, as the Prometheus text
+// format requires all samples of a family to be emitted together
+type metricsRegistry struct {
+	families map[string]*metricFamily
+}
+
+func newMetricsRegistry() *metricsRegistry {
+	return &metricsRegistry{families: make(map[string]*metricFamily)}
+}
+
+func (mr *metricsRegistry) add(name, kind, help, suffix, labels string, value float64) {
+	family, ok := mr.families[name]
+	if !ok {
+		family = &metricFamily{help: help, kind: kind}
+		mr.families[name] = family
+	}
+	family.samples = append(family.samples, metricSample{suffix, labels, value})
+}
+
+func (mr *metricsRegistry) write(w io.Writer) {
+	names := make([]string, 0, len(mr.families))
+	for name := range mr.families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		family := mr.families[name]
+		fmt.Fprintf(w, "# HELP %s %s\n", name, family.help)
+		fmt.Fprintf(w, "# TYPE %s %s\n", name, family.kind)
+		for _, sample := range family.samples {
+			fmt.Fprintf(w, "%s%s{%s} %s\n", name, sample.suffix, sample.labels,
+				strconv.FormatFloat(sample.value, 'g', -1, 64))
+		}
+	}
+}
+
+func metricName(parts ...string) string {
+	name := metricsNamespace + "_" + strings.Join(parts, "_")
+	return invalidMetricNameChars.ReplaceAllString(name, "_")
+}
+
+func formatLabels(pairs ...string) string {
+	labels := make([]string, 0, len(pairs)/2)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		labels = append(labels, fmt.Sprintf(`%s="%s"`, pairs[i], labelValueEscaper.Replace(pairs[i+1])))
+	}
+	return strings.Join(labels, ",")
+}
+
+func toFloat64(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case uint64:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	}
+	return 0, false
+}
+
+// addStatsMap adds stats of a group, e.g. execution or failure stats, as families prefixed
+// by the group, so that stats of the same name in different groups don't collide
+func addStatsMap(mr *metricsRegistry, stats map[string]interface{}, group, labels, help string) {
+	for stat, value := range stats {
+		v, ok := toFloat64(value)
+		if !ok {
+			// Skips non-numeric entries like timestamp
+			continue
+		}
+
+		if gaugeExecutionStats[stat] {
+			mr.add(metricName(group, stat), metricTypeGauge, help, "", labels, v)
+		} else {
+			mr.add(metricName(group, stat, "total"), metricTypeCounter, help, "", labels, v)
+		}
+	}
+}
+
+// addLatencyHistogram converts latency bins reported by eventing-consumer into
+// a cumulative histogram
+func addLatencyHistogram(mr *metricsRegistry, latencyStats map[string]uint64, labels string) {
+	addHistogram(mr, metricName("handler_latency_seconds"), "Time taken by handler to process an event",
+		latencyStats, latencyLayout, labels)
+}
+
+// addHistogram emits a bucket per bin at its upper edge, with the overflow bin only counted
+// in the +Inf bucket. Sum is approximated from upper edges, and lower edge of the overflow bin.
+func addHistogram(mr *metricsRegistry, name, help string, stats map[string]uint64, layout histogramLayout, labels string) {
+	var bins sort.IntSlice
+	for bin := range stats {
+		if sample, err := strconv.Atoi(bin); err == nil {
+			bins = append(bins, sample)
+		}
+	}
+	sort.Sort(bins)
+
+	var count uint64
+	var sum float64
+	for _, bin := range bins {
+		binCount := stats[strconv.Itoa(bin)]
+		count += binCount
+
+		if bin >= layout.overflowBin {
+			sum += float64(bin) / layout.binsPerSecond * float64(binCount)
+			continue
+		}
+
+		le := float64(bin+layout.offset) / layout.binsPerSecond
+		sum += le * float64(binCount)
+		mr.add(name, metricTypeHistogram, help, "_bucket",
+			labels+","+formatLabels("le", strconv.FormatFloat(le, 'g', -1, 64)), float64(count))
+	}
+
+	mr.add(name, metricTypeHistogram, help, "_bucket", labels+","+formatLabels("le", "+Inf"), float64(count))
+	mr.add(name, metricTypeHistogram, help, "_sum", labels, sum)
+	mr.add(name, metricTypeHistogram, help, "_count", labels, float64(count))
+}
+
+func (m *ServiceMgr) populateMetrics() *metricsRegistry {
+	mr := newMetricsRegistry()
+
+	for _, appName := range m.superSup.DeployedAppList() {
+		for workerName, workerStats := range m.superSup.GetWorkerStats(appName) {
+			labels := formatLabels("function", appName, "node_uuid", m.uuid, "worker", workerName)
+
+			addStatsMap(mr, workerStats.ExecutionStats, "execution", labels,
+				"Handler execution stat reported by eventing-consumer")
+			addStatsMap(mr, workerStats.FailureStats, "failure", labels,
+				"Handler failure stat reported by eventing-consumer")
+
+			for code, count := range workerStats.LcbExceptionStats {
+				mr.add(metricName("lcb_exceptions_total"), metricTypeCounter,
+					"Libcouchbase exceptions encountered by handler, by error code", "",
+					labels+","+formatLabels("code", code), float64(count))
+			}
+
+			for stat, value := range workerStats.MetaStoreStats {
+				mr.add(metricName(stat, "total"), metricTypeCounter, "Timer metastore operation stat", "",
+					labels, float64(value))
+			}
+
+			for vb, remaining := range workerStats.VbDcpEventsRemaining {
+				mr.add(metricName("dcp_events_remaining"), metricTypeGauge,
+					"DCP events remaining to be processed per vbucket", "",
+					labels+","+formatLabels("vb", strconv.Itoa(vb)), math.Max(float64(remaining), 0))
+			}
+
+			addLatencyHistogram(mr, workerStats.LatencyStats, labels)
+
+			// Only workers of functions using timers report metastore stats
+			if workerStats.MetaStoreStats != nil {
+				addHistogram(mr, metricName("timer_lateness_seconds"), "Time by which timers fired after their due time",
+					workerStats.TimerLatenessStats, timerLatenessLayout, labels)
+			}
+		}
+	}
+
+	return mr
+}
+
+// metricsHandler exposes per function stats in Prometheus text exposition format
+func (m *ServiceMgr) metricsHandler(w http.ResponseWriter, r *http.Request) {
+	if !m.validateAuth(w, r, EventingPermissionManage) {
+		return
+	}
+
+	if r.Method != "GET" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", metricsContentType)
+	m.populateMetrics().write(w)
+}
@@ -0,0 +1,78 @@
+package servicemanager
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestLatencyHistogramText(t *testing.T) {
+	mr := newMetricsRegistry()
+	stats := map[string]uint64{
+		"0":        2, // Below 100us
+		"1000":     3, // 100us to 1.1ms
+		"10000000": 1, // 10s and beyond
+	}
+	addLatencyHistogram(mr, stats, `function="fn"`)
+
+	var out bytes.Buffer
+	mr.write(&out)
+
+	expected := `# HELP eventing_handler_latency_seconds Time taken by handler to process an event
+# TYPE eventing_handler_latency_seconds histogram
+eventing_handler_latency_seconds_bucket{function="fn",le="0.0001"} 2
+eventing_handler_latency_seconds_bucket{function="fn",le="0.0011"} 5
+eventing_handler_latency_seconds_bucket{function="fn",le="+Inf"} 6
+eventing_handler_latency_seconds_sum{function="fn"} 10.0035
+eventing_handler_latency_seconds_count{function="fn"} 6
+`
+	if out.String() != expected {
+		t.Errorf("Unexpected histogram text:\n%s\nexpected:\n%s", out.String(), expected)
+	}
+}
+
+func TestTimerLatenessHistogramText(t *testing.T) {
+	mr := newMetricsRegistry()
+	stats := map[string]uint64{
+		"100":     4,
+		"2000":    1,
+		"3600000": 2, // An hour and beyond
+	}
+	addHistogram(mr, metricName("timer_lateness_seconds"), "lateness", stats, timerLatenessLayout, `function="fn"`)
+
+	var out bytes.Buffer
+	mr.write(&out)
+
+	expected := `# HELP eventing_timer_lateness_seconds lateness
+# TYPE eventing_timer_lateness_seconds histogram
+eventing_timer_lateness_seconds_bucket{function="fn",le="0.1"} 4
+eventing_timer_lateness_seconds_bucket{function="fn",le="2"} 5
+eventing_timer_lateness_seconds_bucket{function="fn",le="+Inf"} 7
+eventing_timer_lateness_seconds_sum{function="fn"} 7202.4
+eventing_timer_lateness_seconds_count{function="fn"} 7
+`
+	if out.String() != expected {
+		t.Errorf("Unexpected histogram text:\n%s\nexpected:\n%s", out.String(), expected)
+	}
+}
+
+func TestStatsMapFamilies(t *testing.T) {
+	mr := newMetricsRegistry()
+	addStatsMap(mr, map[string]interface{}{"on_update_success": uint64(3), "agg_queue_size": int64(5)},
+		"execution", `function="fn"`, "execution")
+	addStatsMap(mr, map[string]interface{}{"on_update_success": uint64(1), "timestamp": "now"},
+		"failure", `function="fn"`, "failure")
+
+	for name, kind := range map[string]string{
+		"eventing_execution_on_update_success_total": metricTypeCounter,
+		"eventing_execution_agg_queue_size":          metricTypeGauge,
+		"eventing_failure_on_update_success_total":   metricTypeCounter,
+	} {
+		family, ok := mr.families[name]
+		if !ok || family.kind != kind || len(family.samples) != 1 {
+			t.Errorf("Family %s should hold one %s sample, got %+v", name, kind, family)
+		}
+	}
+	if len(mr.families) != 3 {
+		t.Errorf("Expected 3 families, got %d", len(mr.families))
+	}
+}
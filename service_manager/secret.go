@@ -0,0 +1,207 @@
+package servicemanager
+
+import (
+	"encoding/json"
+
+	"github.com/couchbase/eventing/logging"
+	"github.com/couchbase/eventing/util"
+)
+
+// secret holds credentials within function definitions. Plaintext is masked when marshalled,
+// so it doesn't leak through REST responses, and is encrypted by sealSecrets before definitions
+// are written to metakv. Encrypted values pass through marshalling as is, so responses need
+// redactSecrets too.
+type secret string
+
+func (s secret) MarshalJSON() ([]byte, error) {
+	if s == "" || util.IsEncryptedSecret(string(s)) {
+		return json.Marshal(string(s))
+	}
+	return json.Marshal(redactedCredential)
+}
+
+func (s *secret) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+
+	plaintext, err := util.DecryptSecret(value)
+	if err != nil {
+		// Left encrypted, so that it's neither lost nor revealed
+		logging.Errorf("ServiceMgr::secret Failed to decrypt secret, err: %v", err)
+		*s = secret(value)
+		return nil
+	}
+
+	*s = secret(plaintext)
+	return nil
+}
+
+func sealSecret(s secret) (secret, error) {
+	if s == "" || s == redactedCredential || util.IsEncryptedSecret(string(s)) {
+		return s, nil
+	}
+
+	sealed, err := util.EncryptSecret(string(s))
+	return secret(sealed), err
+}
+
+// isSealedConstant reports if value of a secret constant is encrypted or masked, i.e. a JSON
+// string that's either the output of util.EncryptSecret or the mask
+func isSealedConstant(c *constant) bool {
+	var value string
+	if json.Unmarshal(c.Value, &value) != nil {
+		return false
+	}
+	return value == redactedCredential || util.IsEncryptedSecret(value)
+}
+
+func isRedactedConstant(c *constant) bool {
+	var value string
+	return json.Unmarshal(c.Value, &value) == nil && value == redactedCredential
+}
+
+// sealConstants returns copy of constants with values of secret ones encrypted. Encrypted
+// value is stored as a JSON string, which util.ConstantsToJSON decrypts for workers.
+func sealConstants(constants []constant) ([]constant, error) {
+	if constants == nil {
+		return nil, nil
+	}
+
+	sealed := make([]constant, len(constants))
+	copy(sealed, constants)
+
+	for i := range sealed {
+		if !sealed[i].Secret || isSealedConstant(&sealed[i]) {
+			continue
+		}
+
+		value, err := util.EncryptSecret(string(sealed[i].Value))
+		if err != nil {
+			return nil, err
+		}
+		sealed[i].Value, _ = json.Marshal(value)
+	}
+	return sealed, nil
+}
+
+// redactConstants returns copy of constants with values of secret ones masked
+func redactConstants(constants []constant) []constant {
+	if constants == nil {
+		return nil
+	}
+
+	redacted := make([]constant, len(constants))
+	copy(redacted, constants)
+
+	mask, _ := json.Marshal(redactedCredential)
+	for i := range redacted {
+		if redacted[i].Secret {
+			redacted[i].Value = mask
+		}
+	}
+	return redacted
+}
+
+// reconcileConstants carries over stored values of secret constants that are masked
+func reconcileConstants(constants, stored []constant) []constant {
+	prev := make(map[string]constant)
+	for _, c := range stored {
+		prev[c.Alias] = c
+	}
+
+	for i := range constants {
+		c := &constants[i]
+		if p, ok := prev[c.Alias]; ok && c.Secret && p.Secret && isRedactedConstant(c) {
+			c.Value = p.Value
+		}
+	}
+	return constants
+}
+
+// reconcileSettings carries over stored values of masked secret constants in settings
+func reconcileSettings(settings, stored map[string]interface{}) {
+	val, ok := settings["constants"]
+	if !ok {
+		return
+	}
+
+	constants, err := constantsFromSettings(val)
+	if err != nil {
+		// Left as is for validation to reject
+		return
+	}
+
+	prev, _ := constantsFromSettings(stored["constants"])
+	settings["constants"] = reconcileConstants(constants, prev)
+}
+
+// sealSecrets returns copy of the function definition with its secrets encrypted, to be
+// persisted in metakv
+func sealSecrets(app application) (application, error) {
+	var err error
+
+	bindings := make([]curlBinding, len(app.DeploymentConfig.Curl))
+	copy(bindings, app.DeploymentConfig.Curl)
+
+	for i := range bindings {
+		if bindings[i].Password, err = sealSecret(bindings[i].Password); err != nil {
+			return app, err
+		}
+		if bindings[i].BearerKey, err = sealSecret(bindings[i].BearerKey); err != nil {
+			return app, err
+		}
+	}
+
+	if app.DeploymentConfig.Curl != nil {
+		app.DeploymentConfig.Curl = bindings
+	}
+
+	if app.DeploymentConfig.Constants, err = sealConstants(app.DeploymentConfig.Constants); err != nil {
+		return app, err
+	}
+
+	// Constants mirrored in settings need sealing as well. Settings are shared with the caller,
+	// which keeps them sealed too, hence secrets never reach metakv in plaintext through them
+	if val, ok := app.Settings["constants"]; ok {
+		constants, err := constantsFromSettings(val)
+		if err != nil {
+			return app, err
+		}
+		if constants, err = sealConstants(constants); err != nil {
+			return app, err
+		}
+		app.Settings["constants"] = constants
+	}
+	return app, nil
+}
+
+// redactSettings masks values of secret constants in settings
+func redactSettings(settings map[string]interface{}) {
+	val, ok := settings["constants"]
+	if !ok {
+		return
+	}
+
+	constants, err := constantsFromSettings(val)
+	if err != nil {
+		// Not something a worker would bind either, hence nothing to reveal
+		delete(settings, "constants")
+		return
+	}
+	settings["constants"] = redactConstants(constants)
+}
+
+// redactSecrets masks all secrets of a function definition, for definitions returned by REST
+// endpoints. Secrets are masked whether or not they are encrypted, so that undecryptable ones
+// don't leak their ciphertext either.
+func redactSecrets(app *application) {
+	redactDepCfg(&app.DeploymentConfig)
+	redactSettings(app.Settings)
+}
+
+func redactDepCfg(cfg *depCfg) {
+	redactCurlCredentials(cfg)
+	cfg.Constants = redactConstants(cfg.Constants)
+}
@@ -174,10 +174,12 @@ func (m *ServiceMgr) StartTopologyChange(change service.TopologyChange) error {
 	switch change.Type {
 	case service.TopologyChangeTypeFailover:
 		util.Retry(util.NewFixedBackoff(time.Second), nil, storeKeepNodesCallback, m.keepNodeUUIDs)
+		util.Retry(util.NewFixedBackoff(time.Second), nil, storeNodeWeightsCallback, m)
 		m.failoverNotif = true
 
 	case service.TopologyChangeTypeRebalance:
 		util.Retry(util.NewFixedBackoff(time.Second), nil, storeKeepNodesCallback, m.keepNodeUUIDs)
+		util.Retry(util.NewFixedBackoff(time.Second), nil, storeNodeWeightsCallback, m)
 
 		m.startRebalance(change)
 
@@ -83,6 +83,14 @@ type statusCodes struct {
 	errAppDelete           statusBase
 	errDebuggerDisabled    statusBase
 	errMixedMode           statusBase
+	errAppNotPaused        statusBase
+	errAppVersionNotFound  statusBase
+	errDeadLetterReplay    statusBase
+	errHandlerTestRun      statusBase
+	errBucketRecursion     statusBase
+	errBulkOpSkipped       statusBase
+	errForbidden           statusBase
+	errTimerOp             statusBase
 }
 
 func (m *ServiceMgr) getDisposition(code int) int {
@@ -167,6 +175,22 @@ func (m *ServiceMgr) getDisposition(code int) int {
 		return http.StatusInternalServerError
 	case m.statusCodes.errMixedMode.Code:
 		return http.StatusInternalServerError
+	case m.statusCodes.errAppNotPaused.Code:
+		return http.StatusNotAcceptable
+	case m.statusCodes.errAppVersionNotFound.Code:
+		return http.StatusNotFound
+	case m.statusCodes.errDeadLetterReplay.Code:
+		return http.StatusInternalServerError
+	case m.statusCodes.errHandlerTestRun.Code:
+		return http.StatusInternalServerError
+	case m.statusCodes.errBucketRecursion.Code:
+		return http.StatusUnprocessableEntity
+	case m.statusCodes.errBulkOpSkipped.Code:
+		return http.StatusFailedDependency
+	case m.statusCodes.errForbidden.Code:
+		return http.StatusForbidden
+	case m.statusCodes.errTimerOp.Code:
+		return http.StatusInternalServerError
 	default:
 		logging.Warnf("Unknown status code: %v", code)
 		return http.StatusInternalServerError
@@ -216,6 +240,14 @@ func (m *ServiceMgr) initErrCodes() {
 		errAppDelete:           statusBase{"ERR_APP_DELETE_NOT_ALLOWED", 44},
 		errDebuggerDisabled:    statusBase{"ERR_DEBUGGER_DISABLED", 45},
 		errMixedMode:           statusBase{"ERR_MIXED_MODE", 46},
+		errAppNotPaused:        statusBase{"ERR_APP_NOT_PAUSED", 47},
+		errAppVersionNotFound:  statusBase{"ERR_APP_VERSION_NOT_FOUND", 48},
+		errDeadLetterReplay:    statusBase{"ERR_DEAD_LETTER_REPLAY", 49},
+		errHandlerTestRun:      statusBase{"ERR_HANDLER_TEST_RUN", 50},
+		errBucketRecursion:     statusBase{"ERR_INTER_BUCKET_RECURSION", 51},
+		errBulkOpSkipped:       statusBase{"ERR_BULK_OP_SKIPPED", 52},
+		errForbidden:           statusBase{"ERR_FORBIDDEN", 53},
+		errTimerOp:             statusBase{"ERR_TIMER_OP", 54},
 	}
 
 	errors := []errorPayload{
@@ -442,6 +474,46 @@ func (m *ServiceMgr) initErrCodes() {
 			Code:        m.statusCodes.errMixedMode.Code,
 			Description: "Unable to start debugger in mixed mode cluster",
 		},
+		{
+			Name:        m.statusCodes.errAppNotPaused.Name,
+			Code:        m.statusCodes.errAppNotPaused.Code,
+			Description: "Function not paused",
+		},
+		{
+			Name:        m.statusCodes.errAppVersionNotFound.Name,
+			Code:        m.statusCodes.errAppVersionNotFound.Code,
+			Description: "Function revision not found in version history",
+		},
+		{
+			Name:        m.statusCodes.errDeadLetterReplay.Name,
+			Code:        m.statusCodes.errDeadLetterReplay.Code,
+			Description: "Failed to replay dead lettered events",
+		},
+		{
+			Name:        m.statusCodes.errHandlerTestRun.Name,
+			Code:        m.statusCodes.errHandlerTestRun.Code,
+			Description: "Failed to test invoke handler",
+		},
+		{
+			Name:        m.statusCodes.errBucketRecursion.Name,
+			Code:        m.statusCodes.errBucketRecursion.Code,
+			Description: "Function would form a cycle with deployed functions through buckets it writes to",
+		},
+		{
+			Name:        m.statusCodes.errBulkOpSkipped.Name,
+			Code:        m.statusCodes.errBulkOpSkipped.Code,
+			Description: "Function skipped by bulk operation as an earlier function failed",
+		},
+		{
+			Name:        m.statusCodes.errForbidden.Name,
+			Code:        m.statusCodes.errForbidden.Code,
+			Description: "Not permitted to manage functions of the group",
+		},
+		{
+			Name:        m.statusCodes.errTimerOp.Name,
+			Code:        m.statusCodes.errTimerOp.Code,
+			Description: "Failed to list or cancel timers of the function",
+		},
 	}
 
 	m.errorCodes = make(map[int]errorPayload)
@@ -0,0 +1,201 @@
+package servicemanager
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/couchbase/eventing/common"
+	"github.com/couchbase/eventing/logging"
+)
+
+// parseTimerQuery reads vbuckets and due time range of timers from query parameters
+// "vb", a comma separated list, and "from", "to" as unix time in seconds
+func (m *ServiceMgr) parseTimerQuery(params url.Values) (query common.TimerQuery, info *runtimeInfo) {
+	info = &runtimeInfo{}
+	info.Code = m.statusCodes.errInvalidConfig.Code
+
+	var err error
+	for _, param := range []string{"from", "to"} {
+		if params.Get(param) == "" {
+			continue
+		}
+
+		var val int64
+		if val, err = strconv.ParseInt(params.Get(param), 10, 64); err != nil || val < 0 {
+			info.Info = fmt.Sprintf("%s should be unix time in seconds, received: %s", param, params.Get(param))
+			return
+		}
+
+		if param == "from" {
+			query.From = val
+		} else {
+			query.To = val
+		}
+	}
+
+	if query.From > 0 && query.To > 0 && query.From > query.To {
+		info.Info = fmt.Sprintf("from: %d is later than to: %d", query.From, query.To)
+		return
+	}
+
+	if params.Get("vb") != "" {
+		for _, vbStr := range strings.Split(params.Get("vb"), ",") {
+			vb, err := strconv.ParseUint(strings.TrimSpace(vbStr), 10, 16)
+			if err != nil {
+				info.Info = fmt.Sprintf("vb should be a list of vbucket numbers, received: %s", params.Get("vb"))
+				return
+			}
+			query.Vbs = append(query.Vbs, uint16(vb))
+		}
+	}
+
+	query.Limit = defaultTimerListLimit
+	if params.Get("limit") != "" {
+		if query.Limit, err = strconv.Atoi(params.Get("limit")); err != nil || query.Limit <= 0 || query.Limit > maxTimerListLimit {
+			info.Info = fmt.Sprintf("limit should be between 1 and %d, received: %s", maxTimerListLimit, params.Get("limit"))
+			return
+		}
+	}
+
+	info.Code = m.statusCodes.ok.Code
+	return
+}
+
+func (m *ServiceMgr) checkTimersAccessible(appName string) (info *runtimeInfo) {
+	app, info := m.getTempStore(appName)
+	if info.Code != m.statusCodes.ok.Code {
+		return
+	}
+
+	if usingTimer, ok := app.Settings["using_timer"].(bool); !ok || !usingTimer {
+		info.Code = m.statusCodes.errInvalidConfig.Code
+		info.Info = fmt.Sprintf("Function: %s doesn't use timers", appName)
+		return
+	}
+
+	if m.superSup.GetAppState(appName) != common.AppStateEnabled {
+		info.Code = m.statusCodes.errAppNotDeployed.Code
+		info.Info = fmt.Sprintf("Function: %s is not processing mutations, timers can only be inspected for deployed function", appName)
+		return
+	}
+
+	info.Code = m.statusCodes.ok.Code
+	return
+}
+
+// listTimers returns pending timers of a deployed function
+func (m *ServiceMgr) listTimers(appName string, params url.Values) (records []*common.TimerRecord, info *runtimeInfo) {
+	logPrefix := "ServiceMgr::listTimers"
+
+	query, info := m.parseTimerQuery(params)
+	if info.Code != m.statusCodes.ok.Code {
+		logging.Errorf("%s Function: %s %s", logPrefix, appName, info.Info)
+		return
+	}
+
+	if info = m.checkTimersAccessible(appName); info.Code != m.statusCodes.ok.Code {
+		logging.Errorf("%s %s", logPrefix, info.Info)
+		return
+	}
+
+	records, err := m.superSup.ListTimers(appName, &query)
+	if err != nil {
+		info.Code = m.statusCodes.errTimerOp.Code
+		info.Info = fmt.Sprintf("Function: %s failed to list timers, err: %v", appName, err)
+		logging.Errorf("%s %s", logPrefix, info.Info)
+		return
+	}
+
+	info.Code = m.statusCodes.ok.Code
+	return
+}
+
+// cancelTimers cancels a timer of deployed function by its callback and reference, or
+// else all timers within the requested due time range
+func (m *ServiceMgr) cancelTimers(appName string, params url.Values) (cancelled int, info *runtimeInfo) {
+	logPrefix := "ServiceMgr::cancelTimers"
+
+	query, info := m.parseTimerQuery(params)
+	if info.Code != m.statusCodes.ok.Code {
+		logging.Errorf("%s Function: %s %s", logPrefix, appName, info.Info)
+		return
+	}
+
+	req := &common.TimerCancelRequest{
+		TimerQuery: query,
+		Callback:   params.Get("callback"),
+		Reference:  params.Get("reference"),
+	}
+
+	info.Code = m.statusCodes.errInvalidConfig.Code
+	if req.Reference != "" {
+		if req.Callback == "" || len(req.Vbs) != 1 {
+			info.Info = fmt.Sprintf("Function: %s callback and a single vb are needed to cancel timer by reference", appName)
+			logging.Errorf("%s %s", logPrefix, info.Info)
+			return
+		}
+	} else if req.From == 0 && req.To == 0 {
+		// Guards against cancelling every timer of the function by mistake
+		info.Info = fmt.Sprintf("Function: %s either reference or from, to range is needed to cancel timers", appName)
+		logging.Errorf("%s %s", logPrefix, info.Info)
+		return
+	}
+
+	if info = m.checkTimersAccessible(appName); info.Code != m.statusCodes.ok.Code {
+		logging.Errorf("%s %s", logPrefix, info.Info)
+		return
+	}
+
+	cancelled, err := m.superSup.CancelTimers(appName, req)
+	if err != nil {
+		info.Code = m.statusCodes.errTimerOp.Code
+		info.Info = fmt.Sprintf("Function: %s failed to cancel timers, cancelled: %d err: %v", appName, cancelled, err)
+		logging.Errorf("%s %s", logPrefix, info.Info)
+		return
+	}
+
+	logging.Infof("%s Function: %s cancelled %d timers", logPrefix, appName, cancelled)
+	info.Code = m.statusCodes.ok.Code
+	return
+}
+
+// summarizeTimerBacklog rolls per vbucket timer backlog gauges up to function level,
+// listing vbuckets flagged as lagging
+func summarizeTimerBacklog(debugStats map[int]map[string]interface{}) map[string]interface{} {
+	var pending, overdue, oldestDue, maxLag, maxFireLag int64
+	laggingVbs := make([]int, 0)
+
+	for vb, vbStats := range debugStats {
+		if count, ok := vbStats["timer_pending_alarms"].(int64); ok {
+			pending += count
+		}
+		if count, ok := vbStats["timer_overdue_alarms"].(int64); ok {
+			overdue += count
+		}
+		if due, ok := vbStats["timer_oldest_overdue"].(int64); ok && due > 0 && (oldestDue == 0 || due < oldestDue) {
+			oldestDue = due
+		}
+		if lag, ok := vbStats["timer_lag"].(int64); ok && lag > maxLag {
+			maxLag = lag
+		}
+		if lag, ok := vbStats["timer_fire_lag"].(int64); ok && lag > maxFireLag {
+			maxFireLag = lag
+		}
+		if lagging, ok := vbStats["timer_lagging"].(bool); ok && lagging {
+			laggingVbs = append(laggingVbs, vb)
+		}
+	}
+	sort.Ints(laggingVbs)
+
+	return map[string]interface{}{
+		"pending_alarms": pending,
+		"overdue_alarms": overdue,
+		"oldest_overdue": oldestDue,
+		"max_lag":        maxLag,
+		"max_fire_lag":   maxFireLag,
+		"lagging_vbs":    laggingVbs,
+	}
+}
@@ -34,11 +34,39 @@ func encodeRev(rev uint64) service.Revision {
 	return ext
 }
 
+// bindingAccess returns access mode of bucket binding. Bindings saved before access
+// was introduced are read-only on source bucket and read-write elsewhere.
+func bindingAccess(b *bucket, sourceBucket string) string {
+	if b.Access != "" {
+		return b.Access
+	}
+
+	if b.BucketName == sourceBucket {
+		return bucketAccessRead
+	}
+	return bucketAccessReadWrite
+}
+
+// constantsFromSettings decodes constant bindings supplied through function settings
+func constantsFromSettings(val interface{}) ([]constant, error) {
+	data, err := json.Marshal(val)
+	if err != nil {
+		return nil, err
+	}
+
+	var constants []constant
+	if err = json.Unmarshal(data, &constants); err != nil {
+		return nil, err
+	}
+	return constants, nil
+}
+
 func fillMissingWithDefaults(settings map[string]interface{}) {
 	// Handler related configurations
 	fillMissingDefault(settings, "checkpoint_interval", float64(60000))
 	fillMissingDefault(settings, "cleanup_timers", false)
 	fillMissingDefault(settings, "cpp_worker_thread_count", float64(2))
+	fillMissingDefault(settings, "cpu_shares", float64(0))
 	fillMissingDefault(settings, "curl_timeout", float64(10000))
 	fillMissingDefault(settings, "deadline_timeout", float64(62))
 	fillMissingDefault(settings, "execution_timeout", float64(60))
@@ -47,6 +75,7 @@ func fillMissingWithDefaults(settings map[string]interface{}) {
 	fillMissingDefault(settings, "idle_checkpoint_interval", float64(30000))
 	fillMissingDefault(settings, "lcb_inst_capacity", float64(5))
 	fillMissingDefault(settings, "log_level", "INFO")
+	fillMissingDefault(settings, "memory_limit", float64(0))
 	fillMissingDefault(settings, "poll_bucket_interval", float64(10))
 	fillMissingDefault(settings, "sock_batch_size", float64(100))
 	fillMissingDefault(settings, "tick_duration", float64(60000))
@@ -64,13 +93,17 @@ func fillMissingWithDefaults(settings map[string]interface{}) {
 	fillMissingDefault(settings, "timer_storage_chan_size", float64(10*1000))
 	fillMissingDefault(settings, "timer_queue_mem_cap", float64(50))
 	fillMissingDefault(settings, "timer_queue_size", float64(10000))
+	fillMissingDefault(settings, "timer_resolution", float64(7))
 
 	// Process related configuration
 	fillMissingDefault(settings, "breakpad_on", true)
 
 	// Rebalance related configurations
+	fillMissingDefault(settings, "planner_weighting", "none")
+	fillMissingDefault(settings, "server_group_aware_planner", false)
 	fillMissingDefault(settings, "vb_ownership_giveup_routine_count", float64(3))
 	fillMissingDefault(settings, "vb_ownership_takeover_routine_count", float64(3))
+	fillMissingDefault(settings, "worker_vb_rebalance_interval", float64(0))
 
 	// Application logging related configurations
 	fillMissingDefault(settings, "app_log_max_size", float64(1024*1024*40))
@@ -151,6 +184,11 @@ func (m *ServiceMgr) sendRuntimeInfoList(w http.ResponseWriter, runtimeInfoList
 		return
 	}
 
+	w.WriteHeader(m.getListDisposition(runtimeInfoList))
+	fmt.Fprintf(w, string(response))
+}
+
+func (m *ServiceMgr) getListDisposition(runtimeInfoList []*runtimeInfo) int {
 	allOK := true
 	allFail := true
 	for _, info := range runtimeInfoList {
@@ -159,14 +197,11 @@ func (m *ServiceMgr) sendRuntimeInfoList(w http.ResponseWriter, runtimeInfoList
 	}
 
 	if allOK {
-		w.WriteHeader(http.StatusOK)
+		return http.StatusOK
 	} else if allFail {
-		w.WriteHeader(http.StatusBadRequest)
-	} else {
-		w.WriteHeader(http.StatusMultiStatus)
+		return http.StatusBadRequest
 	}
-
-	fmt.Fprintf(w, string(response))
+	return http.StatusMultiStatus
 }
 
 func (m *ServiceMgr) unmarshalApp(r *http.Request) (app application, info *runtimeInfo) {
@@ -1,15 +1,18 @@
 package servicemanager
 
 import (
+	"encoding/json"
 	"fmt"
 	"math"
 	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"regexp"
 	"strings"
 
 	"github.com/couchbase/cbauth"
+	"github.com/couchbase/eventing/common"
 	"github.com/couchbase/eventing/logging"
 	"github.com/couchbase/eventing/util"
 )
@@ -22,7 +25,11 @@ func (m *ServiceMgr) validateApplication(app *application) (info *runtimeInfo) {
 		return
 	}
 
-	if info = m.validateDeploymentConfig(&app.DeploymentConfig); info.Code != m.statusCodes.ok.Code {
+	if info = m.validateGroupName(app.Group); info.Code != m.statusCodes.ok.Code {
+		return
+	}
+
+	if info = m.validateDeploymentConfig(app.Name, &app.DeploymentConfig, isDeployed(app)); info.Code != m.statusCodes.ok.Code {
 		return
 	}
 
@@ -188,6 +195,12 @@ func (m *ServiceMgr) validateApplicationName(applicationName string) (info *runt
 		return
 	}
 
+	if applicationName == bulkOpPathName {
+		info.Code = m.statusCodes.errInvalidConfig.Code
+		info.Info = fmt.Sprintf("Function name %s is reserved", bulkOpPathName)
+		return
+	}
+
 	info.Code = m.statusCodes.ok.Code
 	return
 }
@@ -236,6 +249,10 @@ func (m *ServiceMgr) validateConfig(c map[string]interface{}) (info *runtimeInfo
 		return
 	}
 
+	if info = m.validateBoolean("allow_interbucket_recursion", c); info.Code != m.statusCodes.ok.Code {
+		return
+	}
+
 	if info = m.validatePositiveInteger("ram_quota", c); info.Code != m.statusCodes.ok.Code {
 		return
 	}
@@ -244,6 +261,10 @@ func (m *ServiceMgr) validateConfig(c map[string]interface{}) (info *runtimeInfo
 		return
 	}
 
+	if info = m.validatePositiveInteger("function_version_history", c); info.Code != m.statusCodes.ok.Code {
+		return
+	}
+
 	info.Code = m.statusCodes.ok.Code
 	return
 }
@@ -276,7 +297,7 @@ func (m *ServiceMgr) validateNonMemcached(bucketName string) (info *runtimeInfo)
 	return
 }
 
-func (m *ServiceMgr) validateDeploymentConfig(deploymentConfig *depCfg) (info *runtimeInfo) {
+func (m *ServiceMgr) validateDeploymentConfig(appName string, deploymentConfig *depCfg, deploying bool) (info *runtimeInfo) {
 	info = &runtimeInfo{}
 	info.Code = m.statusCodes.errInvalidConfig.Code
 
@@ -300,7 +321,7 @@ func (m *ServiceMgr) validateDeploymentConfig(deploymentConfig *depCfg) (info *r
 		return
 	}
 
-	for _, bucket := range deploymentConfig.Buckets {
+	for i, bucket := range deploymentConfig.Buckets {
 		if info = m.validateNonEmpty(bucket.BucketName, "Alias bucket name"); info.Code != m.statusCodes.ok.Code {
 			return
 		}
@@ -308,6 +329,229 @@ func (m *ServiceMgr) validateDeploymentConfig(deploymentConfig *depCfg) (info *r
 		if info = m.validateAliasName(bucket.Alias); info.Code != m.statusCodes.ok.Code {
 			return
 		}
+
+		access := bindingAccess(&deploymentConfig.Buckets[i], deploymentConfig.SourceBucket)
+		if access != bucketAccessRead && access != bucketAccessReadWrite {
+			info.Code = m.statusCodes.errInvalidConfig.Code
+			info.Info = fmt.Sprintf("Invalid access: %s for alias: %s, possible values are %s, %s",
+				access, bucket.Alias, bucketAccessRead, bucketAccessReadWrite)
+			return
+		}
+
+		if access == bucketAccessReadWrite && bucket.BucketName == deploymentConfig.SourceBucket {
+			info.Code = m.statusCodes.errInvalidConfig.Code
+			info.Info = fmt.Sprintf("Source bucket can only be bound read-only, alias: %s", bucket.Alias)
+			return
+		}
+		deploymentConfig.Buckets[i].Access = access
+	}
+
+	if info = m.validateConstants(deploymentConfig.Constants, deploymentConfig); info.Code != m.statusCodes.ok.Code {
+		return
+	}
+
+	if info = m.validateCurlBindings(deploymentConfig); info.Code != m.statusCodes.ok.Code {
+		return
+	}
+
+	if dl := deploymentConfig.DeadLetter; dl != nil {
+		if info = m.validateNonEmpty(dl.BucketName, "Dead letter bucket name"); info.Code != m.statusCodes.ok.Code {
+			return
+		}
+
+		if info = m.validateBucketExists(dl.BucketName); info.Code != m.statusCodes.ok.Code {
+			return
+		}
+
+		if info = m.validateNonMemcached(dl.BucketName); info.Code != m.statusCodes.ok.Code {
+			return
+		}
+
+		if dl.BucketName == deploymentConfig.SourceBucket || dl.BucketName == deploymentConfig.MetadataBucket {
+			info.Code = m.statusCodes.errInvalidConfig.Code
+			info.Info = "Dead letter bucket must be different from source and metadata bucket"
+			return
+		}
+
+		if info = m.validateNonEmpty(dl.KeyPrefix, "Dead letter key prefix"); info.Code != m.statusCodes.ok.Code {
+			return
+		}
+	}
+
+	if deploymentConfig.Filter != nil {
+		if info = m.validateFilter(deploymentConfig.Filter); info.Code != m.statusCodes.ok.Code {
+			return
+		}
+	}
+
+	if deploying {
+		if info = m.validateInterBucketRecursion(appName, deploymentConfig); info.Code != m.statusCodes.ok.Code {
+			return
+		}
+	}
+
+	info.Code = m.statusCodes.ok.Code
+	return
+}
+
+// validateConstants checks constant aliases are usable as JavaScript globals and
+// don't shadow each other or other bindings of deployment config
+func (m *ServiceMgr) validateConstants(constants []constant, deploymentConfig *depCfg) (info *runtimeInfo) {
+	info = &runtimeInfo{}
+	info.Code = m.statusCodes.errInvalidConfig.Code
+
+	aliases := make(map[string]struct{})
+	for _, b := range deploymentConfig.Buckets {
+		aliases[b.Alias] = struct{}{}
+	}
+	for _, c := range deploymentConfig.Curl {
+		aliases[c.Alias] = struct{}{}
+	}
+
+	for _, c := range constants {
+		if info = m.validateAliasName(c.Alias); info.Code != m.statusCodes.ok.Code {
+			return
+		}
+
+		if _, ok := aliases[c.Alias]; ok {
+			info.Code = m.statusCodes.errInvalidConfig.Code
+			info.Info = fmt.Sprintf("Constant alias: %s is already in use by another binding", c.Alias)
+			return
+		}
+		aliases[c.Alias] = struct{}{}
+
+		var value interface{}
+		if len(c.Value) == 0 || json.Unmarshal(c.Value, &value) != nil {
+			info.Code = m.statusCodes.errInvalidConfig.Code
+			info.Info = fmt.Sprintf("Value of constant: %s must be valid JSON", c.Alias)
+			return
+		}
+	}
+
+	info.Code = m.statusCodes.ok.Code
+	return
+}
+
+func (m *ServiceMgr) validateCurlBindings(deploymentConfig *depCfg) (info *runtimeInfo) {
+	info = &runtimeInfo{}
+	info.Code = m.statusCodes.errInvalidConfig.Code
+
+	aliases := make(map[string]struct{})
+	for _, b := range deploymentConfig.Buckets {
+		aliases[b.Alias] = struct{}{}
+	}
+	for _, c := range deploymentConfig.Constants {
+		aliases[c.Alias] = struct{}{}
+	}
+
+	for i, binding := range deploymentConfig.Curl {
+		if info = m.validateAliasName(binding.Alias); info.Code != m.statusCodes.ok.Code {
+			return
+		}
+
+		if _, ok := aliases[binding.Alias]; ok {
+			info.Code = m.statusCodes.errInvalidConfig.Code
+			info.Info = fmt.Sprintf("Curl alias: %s is already in use by another binding", binding.Alias)
+			return
+		}
+		aliases[binding.Alias] = struct{}{}
+
+		baseURL, err := url.Parse(binding.BaseURL)
+		if err != nil || (baseURL.Scheme != "http" && baseURL.Scheme != "https") || baseURL.Host == "" ||
+			baseURL.User != nil || baseURL.RawQuery != "" || baseURL.Fragment != "" {
+			info.Code = m.statusCodes.errInvalidConfig.Code
+			info.Info = fmt.Sprintf("Curl alias: %s base URL must be an http or https URL without credentials, query or fragment", binding.Alias)
+			return
+		}
+
+		switch binding.AuthType {
+		case curlAuthNone:
+		case curlAuthBasic, curlAuthDigest:
+			if binding.Username == "" {
+				info.Code = m.statusCodes.errInvalidConfig.Code
+				info.Info = fmt.Sprintf("Curl alias: %s %s auth requires username", binding.Alias, binding.AuthType)
+				return
+			}
+		case curlAuthBearer:
+			if binding.BearerKey == "" {
+				info.Code = m.statusCodes.errInvalidConfig.Code
+				info.Info = fmt.Sprintf("Curl alias: %s bearer auth requires bearer_key", binding.Alias)
+				return
+			}
+		default:
+			info.Code = m.statusCodes.errInvalidConfig.Code
+			info.Info = fmt.Sprintf("Invalid auth_type: %s for curl alias: %s, possible values are %s, %s, %s, %s",
+				binding.AuthType, binding.Alias, curlAuthNone, curlAuthBasic, curlAuthDigest, curlAuthBearer)
+			return
+		}
+
+		if len(binding.AllowedMethods) == 0 {
+			deploymentConfig.Curl[i].AllowedMethods = []string{"GET", "POST"}
+		}
+
+		for j, method := range deploymentConfig.Curl[i].AllowedMethods {
+			method = strings.ToUpper(method)
+			if !util.Contains(method, curlMethods) {
+				info.Code = m.statusCodes.errInvalidConfig.Code
+				info.Info = fmt.Sprintf("Invalid method: %s for curl alias: %s, possible values are %s",
+					method, binding.Alias, strings.Join(curlMethods, ", "))
+				return
+			}
+			deploymentConfig.Curl[i].AllowedMethods[j] = method
+		}
+	}
+
+	info.Code = m.statusCodes.ok.Code
+	return
+}
+
+func (m *ServiceMgr) validateFilter(f *filter) (info *runtimeInfo) {
+	info = &runtimeInfo{}
+	info.Code = m.statusCodes.errInvalidConfig.Code
+
+	if f.KeyPrefix == "" && f.KeyRegex == "" && len(f.Predicates) == 0 {
+		info.Info = "Filter must have at least one of key_prefix, key_regex or predicates"
+		return
+	}
+
+	if f.KeyRegex != "" {
+		if _, err := regexp.Compile(f.KeyRegex); err != nil {
+			info.Info = fmt.Sprintf("Filter key_regex is invalid, err: %v", err)
+			return
+		}
+	}
+
+	for _, predicate := range f.Predicates {
+		if info = m.validateNonEmpty(predicate.Field, "Filter predicate field"); info.Code != m.statusCodes.ok.Code {
+			return
+		}
+
+		info.Code = m.statusCodes.errInvalidConfig.Code
+
+		switch predicate.Op {
+		case common.FilterOpEquals:
+			if len(predicate.Value) == 0 || len(predicate.Values) != 0 {
+				info.Info = fmt.Sprintf("Filter predicate on field: %s with op: %s expects value", predicate.Field, predicate.Op)
+				return
+			}
+
+		case common.FilterOpExists:
+			if len(predicate.Value) != 0 || len(predicate.Values) != 0 {
+				info.Info = fmt.Sprintf("Filter predicate on field: %s with op: %s doesn't take a value", predicate.Field, predicate.Op)
+				return
+			}
+
+		case common.FilterOpIn:
+			if len(predicate.Value) != 0 || len(predicate.Values) == 0 {
+				info.Info = fmt.Sprintf("Filter predicate on field: %s with op: %s expects non-empty values", predicate.Field, predicate.Op)
+				return
+			}
+
+		default:
+			info.Info = fmt.Sprintf("Filter predicate on field: %s has invalid op: %s, possible values: %s, %s, %s",
+				predicate.Field, predicate.Op, common.FilterOpEquals, common.FilterOpExists, common.FilterOpIn)
+			return
+		}
 	}
 
 	info.Code = m.statusCodes.ok.Code
@@ -514,6 +758,21 @@ func (m *ServiceMgr) validateTimerContextSize(field string, settings map[string]
 	return
 }
 
+func (m *ServiceMgr) validateTimerResolution(field string, settings map[string]interface{}) (info *runtimeInfo) {
+	info = &runtimeInfo{}
+	info.Code = m.statusCodes.errInvalidConfig.Code
+
+	if val, ok := settings[field]; ok {
+		if val.(float64) > 60 {
+			info.Info = fmt.Sprintf("%s value can not be more than 60 seconds", field)
+			return
+		}
+	}
+
+	info.Code = m.statusCodes.ok.Code
+	return
+}
+
 func (m *ServiceMgr) validatePossibleValues(field string, settings map[string]interface{}, possibleValues []string) (info *runtimeInfo) {
 	info = &runtimeInfo{}
 	info.Code = m.statusCodes.errInvalidConfig.Code
@@ -558,6 +817,10 @@ func (m *ServiceMgr) validateSettings(settings map[string]interface{}) (info *ru
 		return
 	}
 
+	if info = m.validateZeroOrPositiveInteger("cpu_shares", settings); info.Code != m.statusCodes.ok.Code {
+		return
+	}
+
 	if info = m.validatePositiveInteger("curl_timeout", settings); info.Code != m.statusCodes.ok.Code {
 		return
 	}
@@ -616,6 +879,10 @@ func (m *ServiceMgr) validateSettings(settings map[string]interface{}) (info *ru
 		return
 	}
 
+	if info = m.validateZeroOrPositiveInteger("memory_limit", settings); info.Code != m.statusCodes.ok.Code {
+		return
+	}
+
 	if info = m.validatePositiveInteger("poll_bucket_interval", settings); info.Code != m.statusCodes.ok.Code {
 		return
 	}
@@ -677,6 +944,14 @@ func (m *ServiceMgr) validateSettings(settings map[string]interface{}) (info *ru
 		return
 	}
 
+	if info = m.validatePositiveInteger("timer_resolution", settings); info.Code != m.statusCodes.ok.Code {
+		return
+	}
+
+	if info = m.validateTimerResolution("timer_resolution", settings); info.Code != m.statusCodes.ok.Code {
+		return
+	}
+
 	if info = m.validatePositiveInteger("undeploy_routine_count", settings); info.Code != m.statusCodes.ok.Code {
 		return
 	}
@@ -687,6 +962,15 @@ func (m *ServiceMgr) validateSettings(settings map[string]interface{}) (info *ru
 	}
 
 	// Rebalance related configurations
+	plannerWeightingValues := []string{common.PlannerWeightingNone, common.PlannerWeightingCPUCount, common.PlannerWeightingThroughput}
+	if info = m.validatePossibleValues("planner_weighting", settings, plannerWeightingValues); info.Code != m.statusCodes.ok.Code {
+		return
+	}
+
+	if info = m.validateBoolean("server_group_aware_planner", settings); info.Code != m.statusCodes.ok.Code {
+		return
+	}
+
 	if info = m.validatePositiveInteger("vb_ownership_giveup_routine_count", settings); info.Code != m.statusCodes.ok.Code {
 		return
 	}
@@ -695,6 +979,10 @@ func (m *ServiceMgr) validateSettings(settings map[string]interface{}) (info *ru
 		return
 	}
 
+	if info = m.validateZeroOrPositiveInteger("worker_vb_rebalance_interval", settings); info.Code != m.statusCodes.ok.Code {
+		return
+	}
+
 	// Application logging related configurations
 	if info = m.validateDirPath("app_log_dir", settings); info.Code != m.statusCodes.ok.Code {
 		return
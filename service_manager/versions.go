@@ -0,0 +1,266 @@
+package servicemanager
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/couchbase/eventing/common"
+	"github.com/couchbase/eventing/logging"
+	"github.com/couchbase/eventing/util"
+)
+
+func appVersionsPath(appName string) string {
+	return metakvAppVersionsPath + appName + "/"
+}
+
+func appVersionChecksumPath(appName string) string {
+	return metakvVersionChecksumPath + appName + "/"
+}
+
+// getAppVersionHistory returns number of revisions to retain per function, as per global config
+func (m *ServiceMgr) getAppVersionHistory() int {
+	logPrefix := "ServiceMgr::getAppVersionHistory"
+
+	config, info := m.getConfig()
+	if info.Code != m.statusCodes.ok.Code {
+		return defaultAppVersionHistory
+	}
+
+	val, exists := config["function_version_history"]
+	if !exists {
+		return defaultAppVersionHistory
+	}
+
+	history, ok := val.(float64)
+	if !ok || history < 1 {
+		logging.Warnf("%s Supplied function_version_history value unexpected. Defaulting to %d",
+			logPrefix, defaultAppVersionHistory)
+		return defaultAppVersionHistory
+	}
+
+	return int(history)
+}
+
+// listAppRevisions returns saved revisions of a function in ascending order
+func listAppRevisions(appName string) []int {
+	revisions := make([]int, 0)
+	for _, child := range util.ListChildren(appVersionsPath(appName)) {
+		if rev, err := strconv.Atoi(child); err == nil {
+			revisions = append(revisions, rev)
+		}
+	}
+	sort.Ints(revisions)
+	return revisions
+}
+
+// saveAppVersion records the function definition as a new revision and prunes
+// revisions beyond the configured history length
+func (m *ServiceMgr) saveAppVersion(app *application) error {
+	logPrefix := "ServiceMgr::saveAppVersion"
+
+	revisions := listAppRevisions(app.Name)
+
+	version := appVersion{
+		AppHandlers:      app.AppHandlers,
+		DeploymentConfig: app.DeploymentConfig,
+		HandlerUUID:      app.HandlerUUID,
+		Revision:         1,
+		Settings:         app.Settings,
+		Timestamp:        time.Now().UTC().Format(time.RFC3339),
+	}
+
+	if len(revisions) > 0 {
+		version.Revision = revisions[len(revisions)-1] + 1
+	}
+
+	data, err := json.Marshal(&version)
+	if err != nil {
+		return err
+	}
+
+	err = util.WriteAppContent(appVersionsPath(app.Name), appVersionChecksumPath(app.Name),
+		strconv.Itoa(version.Revision), data)
+	if err != nil {
+		return err
+	}
+
+	logging.Infof("%s Function: %s saved revision: %d", logPrefix, app.Name, version.Revision)
+
+	revisions = append(revisions, version.Revision)
+	for len(revisions) > m.getAppVersionHistory() {
+		err = util.DeleteAppContent(appVersionsPath(app.Name), appVersionChecksumPath(app.Name),
+			strconv.Itoa(revisions[0]))
+		if err != nil {
+			logging.Errorf("%s Function: %s failed to prune revision: %d, err: %v",
+				logPrefix, app.Name, revisions[0], err)
+			break
+		}
+
+		logging.Infof("%s Function: %s pruned revision: %d", logPrefix, app.Name, revisions[0])
+		revisions = revisions[1:]
+	}
+
+	return nil
+}
+
+func (m *ServiceMgr) getAppVersion(appName string, rev int) (version appVersion, info *runtimeInfo) {
+	logPrefix := "ServiceMgr::getAppVersion"
+
+	info = &runtimeInfo{}
+
+	data, err := util.ReadAppContent(appVersionsPath(appName), appVersionChecksumPath(appName), strconv.Itoa(rev))
+	if err != nil || data == nil {
+		info.Code = m.statusCodes.errAppVersionNotFound.Code
+		info.Info = fmt.Sprintf("Function: %s revision: %d not found", appName, rev)
+		logging.Errorf("%s %s", logPrefix, info.Info)
+		return
+	}
+
+	err = json.Unmarshal(data, &version)
+	if err != nil {
+		info.Code = m.statusCodes.errUnmarshalPld.Code
+		info.Info = fmt.Sprintf("Function: %s failed to unmarshal revision: %d, err: %v", appName, rev, err)
+		logging.Errorf("%s %s", logPrefix, info.Info)
+		return
+	}
+
+	info.Code = m.statusCodes.ok.Code
+	return
+}
+
+// getAppVersions returns saved revisions of a function, latest first
+func (m *ServiceMgr) getAppVersions(appName string) (versions []appVersion, info *runtimeInfo) {
+	revisions := listAppRevisions(appName)
+
+	versions = make([]appVersion, 0, len(revisions))
+	for i := len(revisions) - 1; i >= 0; i-- {
+		version, vInfo := m.getAppVersion(appName, revisions[i])
+		if vInfo.Code != m.statusCodes.ok.Code {
+			info = vInfo
+			return
+		}
+		versions = append(versions, version)
+	}
+
+	info = &runtimeInfo{Code: m.statusCodes.ok.Code}
+	return
+}
+
+// deleteAppVersions removes revision history of a function being deleted
+func deleteAppVersions(appName string) error {
+	if err := util.MetakvRecursiveDelete(appVersionChecksumPath(appName)); err != nil {
+		return err
+	}
+	return util.MetakvRecursiveDelete(appVersionsPath(appName))
+}
+
+// waitForPause blocks until workers of a paused function have checkpointed and
+// stopped on this node
+func (m *ServiceMgr) waitForPause(appName string) (info *runtimeInfo) {
+	logPrefix := "ServiceMgr::waitForPause"
+
+	info = &runtimeInfo{}
+	for start := time.Now(); time.Since(start) < restorePauseTimeout; time.Sleep(time.Second) {
+		if m.superSup.GetAppState(appName) == common.AppStateDisabled && len(m.superSup.GetEventingConsumerPids(appName)) == 0 {
+			info.Code = m.statusCodes.ok.Code
+			return
+		}
+	}
+
+	info.Code = m.statusCodes.errAppDeployed.Code
+	info.Info = fmt.Sprintf("Function: %s pause didn't finish within %v", appName, restorePauseTimeout)
+	logging.Errorf("%s %s", logPrefix, info.Info)
+	return
+}
+
+// restoreAppVersion replaces definition of function with a saved revision, leaving it
+// deployed, paused or undeployed as it is. A deployed function is paused meanwhile and
+// keeps its handler uuid, so that it resumes from its checkpoints with timers intact.
+func (m *ServiceMgr) restoreAppVersion(r *http.Request, appName string, rev int) (info *runtimeInfo) {
+	logPrefix := "ServiceMgr::restoreAppVersion"
+
+	version, info := m.getAppVersion(appName, rev)
+	if info.Code != m.statusCodes.ok.Code {
+		return
+	}
+
+	app := application{
+		AppHandlers:      version.AppHandlers,
+		DeploymentConfig: version.DeploymentConfig,
+		Name:             appName,
+		Settings:         make(map[string]interface{}),
+	}
+	for k, v := range version.Settings {
+		app.Settings[k] = v
+	}
+
+	current, tInfo := m.getTempStore(appName)
+	if tInfo.Code == m.statusCodes.ok.Code {
+		app.Group = current.Group
+	}
+
+	deployed, _ := current.Settings["deployment_status"].(bool)
+	processing, _ := current.Settings["processing_status"].(bool)
+	if tInfo.Code != m.statusCodes.ok.Code || !deployed {
+		// Request is authorized on group of the function already
+		infoList := m.createApplications(r, &[]application{app}, true, nil)
+		if len(infoList) == 0 {
+			info.Code = m.statusCodes.errSaveAppPs.Code
+			info.Info = fmt.Sprintf("Function: %s failed to restore revision: %d", appName, rev)
+			logging.Errorf("%s %s", logPrefix, info.Info)
+			return
+		}
+
+		info = infoList[0]
+		if info.Code == m.statusCodes.ok.Code {
+			logging.Infof("%s Function: %s restored revision: %d undeployed", logPrefix, appName, rev)
+		}
+		return
+	}
+
+	if processing {
+		logging.Infof("%s Function: %s pausing before restoring revision: %d", logPrefix, appName, rev)
+
+		if info = m.pauseApp(appName); info.Code != m.statusCodes.ok.Code {
+			return
+		}
+
+		if info = m.waitForPause(appName); info.Code != m.statusCodes.ok.Code {
+			return
+		}
+	}
+
+	app.Settings["deployment_status"] = true
+	app.Settings["processing_status"] = false
+	app.HandlerUUID = current.HandlerUUID
+	app.EventingVersion = util.EventingVer()
+
+	m.reconcileSecrets(r, &app)
+	if info = m.validateApplication(&app); info.Code != m.statusCodes.ok.Code {
+		logging.Errorf("%s Function: %s revision: %d failed validation: %v", logPrefix, appName, rev, info.Info)
+		return
+	}
+
+	if info = m.savePrimaryStoreImpl(app, true); info.Code != m.statusCodes.ok.Code {
+		return
+	}
+
+	if tInfo = m.saveTempStore(app); tInfo.Code != m.statusCodes.ok.Code {
+		info = tInfo
+		return
+	}
+
+	if processing {
+		if rInfo := m.resumeApp(appName); rInfo.Code != m.statusCodes.ok.Code {
+			info = rInfo
+			return
+		}
+	}
+
+	logging.Infof("%s Function: %s restored revision: %d", logPrefix, appName, rev)
+	return
+}
@@ -2,6 +2,7 @@ package supervisor
 
 import (
 	"sync"
+	"time"
 
 	"github.com/couchbase/eventing/common"
 	"github.com/couchbase/eventing/suptree"
@@ -39,6 +40,12 @@ const (
 	numVbuckets = 1024
 )
 
+const (
+	// Node throughput used by weighted planner is averaged over the last 5 minutes
+	throughputSampleInterval = 10 * time.Second
+	throughputSampleCount    = 30
+)
+
 const (
 	supCmdType int8 = iota
 	cmdAppDelete
@@ -102,6 +109,8 @@ type SuperSupervisor struct {
 	tokenMapRWMutex            *sync.RWMutex
 	runningProducers           map[string]common.EventingProducer // Access controlled by runningProducersRWMutex
 	runningProducersRWMutex    *sync.RWMutex
+	throughputSamples          []float64 // Access controlled by throughputRWMutex
+	throughputRWMutex          *sync.RWMutex
 	vbucketsToOwn              []uint16
 
 	serviceMgr common.EventingServiceMgr
@@ -35,6 +35,48 @@ func (s *SuperSupervisor) GetEventProcessingStats(appName string) map[string]uin
 	return nil
 }
 
+// GetDcpEventsProcessedPSecPerVb returns dcp events processed per second per owned vbucket,
+// averaged over running functions and over samples of the last few minutes
+func (s *SuperSupervisor) GetDcpEventsProcessedPSecPerVb() float64 {
+	s.throughputRWMutex.RLock()
+	defer s.throughputRWMutex.RUnlock()
+
+	if len(s.throughputSamples) == 0 {
+		return 0
+	}
+
+	var total float64
+	for _, sample := range s.throughputSamples {
+		total += sample
+	}
+	return total / float64(len(s.throughputSamples))
+}
+
+// sampleThroughput records dcp events processed per second per owned vbucket, averaged over
+// functions that are processing events. Idle nodes leave no sample, as they say nothing of capacity.
+func (s *SuperSupervisor) sampleThroughput() {
+	var total float64
+	var active int
+	for _, p := range s.runningFns() {
+		if rate := p.GetDcpEventsProcessedPSecPerVb(); rate > 0 {
+			total += rate
+			active++
+		}
+	}
+
+	if active == 0 {
+		return
+	}
+
+	s.throughputRWMutex.Lock()
+	defer s.throughputRWMutex.Unlock()
+
+	s.throughputSamples = append(s.throughputSamples, total/float64(active))
+	if len(s.throughputSamples) > throughputSampleCount {
+		s.throughputSamples = s.throughputSamples[len(s.throughputSamples)-throughputSampleCount:]
+	}
+}
+
 // GetAppCode returns handler code for requested appname
 func (s *SuperSupervisor) GetAppCode(appName string) string {
 	logPrefix := "SuperSupervisor::GetAppCode"
@@ -90,6 +132,14 @@ func (s *SuperSupervisor) GetLatencyStats(appName string) map[string]uint64 {
 	return nil
 }
 
+// GetTimerLatenessStats returns histogram of how late timers of the app fired
+func (s *SuperSupervisor) GetTimerLatenessStats(appName string) map[string]uint64 {
+	if p, ok := s.runningFns()[appName]; ok {
+		return p.GetTimerLatenessStats()
+	}
+	return nil
+}
+
 // GetLocallyDeployedApps returns list of deployed apps and their last deployment time
 func (s *SuperSupervisor) GetLocallyDeployedApps() map[string]string {
 	s.appListRWMutex.RLock()
@@ -147,6 +197,14 @@ func (s *SuperSupervisor) GetSourceMap(appName string) string {
 	return ""
 }
 
+// GetWorkerStats returns per worker stat counters for the requested appname
+func (s *SuperSupervisor) GetWorkerStats(appName string) map[string]*common.WorkerStats {
+	if p, ok := s.runningFns()[appName]; ok {
+		return p.GetWorkerStats()
+	}
+	return nil
+}
+
 // RestPort returns ns_server port(typically 8091/9000)
 func (s *SuperSupervisor) RestPort() string {
 	return s.restPort
@@ -276,6 +334,36 @@ func (s *SuperSupervisor) RebalanceTaskProgress(appName string) (*common.Rebalan
 	return nil, fmt.Errorf("Eventing.Producer isn't alive")
 }
 
+// ReplayDeadLetters re-triggers handler execution for events captured in dead letter bucket
+func (s *SuperSupervisor) ReplayDeadLetters(appName string) (*common.DeadLetterReplayStats, error) {
+	p, ok := s.runningFns()[appName]
+	if ok {
+		return p.ReplayDeadLetters()
+	}
+
+	return nil, fmt.Errorf("Eventing.Producer isn't alive")
+}
+
+// ListTimers returns pending timers of the app selected by query
+func (s *SuperSupervisor) ListTimers(appName string, query *common.TimerQuery) ([]*common.TimerRecord, error) {
+	p, ok := s.runningFns()[appName]
+	if ok {
+		return p.ListTimers(query)
+	}
+
+	return nil, fmt.Errorf("Eventing.Producer isn't alive")
+}
+
+// CancelTimers cancels pending timers of the app, returning number of timers cancelled
+func (s *SuperSupervisor) CancelTimers(appName string, req *common.TimerCancelRequest) (int, error) {
+	p, ok := s.runningFns()[appName]
+	if ok {
+		return p.CancelTimers(req)
+	}
+
+	return 0, fmt.Errorf("Eventing.Producer isn't alive")
+}
+
 // TimerDebugStats captures timer related stats to assist in debugging mismtaches during rebalance
 func (s *SuperSupervisor) TimerDebugStats(appName string) (map[int]map[string]interface{}, error) {
 	p, ok := s.runningFns()[appName]
@@ -43,6 +43,7 @@ func NewSuperSupervisor(adminPort AdminPortConfig, eventingDir, kvPort, restPort
 		runningProducersRWMutex:    &sync.RWMutex{},
 		supCmdCh:                   make(chan supCmdMsg, 10),
 		superSup:                   suptree.NewSimple("super_supervisor"),
+		throughputRWMutex:          &sync.RWMutex{},
 		tokenMapRWMutex:            &sync.RWMutex{},
 		uuid:                       uuid,
 	}
@@ -73,10 +74,15 @@ func NewSuperSupervisor(adminPort AdminPortConfig, eventingDir, kvPort, restPort
 		tick := time.NewTicker(time.Minute)
 		defer tick.Stop()
 
+		throughputTick := time.NewTicker(throughputSampleInterval)
+		defer throughputTick.Stop()
+
 		for {
 			select {
 			case <-tick.C:
 				printMemoryStats()
+			case <-throughputTick.C:
+				s.sampleThroughput()
 			}
 		}
 	}()
@@ -305,7 +311,7 @@ func (s *SuperSupervisor) SettingsChangeCallback(path string, value []byte, rev
 		/*
 			State 1(Deployment status = False, Processing status = False)
 			State 2 (Deployment status = True, Processing status = True)
-			State 3 (Deployment status = True,  Processing status = False)
+			State 3 (Deployment status = True,  Processing status = False) - paused, checkpoints retained
 
 			Possible state transitions:
 
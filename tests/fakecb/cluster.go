@@ -0,0 +1,372 @@
+// Package fakecb is an in-memory, single node stand-in for Couchbase Server.
+// It serves enough of the memcached binary protocol for dcp/transport/client.DcpFeed
+// and enough of the REST API for dcp.GetBucket and util.ClusterInfoCache, so that
+// DCP driven behaviour like rollback, vbucket takeover and checkpointing can be
+// exercised without a cluster. SDK bucket operations aren't served.
+package fakecb
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"math/rand"
+	"net"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+)
+
+const (
+	// ClusterCompatibility reported for the node, 5.5
+	clusterCompatibility = 5*65536 + 5
+
+	datatypeRaw  = uint8(0)
+	datatypeJSON = uint8(1)
+)
+
+type itemOp uint8
+
+const (
+	itemMutation itemOp = iota
+	itemDeletion
+	itemExpiration
+)
+
+// item is one entry of a vbucket's sequence log
+type item struct {
+	op       itemOp
+	key      []byte
+	value    []byte
+	datatype uint8
+	seqno    uint64
+	revSeqno uint64
+	cas      uint64
+	flags    uint32
+	expiry   uint32
+}
+
+type vbucket struct {
+	id          uint16
+	highSeqno   uint64
+	failoverLog [][2]uint64 // [vbuuid, seqno], most recent entry first
+	items       []*item
+	revSeqnos   map[string]uint64
+
+	// Closed whenever new items are appended, so that active streams wake up
+	notify chan struct{}
+}
+
+// Bucket holds per vbucket sequence logs of a fake bucket
+type Bucket struct {
+	sync.RWMutex
+	name string
+	uuid string
+	vbs  []*vbucket
+}
+
+// Cluster is a fake single node cluster, hosting KV and REST endpoints on loopback
+type Cluster struct {
+	sync.RWMutex
+	numVbuckets  int
+	buckets      map[string]*Bucket
+	servicePorts map[string]int
+	services     []string
+
+	kvListener net.Listener
+	rest       *httptest.Server
+	conns      map[*kvConn]struct{}
+	stopCh     chan struct{}
+	wg         sync.WaitGroup
+}
+
+// NewCluster starts KV and REST listeners of a fake cluster with the given vbucket count
+func NewCluster(numVbuckets int) (*Cluster, error) {
+	if numVbuckets <= 0 || numVbuckets&(numVbuckets-1) != 0 {
+		return nil, fmt.Errorf("vbucket count should be a power of 2, received: %d", numVbuckets)
+	}
+
+	kvListener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Cluster{
+		numVbuckets:  numVbuckets,
+		buckets:      make(map[string]*Bucket),
+		servicePorts: make(map[string]int),
+		services:     []string{"kv"},
+		kvListener:   kvListener,
+		conns:        make(map[*kvConn]struct{}),
+		stopCh:       make(chan struct{}),
+	}
+
+	c.rest = httptest.NewServer(c.restHandler())
+
+	c.wg.Add(1)
+	go c.acceptKVConns()
+
+	return c, nil
+}
+
+// Close stops listeners and drops open KV connections
+func (c *Cluster) Close() {
+	close(c.stopCh)
+	c.kvListener.Close()
+	c.rest.CloseClientConnections()
+	c.rest.Close()
+
+	c.Lock()
+	for conn := range c.conns {
+		conn.conn.Close()
+	}
+	c.Unlock()
+
+	c.wg.Wait()
+}
+
+// URL is the REST endpoint of the cluster, usable as cluster url by dcp.GetBucket
+func (c *Cluster) URL() string {
+	return c.rest.URL
+}
+
+// RESTAddr is host:port of the REST endpoint
+func (c *Cluster) RESTAddr() string {
+	return c.rest.Listener.Addr().String()
+}
+
+// KVAddr is host:port of the memcached endpoint
+func (c *Cluster) KVAddr() string {
+	return c.kvListener.Addr().String()
+}
+
+// NumVbuckets is the vbucket count of every bucket in the cluster
+func (c *Cluster) NumVbuckets() int {
+	return c.numVbuckets
+}
+
+// SetService advertises a service on the node, along with its port in nodeServices
+func (c *Cluster) SetService(service, portName string, port int) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.services = append(c.services, service)
+	c.servicePorts[portName] = port
+}
+
+// CreateBucket adds an empty bucket, or returns the existing one of the same name
+func (c *Cluster) CreateBucket(name string) *Bucket {
+	c.Lock()
+	defer c.Unlock()
+
+	if b, ok := c.buckets[name]; ok {
+		return b
+	}
+
+	b := &Bucket{
+		name: name,
+		uuid: fmt.Sprintf("%016x", rand.Int63()),
+		vbs:  make([]*vbucket, c.numVbuckets),
+	}
+
+	for i := range b.vbs {
+		b.vbs[i] = &vbucket{
+			id:          uint16(i),
+			failoverLog: [][2]uint64{{newVbuuid(), 0}},
+			revSeqnos:   make(map[string]uint64),
+			notify:      make(chan struct{}),
+		}
+	}
+
+	c.buckets[name] = b
+	return b
+}
+
+// GetBucket returns bucket of given name, nil if it doesn't exist
+func (c *Cluster) GetBucket(name string) *Bucket {
+	c.RLock()
+	defer c.RUnlock()
+	return c.buckets[name]
+}
+
+func (c *Cluster) bucketNames() []string {
+	c.RLock()
+	defer c.RUnlock()
+
+	names := make([]string, 0, len(c.buckets))
+	for name := range c.buckets {
+		names = append(names, name)
+	}
+	return names
+}
+
+// Name of the bucket
+func (b *Bucket) Name() string {
+	return b.name
+}
+
+// VbucketByKey returns vbucket a key maps to, using the same hash as KV
+func (b *Bucket) VbucketByKey(key string) uint16 {
+	return uint16((crc32.ChecksumIEEE([]byte(key)) >> 16) % uint32(len(b.vbs)))
+}
+
+// Set stores a document and returns the vbucket and seqno of the mutation.
+// Value is tagged as JSON on the wire if it parses as JSON.
+func (b *Bucket) Set(key string, value []byte) (uint16, uint64) {
+	vb := b.VbucketByKey(key)
+	return vb, b.SetVb(vb, key, value, 0)
+}
+
+// SetVb stores a document in given vbucket, with given expiry
+func (b *Bucket) SetVb(vb uint16, key string, value []byte, expiry uint32) uint64 {
+	datatype := datatypeRaw
+	var doc interface{}
+	if json.Unmarshal(value, &doc) == nil {
+		datatype = datatypeJSON
+	}
+
+	return b.appendItem(vb, &item{
+		op:       itemMutation,
+		key:      []byte(key),
+		value:    value,
+		datatype: datatype,
+		expiry:   expiry,
+	})
+}
+
+// Delete records deletion of a document and returns the vbucket and seqno of it
+func (b *Bucket) Delete(key string) (uint16, uint64) {
+	vb := b.VbucketByKey(key)
+	return vb, b.appendItem(vb, &item{op: itemDeletion, key: []byte(key)})
+}
+
+// Expire records expiration of a document and returns the vbucket and seqno of it.
+// It's streamed as deletion to connections that haven't enabled expiry opcode.
+func (b *Bucket) Expire(key string) (uint16, uint64) {
+	vb := b.VbucketByKey(key)
+	return vb, b.appendItem(vb, &item{op: itemExpiration, key: []byte(key)})
+}
+
+func (b *Bucket) appendItem(vb uint16, it *item) uint64 {
+	b.Lock()
+	defer b.Unlock()
+
+	v := b.vbs[vb]
+	v.highSeqno++
+	v.revSeqnos[string(it.key)]++
+
+	it.seqno = v.highSeqno
+	it.revSeqno = v.revSeqnos[string(it.key)]
+	it.cas = uint64(v.highSeqno)<<16 | uint64(vb)
+	v.items = append(v.items, it)
+
+	close(v.notify)
+	v.notify = make(chan struct{})
+
+	return it.seqno
+}
+
+// HighSeqno of the vbucket
+func (b *Bucket) HighSeqno(vb uint16) uint64 {
+	b.RLock()
+	defer b.RUnlock()
+	return b.vbs[vb].highSeqno
+}
+
+// Vbuuid of the vbucket's current failover log entry
+func (b *Bucket) Vbuuid(vb uint16) uint64 {
+	b.RLock()
+	defer b.RUnlock()
+	return b.vbs[vb].failoverLog[0][0]
+}
+
+// FailoverLog of the vbucket, most recent entry first
+func (b *Bucket) FailoverLog(vb uint16) [][2]uint64 {
+	b.RLock()
+	defer b.RUnlock()
+	return append([][2]uint64(nil), b.vbs[vb].failoverLog...)
+}
+
+// Failover emulates promotion of a replica that had only seen items up to seqno.
+// Items past seqno are lost and a new failover log entry is added, so that
+// clients resuming past seqno are asked to roll back.
+func (b *Bucket) Failover(vb uint16, seqno uint64) {
+	b.Lock()
+	defer b.Unlock()
+
+	v := b.vbs[vb]
+	if seqno > v.highSeqno {
+		seqno = v.highSeqno
+	}
+
+	retained := v.items[:0]
+	for _, it := range v.items {
+		if it.seqno <= seqno {
+			retained = append(retained, it)
+		}
+	}
+	v.items = retained
+	v.highSeqno = seqno
+
+	for i := range v.failoverLog {
+		if v.failoverLog[i][1] > seqno {
+			v.failoverLog[i][1] = seqno
+		}
+	}
+	v.failoverLog = append([][2]uint64{{newVbuuid(), seqno}}, v.failoverLog...)
+
+	close(v.notify)
+	v.notify = make(chan struct{})
+}
+
+// rollbackSeqno mirrors KV's decision on a stream request. It reports if the
+// client has to roll back, and to which seqno.
+func (v *vbucket) rollbackSeqno(vbuuid, startSeqno, snapStart, snapEnd uint64) (uint64, bool) {
+	if startSeqno == 0 {
+		return 0, false
+	}
+
+	for i, entry := range v.failoverLog {
+		if entry[0] != vbuuid {
+			continue
+		}
+
+		upper := v.highSeqno
+		if i > 0 {
+			upper = v.failoverLog[i-1][1]
+		}
+
+		if startSeqno > upper {
+			if snapStart <= upper {
+				return snapStart, true
+			}
+			return upper, true
+		}
+		return 0, false
+	}
+
+	return 0, true
+}
+
+// itemsSince returns items of vbucket past startSeqno, up to endSeqno
+func (v *vbucket) itemsSince(startSeqno, endSeqno uint64) []*item {
+	items := make([]*item, 0)
+	for _, it := range v.items {
+		if it.seqno > startSeqno && it.seqno <= endSeqno {
+			items = append(items, it)
+		}
+	}
+	return items
+}
+
+func newVbuuid() uint64 {
+	return uint64(rand.Int63())
+}
+
+func portOf(addr string) int {
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return 0
+	}
+	p, _ := strconv.Atoi(port)
+	return p
+}
@@ -0,0 +1,181 @@
+package fakecb
+
+import (
+	"testing"
+	"time"
+
+	couchbase "github.com/couchbase/eventing/dcp"
+	mcd "github.com/couchbase/eventing/dcp/transport"
+	cb "github.com/couchbase/eventing/dcp/transport/client"
+)
+
+const (
+	testBucket   = "default"
+	testVbuckets = 8
+	testOpaque   = uint16(0xa)
+)
+
+var dcpConfig = map[string]interface{}{
+	"genChanSize":    100,
+	"dataChanSize":   100,
+	"numConnections": 1,
+	"activeVbOnly":   true,
+}
+
+func startFeed(t *testing.T, c *Cluster, flags uint32) *couchbase.DcpFeed {
+	b, err := couchbase.GetBucket(c.URL(), "default", testBucket)
+	if err != nil {
+		t.Fatalf("GetBucket failed, err: %v", err)
+	}
+
+	feed, err := b.StartDcpFeedOver(couchbase.NewDcpFeedName("fakecb_test"), 0, flags,
+		[]string{c.KVAddr()}, testOpaque, dcpConfig)
+	if err != nil {
+		t.Fatalf("StartDcpFeedOver failed, err: %v", err)
+	}
+	return feed
+}
+
+func nextEvent(t *testing.T, feed *couchbase.DcpFeed) *cb.DcpEvent {
+	select {
+	case e := <-feed.C:
+		return e
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Timed out waiting for dcp event")
+	}
+	return nil
+}
+
+func expectOpcode(t *testing.T, feed *couchbase.DcpFeed, opcode mcd.CommandCode) *cb.DcpEvent {
+	e := nextEvent(t, feed)
+	if e.Opcode != opcode {
+		t.Fatalf("Expected opcode: %v, received: %v status: %v", opcode, e.Opcode, e.Status)
+	}
+	return e
+}
+
+func TestStreamMutations(t *testing.T) {
+	c, err := NewCluster(testVbuckets)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	b := c.CreateBucket(testBucket)
+	vb, _ := b.Set("doc1", []byte(`{"a":1}`))
+	b.SetVb(vb, "doc2", []byte(`{"a":2}`), 0)
+
+	feed := startFeed(t, c, 0)
+	defer feed.Close()
+
+	seqnos, err := feed.DcpGetSeqnos()
+	if err != nil || seqnos[vb] != 2 {
+		t.Fatalf("Expected high seqno 2 for vb: %d, received: %v err: %v", vb, seqnos[vb], err)
+	}
+
+	err = feed.DcpRequestStream(vb, testOpaque, 0, b.Vbuuid(vb), 0, MaxSeqno, 0, 0)
+	if err != nil {
+		t.Fatalf("DcpRequestStream failed, err: %v", err)
+	}
+
+	e := expectOpcode(t, feed, mcd.DCP_STREAMREQ)
+	if e.Status != mcd.SUCCESS || e.FailoverLog == nil {
+		t.Fatalf("Stream request failed, status: %v", e.Status)
+	}
+
+	expectOpcode(t, feed, mcd.DCP_SNAPSHOT)
+	for _, key := range []string{"doc1", "doc2"} {
+		e = expectOpcode(t, feed, mcd.DCP_MUTATION)
+		if string(e.Key) != key || e.Datatype != datatypeJSON {
+			t.Fatalf("Expected mutation for key: %s, received key: %s datatype: %d", key, e.Key, e.Datatype)
+		}
+	}
+
+	// Open ended stream keeps delivering new mutations
+	b.Delete("doc1")
+	expectOpcode(t, feed, mcd.DCP_SNAPSHOT)
+	e = expectOpcode(t, feed, mcd.DCP_DELETION)
+	if e.Seqno != 3 {
+		t.Fatalf("Expected deletion seqno 3, received: %d", e.Seqno)
+	}
+
+	if err = feed.DcpCloseStream(vb, testOpaque); err != nil {
+		t.Fatalf("DcpCloseStream failed, err: %v", err)
+	}
+	expectOpcode(t, feed, mcd.DCP_STREAMEND)
+}
+
+func TestStreamEndAndExpiry(t *testing.T) {
+	c, err := NewCluster(testVbuckets)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	b := c.CreateBucket(testBucket)
+	vb, _ := b.Set("doc1", []byte("raw"))
+	b.Expire("doc1")
+
+	feed := startFeed(t, c, dcpOpenIncludeDeleteTimes)
+	defer feed.Close()
+
+	err = feed.DcpRequestStream(vb, testOpaque, 0, b.Vbuuid(vb), 0, 2, 0, 0)
+	if err != nil {
+		t.Fatalf("DcpRequestStream failed, err: %v", err)
+	}
+
+	expectOpcode(t, feed, mcd.DCP_STREAMREQ)
+	expectOpcode(t, feed, mcd.DCP_SNAPSHOT)
+	if e := expectOpcode(t, feed, mcd.DCP_MUTATION); e.Datatype != datatypeRaw {
+		t.Fatalf("Expected raw datatype, received: %d", e.Datatype)
+	}
+	expectOpcode(t, feed, mcd.DCP_EXPIRATION)
+	expectOpcode(t, feed, mcd.DCP_STREAMEND)
+}
+
+func TestRollbackAfterFailover(t *testing.T) {
+	c, err := NewCluster(testVbuckets)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	b := c.CreateBucket(testBucket)
+	vb, _ := b.Set("doc1", []byte(`{}`))
+	for i := 0; i < 4; i++ {
+		b.SetVb(vb, "doc1", []byte(`{}`), 0)
+	}
+
+	oldVbuuid := b.Vbuuid(vb)
+	b.Failover(vb, 3)
+
+	feed := startFeed(t, c, 0)
+	defer feed.Close()
+
+	// Client had seen seqno 5 under the old vbuuid, which the new active never received
+	err = feed.DcpRequestStream(vb, testOpaque, 0, oldVbuuid, 5, MaxSeqno, 5, 5)
+	if err != nil {
+		t.Fatalf("DcpRequestStream failed, err: %v", err)
+	}
+
+	e := expectOpcode(t, feed, mcd.DCP_STREAMREQ)
+	if e.Status != mcd.ROLLBACK || e.Seqno != 3 {
+		t.Fatalf("Expected rollback to seqno 3, received status: %v seqno: %d", e.Status, e.Seqno)
+	}
+
+	// Resuming from the rollback point succeeds
+	err = feed.DcpRequestStream(vb, testOpaque, 0, oldVbuuid, 3, MaxSeqno, 3, 3)
+	if err != nil {
+		t.Fatalf("DcpRequestStream failed, err: %v", err)
+	}
+
+	e = expectOpcode(t, feed, mcd.DCP_STREAMREQ)
+	if e.Status != mcd.SUCCESS {
+		t.Fatalf("Expected stream request to succeed, received status: %v", e.Status)
+	}
+
+	flog := *e.FailoverLog
+	if len(flog) != 2 || flog[0][0] != b.Vbuuid(vb) || flog[0][1] != 3 {
+		t.Fatalf("Unexpected failover log: %v", flog)
+	}
+}
@@ -0,0 +1,470 @@
+package fakecb
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/couchbase/eventing/dcp/transport"
+	"github.com/couchbase/eventing/logging"
+)
+
+// MaxSeqno is end seqno for streams that should stay open for new mutations
+const MaxSeqno = uint64(math.MaxUint64)
+
+const (
+	dcpOpenIncludeDeleteTimes = uint32(0x20)
+
+	snapshotTypeMemory = uint32(0x1)
+
+	streamEndOK           = uint32(0x0)
+	streamEndClosed       = uint32(0x1)
+	streamEndStateChanged = uint32(0x2)
+)
+
+// dcpStream is a vbucket stream open on a KV connection
+type dcpStream struct {
+	vb         uint16
+	opaque     uint32
+	startSeqno uint64
+	endSeqno   uint64
+	closeCh    chan struct{}
+	doneCh     chan struct{}
+}
+
+// kvConn is a single memcached binary protocol connection
+type kvConn struct {
+	cluster *Cluster
+	conn    net.Conn
+	bucket  *Bucket
+
+	writeMutex sync.Mutex
+
+	streamsMutex sync.Mutex
+	streams      map[uint16]*dcpStream
+
+	includeDeleteTimes bool
+	expiryOpcode       bool
+}
+
+func (c *Cluster) acceptKVConns() {
+	logPrefix := "fakecb::acceptKVConns"
+
+	defer c.wg.Done()
+
+	for {
+		conn, err := c.kvListener.Accept()
+		if err != nil {
+			select {
+			case <-c.stopCh:
+			default:
+				logging.Errorf("%s Failed to accept connection, err: %v", logPrefix, err)
+			}
+			return
+		}
+
+		kc := &kvConn{
+			cluster: c,
+			conn:    conn,
+			streams: make(map[uint16]*dcpStream),
+		}
+
+		c.Lock()
+		c.conns[kc] = struct{}{}
+		c.Unlock()
+
+		c.wg.Add(1)
+		go kc.serve()
+	}
+}
+
+func (kc *kvConn) serve() {
+	logPrefix := "fakecb::serve"
+
+	defer func() {
+		kc.conn.Close()
+		kc.closeStreams()
+
+		kc.cluster.Lock()
+		delete(kc.cluster.conns, kc)
+		kc.cluster.Unlock()
+
+		kc.cluster.wg.Done()
+	}()
+
+	hdr := make([]byte, transport.HDR_LEN)
+	for {
+		req := &transport.MCRequest{}
+		_, err := req.Receive(kc.conn, hdr)
+		if err != nil {
+			if err != io.EOF {
+				logging.Tracef("%s Connection: %s closed, err: %v", logPrefix, kc.conn.RemoteAddr(), err)
+			}
+			return
+		}
+
+		if err = kc.handleRequest(req); err != nil {
+			logging.Tracef("%s Connection: %s write failed, err: %v", logPrefix, kc.conn.RemoteAddr(), err)
+			return
+		}
+	}
+}
+
+func (kc *kvConn) handleRequest(req *transport.MCRequest) error {
+	switch req.Opcode {
+	case transport.SASL_LIST_MECHS:
+		return kc.respond(req, transport.SUCCESS, []byte("PLAIN"))
+
+	case transport.SASL_AUTH:
+		// Body is \x00user\x00password, connecting as a bucket's name selects it as in pre-RBAC servers
+		parts := strings.Split(string(req.Body), "\x00")
+		if len(parts) == 3 {
+			if b := kc.cluster.GetBucket(parts[1]); b != nil {
+				kc.bucket = b
+			}
+		}
+		return kc.respond(req, transport.SUCCESS, nil)
+
+	case transport.SELECT_BUCKET:
+		b := kc.cluster.GetBucket(string(req.Key))
+		if b == nil {
+			return kc.respond(req, transport.KEY_ENOENT, nil)
+		}
+		kc.bucket = b
+		return kc.respond(req, transport.SUCCESS, nil)
+
+	case transport.DCP_OPEN:
+		if len(req.Extras) == 8 {
+			flags := binary.BigEndian.Uint32(req.Extras[4:])
+			kc.includeDeleteTimes = flags&dcpOpenIncludeDeleteTimes != 0
+		}
+		return kc.respond(req, transport.SUCCESS, nil)
+
+	case transport.DCP_CONTROL:
+		if string(req.Key) == "enable_expiry_opcode" {
+			kc.expiryOpcode = string(req.Body) == "true"
+		}
+		return kc.respond(req, transport.SUCCESS, nil)
+
+	case transport.DCP_BUFFERACK:
+		// Flow control isn't enforced
+		return nil
+
+	case transport.DCP_NOOP:
+		return nil
+	}
+
+	if kc.selectedBucket() == nil {
+		return kc.respond(req, transport.EINVAL, []byte("no bucket selected"))
+	}
+
+	switch req.Opcode {
+	case transport.DCP_FAILOVERLOG:
+		return kc.handleFailoverLog(req)
+
+	case transport.DCP_GET_SEQNO:
+		return kc.handleGetSeqnos(req)
+
+	case transport.DCP_STREAMREQ:
+		return kc.handleStreamRequest(req)
+
+	case transport.DCP_CLOSESTREAM:
+		return kc.handleCloseStream(req)
+
+	default:
+		return kc.respond(req, transport.UNKNOWN_COMMAND, nil)
+	}
+}
+
+// selectedBucket falls back to bucket named default, like connections that skip auth
+func (kc *kvConn) selectedBucket() *Bucket {
+	if kc.bucket == nil {
+		kc.bucket = kc.cluster.GetBucket("default")
+	}
+	return kc.bucket
+}
+
+func (kc *kvConn) validVb(vb uint16) bool {
+	return int(vb) < len(kc.bucket.vbs)
+}
+
+func (kc *kvConn) handleFailoverLog(req *transport.MCRequest) error {
+	if !kc.validVb(req.VBucket) {
+		return kc.respond(req, transport.NOT_MY_VBUCKET, nil)
+	}
+
+	return kc.respond(req, transport.SUCCESS, encodeFailoverLog(kc.bucket.FailoverLog(req.VBucket)))
+}
+
+func (kc *kvConn) handleGetSeqnos(req *transport.MCRequest) error {
+	b := kc.bucket
+
+	b.RLock()
+	body := make([]byte, 10*len(b.vbs))
+	for i, v := range b.vbs {
+		binary.BigEndian.PutUint16(body[i*10:], v.id)
+		binary.BigEndian.PutUint64(body[i*10+2:], v.highSeqno)
+	}
+	b.RUnlock()
+
+	return kc.respond(req, transport.SUCCESS, body)
+}
+
+func (kc *kvConn) handleStreamRequest(req *transport.MCRequest) error {
+	if len(req.Extras) != 48 {
+		return kc.respond(req, transport.EINVAL, nil)
+	}
+
+	vb := req.VBucket
+	if !kc.validVb(vb) {
+		return kc.respond(req, transport.NOT_MY_VBUCKET, nil)
+	}
+
+	startSeqno := binary.BigEndian.Uint64(req.Extras[8:16])
+	endSeqno := binary.BigEndian.Uint64(req.Extras[16:24])
+	vbuuid := binary.BigEndian.Uint64(req.Extras[24:32])
+	snapStart := binary.BigEndian.Uint64(req.Extras[32:40])
+	snapEnd := binary.BigEndian.Uint64(req.Extras[40:48])
+
+	if startSeqno > endSeqno || snapStart > startSeqno || startSeqno > snapEnd {
+		return kc.respond(req, transport.ERANGE, nil)
+	}
+
+	kc.streamsMutex.Lock()
+	_, exists := kc.streams[vb]
+	kc.streamsMutex.Unlock()
+	if exists {
+		return kc.respond(req, transport.KEY_EEXISTS, nil)
+	}
+
+	b := kc.bucket
+	b.RLock()
+	rollbackSeqno, rollback := b.vbs[vb].rollbackSeqno(vbuuid, startSeqno, snapStart, snapEnd)
+	failoverLog := encodeFailoverLog(b.vbs[vb].failoverLog)
+	b.RUnlock()
+
+	if rollback {
+		body := make([]byte, 8)
+		binary.BigEndian.PutUint64(body, rollbackSeqno)
+		return kc.respond(req, transport.ROLLBACK, body)
+	}
+
+	stream := &dcpStream{
+		vb:         vb,
+		opaque:     req.Opaque,
+		startSeqno: startSeqno,
+		endSeqno:   endSeqno,
+		closeCh:    make(chan struct{}),
+		doneCh:     make(chan struct{}),
+	}
+
+	kc.streamsMutex.Lock()
+	kc.streams[vb] = stream
+	kc.streamsMutex.Unlock()
+
+	if err := kc.respond(req, transport.SUCCESS, failoverLog); err != nil {
+		return err
+	}
+
+	go kc.runStream(stream)
+	return nil
+}
+
+func (kc *kvConn) handleCloseStream(req *transport.MCRequest) error {
+	kc.streamsMutex.Lock()
+	stream, ok := kc.streams[req.VBucket]
+	delete(kc.streams, req.VBucket)
+	kc.streamsMutex.Unlock()
+
+	if !ok {
+		return kc.respond(req, transport.KEY_ENOENT, nil)
+	}
+
+	close(stream.closeCh)
+	<-stream.doneCh
+
+	if err := kc.respond(req, transport.SUCCESS, nil); err != nil {
+		return err
+	}
+
+	// Client opts into send_stream_end_on_client_close_stream
+	return kc.sendStreamEnd(stream, streamEndClosed)
+}
+
+func (kc *kvConn) closeStreams() {
+	kc.streamsMutex.Lock()
+	streams := kc.streams
+	kc.streams = make(map[uint16]*dcpStream)
+	kc.streamsMutex.Unlock()
+
+	for _, stream := range streams {
+		close(stream.closeCh)
+		<-stream.doneCh
+	}
+}
+
+// runStream sends items of the vbucket as snapshots, until end seqno is reached or stream is closed
+func (kc *kvConn) runStream(stream *dcpStream) {
+	defer close(stream.doneCh)
+
+	b := kc.bucket
+	cursor := stream.startSeqno
+
+	for {
+		b.RLock()
+		v := b.vbs[stream.vb]
+		items := v.itemsSince(cursor, stream.endSeqno)
+		highSeqno := v.highSeqno
+		notify := v.notify
+		b.RUnlock()
+
+		// Items the stream already sent were lost to a failover
+		if cursor > highSeqno {
+			kc.endStream(stream, streamEndStateChanged)
+			return
+		}
+
+		if len(items) > 0 {
+			if err := kc.sendSnapshot(stream, items); err != nil {
+				return
+			}
+			cursor = items[len(items)-1].seqno
+		}
+
+		if cursor >= stream.endSeqno {
+			kc.endStream(stream, streamEndOK)
+			return
+		}
+
+		select {
+		case <-notify:
+		case <-stream.closeCh:
+			return
+		}
+	}
+}
+
+// endStream sends stream end unless a close stream request raced with it
+func (kc *kvConn) endStream(stream *dcpStream, flags uint32) {
+	kc.streamsMutex.Lock()
+	current, ok := kc.streams[stream.vb]
+	if ok && current == stream {
+		delete(kc.streams, stream.vb)
+	}
+	kc.streamsMutex.Unlock()
+
+	if ok && current == stream {
+		kc.sendStreamEnd(stream, flags)
+	}
+}
+
+func (kc *kvConn) sendSnapshot(stream *dcpStream, items []*item) error {
+	marker := &transport.MCRequest{
+		Opcode:  transport.DCP_SNAPSHOT,
+		VBucket: stream.vb,
+		Opaque:  stream.opaque,
+		Extras:  make([]byte, 20),
+	}
+	binary.BigEndian.PutUint64(marker.Extras[0:8], items[0].seqno)
+	binary.BigEndian.PutUint64(marker.Extras[8:16], items[len(items)-1].seqno)
+	binary.BigEndian.PutUint32(marker.Extras[16:20], snapshotTypeMemory)
+
+	if err := kc.transmit(marker, datatypeRaw); err != nil {
+		return err
+	}
+
+	for _, it := range items {
+		if err := kc.transmit(kc.itemPacket(stream, it), it.datatype); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (kc *kvConn) itemPacket(stream *dcpStream, it *item) *transport.MCRequest {
+	pkt := &transport.MCRequest{
+		VBucket: stream.vb,
+		Opaque:  stream.opaque,
+		Cas:     it.cas,
+		Key:     it.key,
+	}
+
+	switch {
+	case it.op == itemMutation:
+		pkt.Opcode = transport.DCP_MUTATION
+		pkt.Body = it.value
+		pkt.Extras = make([]byte, 31)
+		binary.BigEndian.PutUint64(pkt.Extras[0:8], it.seqno)
+		binary.BigEndian.PutUint64(pkt.Extras[8:16], it.revSeqno)
+		binary.BigEndian.PutUint32(pkt.Extras[16:20], it.flags)
+		binary.BigEndian.PutUint32(pkt.Extras[20:24], it.expiry)
+
+	case it.op == itemExpiration && kc.expiryOpcode:
+		pkt.Opcode = transport.DCP_EXPIRATION
+		pkt.Extras = make([]byte, 20)
+		binary.BigEndian.PutUint64(pkt.Extras[0:8], it.seqno)
+		binary.BigEndian.PutUint64(pkt.Extras[8:16], it.revSeqno)
+
+	default:
+		pkt.Opcode = transport.DCP_DELETION
+		if kc.includeDeleteTimes {
+			pkt.Extras = make([]byte, 20)
+		} else {
+			pkt.Extras = make([]byte, 18)
+		}
+		binary.BigEndian.PutUint64(pkt.Extras[0:8], it.seqno)
+		binary.BigEndian.PutUint64(pkt.Extras[8:16], it.revSeqno)
+	}
+
+	return pkt
+}
+
+func (kc *kvConn) sendStreamEnd(stream *dcpStream, flags uint32) error {
+	pkt := &transport.MCRequest{
+		Opcode:  transport.DCP_STREAMEND,
+		VBucket: stream.vb,
+		Opaque:  stream.opaque,
+		Extras:  make([]byte, 4),
+	}
+	binary.BigEndian.PutUint32(pkt.Extras, flags)
+	return kc.transmit(pkt, datatypeRaw)
+}
+
+// transmit writes a server initiated packet. MCRequest doesn't encode datatype, hence it's patched in.
+func (kc *kvConn) transmit(pkt *transport.MCRequest, datatype uint8) error {
+	data := pkt.Bytes()
+	data[5] = datatype
+
+	kc.writeMutex.Lock()
+	defer kc.writeMutex.Unlock()
+
+	_, err := kc.conn.Write(data)
+	return err
+}
+
+func (kc *kvConn) respond(req *transport.MCRequest, status transport.Status, body []byte) error {
+	res := &transport.MCResponse{
+		Opcode: req.Opcode,
+		Status: status,
+		Opaque: req.Opaque,
+		Body:   body,
+	}
+
+	kc.writeMutex.Lock()
+	defer kc.writeMutex.Unlock()
+
+	_, err := kc.conn.Write(res.Bytes())
+	return err
+}
+
+func encodeFailoverLog(failoverLog [][2]uint64) []byte {
+	body := make([]byte, 16*len(failoverLog))
+	for i, entry := range failoverLog {
+		binary.BigEndian.PutUint64(body[i*16:], entry[0])
+		binary.BigEndian.PutUint64(body[i*16+8:], entry[1])
+	}
+	return body
+}
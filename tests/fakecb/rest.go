@@ -0,0 +1,212 @@
+package fakecb
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/couchbase/eventing/logging"
+)
+
+const (
+	poolName        = "default"
+	serverGroupName = "Group 1"
+)
+
+func (c *Cluster) restHandler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/pools", c.handlePools)
+	mux.HandleFunc("/pools/default", c.handlePool)
+	mux.HandleFunc("/pools/default/buckets", c.handleBuckets)
+	mux.HandleFunc("/pools/default/buckets/", c.handleBucket)
+	mux.HandleFunc("/pools/default/b/", c.handleBucket)
+	mux.HandleFunc("/pools/default/nodeServices", c.handleNodeServices)
+	mux.HandleFunc("/pools/default/serverGroups", c.handleServerGroups)
+	mux.HandleFunc("/poolsStreaming/default", c.streamingHandler(c.poolInfo))
+	mux.HandleFunc("/pools/default/nodeServicesStreaming", c.streamingHandler(c.nodeServices))
+
+	return mux
+}
+
+func (c *Cluster) node() map[string]interface{} {
+	c.RLock()
+	defer c.RUnlock()
+
+	return map[string]interface{}{
+		"clusterCompatibility": clusterCompatibility,
+		"clusterMembership":    "active",
+		"couchApiBase":         c.rest.URL + "/",
+		"hostname":             c.RESTAddr(),
+		"ports":                map[string]int{"direct": portOf(c.KVAddr())},
+		"status":               "healthy",
+		"uptime":               "1",
+		"version":              "5.5.0-0000-enterprise",
+		"thisNode":             true,
+		"services":             append([]string(nil), c.services...),
+	}
+}
+
+func (c *Cluster) poolInfo() interface{} {
+	return map[string]interface{}{
+		"name":  poolName,
+		"nodes": []interface{}{c.node()},
+		"buckets": map[string]string{
+			"uri":              "/pools/default/buckets",
+			"terseBucketsBase": "/pools/default/b/",
+		},
+		"serverGroupsUri": "/pools/default/serverGroups",
+	}
+}
+
+func (c *Cluster) bucketInfo(b *Bucket) interface{} {
+	vbMap := make([][]int, len(b.vbs))
+	for i := range vbMap {
+		vbMap[i] = []int{0}
+	}
+
+	return map[string]interface{}{
+		"name":                  b.name,
+		"uuid":                  b.uuid,
+		"uri":                   "/pools/default/buckets/" + b.name,
+		"streamingUri":          "/pools/default/bucketsStreaming/" + b.name,
+		"bucketType":            "membase",
+		"authType":              "sasl",
+		"nodeLocator":           "vbucket",
+		"replicaNumber":         0,
+		"bucketCapabilities":    []string{"dcp", "xattr", "couchapi"},
+		"bucketCapabilitiesVer": "",
+		"nodes":                 []interface{}{c.node()},
+		"vBucketServerMap": map[string]interface{}{
+			"hashAlgorithm": "CRC",
+			"numReplicas":   0,
+			"serverList":    []string{c.KVAddr()},
+			"vBucketMap":    vbMap,
+		},
+	}
+}
+
+func (c *Cluster) nodeServices() interface{} {
+	c.RLock()
+	services := map[string]int{
+		"mgmt": portOf(c.RESTAddr()),
+		"kv":   portOf(c.KVAddr()),
+	}
+	for name, port := range c.servicePorts {
+		services[name] = port
+	}
+	c.RUnlock()
+
+	host, _, _ := net.SplitHostPort(c.RESTAddr())
+
+	return map[string]interface{}{
+		"rev": 1,
+		"nodesExt": []interface{}{
+			map[string]interface{}{
+				"services": services,
+				"hostname": host,
+				"thisNode": true,
+			},
+		},
+	}
+}
+
+func (c *Cluster) handlePools(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, map[string]interface{}{
+		"implementationVersion": "5.5.0-0000-enterprise",
+		"isAdminCreds":          true,
+		"uuid":                  "fakecb",
+		"pools": []interface{}{
+			map[string]string{
+				"name":         poolName,
+				"uri":          "/pools/default",
+				"streamingUri": "/poolsStreaming/default",
+			},
+		},
+	})
+}
+
+func (c *Cluster) handlePool(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, c.poolInfo())
+}
+
+func (c *Cluster) handleBuckets(w http.ResponseWriter, r *http.Request) {
+	names := c.bucketNames()
+	sort.Strings(names)
+
+	buckets := make([]interface{}, 0, len(names))
+	for _, name := range names {
+		if b := c.GetBucket(name); b != nil {
+			buckets = append(buckets, c.bucketInfo(b))
+		}
+	}
+	writeJSON(w, buckets)
+}
+
+func (c *Cluster) handleBucket(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+
+	b := c.GetBucket(name)
+	if b == nil {
+		http.Error(w, "Requested resource not found.", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, c.bucketInfo(b))
+}
+
+func (c *Cluster) handleNodeServices(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, c.nodeServices())
+}
+
+func (c *Cluster) handleServerGroups(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, map[string]interface{}{
+		"groups": []interface{}{
+			map[string]interface{}{
+				"name":  serverGroupName,
+				"nodes": []interface{}{c.node()},
+			},
+		},
+	})
+}
+
+// streamingHandler sends a single update, as topology never changes, and holds the
+// connection open the way ns_server does until client or cluster goes away
+func (c *Cluster) streamingHandler(info func() interface{}) http.HandlerFunc {
+	logPrefix := "fakecb::streamingHandler"
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		data, err := json.Marshal(info())
+		if err != nil {
+			logging.Errorf("%s Failed to marshal, err: %v", logPrefix, err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(append(data, []byte("\n\n\n\n")...))
+		if f, ok := w.(http.Flusher); ok {
+			f.Flush()
+		}
+
+		select {
+		case <-c.stopCh:
+		case <-r.Context().Done():
+		}
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	logPrefix := "fakecb::writeJSON"
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		logging.Errorf("%s Failed to marshal, err: %v", logPrefix, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
@@ -17,12 +17,31 @@ import (
 
 // globals
 var (
-	maxRetryTime  = 60 * time.Minute.Nanoseconds()
-	singlePool    *kvPool
-	singleLock    sync.Mutex
-	authenticator gocb.Authenticator = &util.DynamicAuthenticator{Caller: "eventing-timerstore"}
+	maxRetryTime   = 60 * time.Minute.Nanoseconds()
+	singlePool     *kvPool
+	singleLock     sync.Mutex
+	authenticator  gocb.Authenticator = &util.DynamicAuthenticator{Caller: "eventing-timerstore"}
+	kvProvider                        = func(connstr string) KV { return Pool(connstr) }
+	kvProviderLock sync.RWMutex
 )
 
+// KV is the storage timer stores keep their documents in. Values are JSON encoded, and
+// a zero cas makes Replace and Remove unconditional. Absence, existence and cas mismatch
+// are reported as flags rather than errors, as stores resolve concurrency through them.
+type KV interface {
+	Get(bucket, key string, valuePtr interface{}) (cas gocb.Cas, absent bool, err error)
+	Insert(bucket, key string, value interface{}, expiry uint32) (rcas gocb.Cas, exists bool, err error)
+	Upsert(bucket, key string, value interface{}, expiry uint32) (cas gocb.Cas, err error)
+	Replace(bucket, key string, value interface{}, cas gocb.Cas, expiry uint32) (rcas gocb.Cas, absent, mismatch bool, err error)
+	Remove(bucket, key string, cas gocb.Cas) (rcas gocb.Cas, absent, mismatch bool, err error)
+	Counter(bucket, key string, delta, initial int64, expiry uint32) (val int64, cas gocb.Cas, err error)
+}
+
+// mustKV retries failed operations of the KV it wraps as per MustRun
+type mustKV struct {
+	KV
+}
+
 type kvPool struct {
 	ident    string
 	status   error
@@ -64,6 +83,23 @@ func Pool(connstr string) *kvPool {
 	return singlePool
 }
 
+// SetKVProvider replaces the KV stores created or opened afterwards are backed by, e.g.
+// with a MemKV to exercise stores without a cluster. Returns the provider it replaced.
+func SetKVProvider(provider func(connstr string) KV) func(connstr string) KV {
+	kvProviderLock.Lock()
+	defer kvProviderLock.Unlock()
+
+	prev := kvProvider
+	kvProvider = provider
+	return prev
+}
+
+func getKV(connstr string) mustKV {
+	kvProviderLock.RLock()
+	defer kvProviderLock.RUnlock()
+	return mustKV{kvProvider(connstr)}
+}
+
 func PoolStats() map[string]uint64 {
 	if singlePool == nil {
 		return nil
@@ -167,7 +203,7 @@ func (r *kvPool) Get(bucket, key string, valuePtr interface{}) (cas gocb.Cas, ab
 	return
 }
 
-func (r *kvPool) Insert(bucket, key string, value interface{}, expiry uint32) (rcas gocb.Cas, mismatch bool, err error) {
+func (r *kvPool) Insert(bucket, key string, value interface{}, expiry uint32) (rcas gocb.Cas, exists bool, err error) {
 	if r.status != nil {
 		return 0, false, r.status
 	}
@@ -178,8 +214,8 @@ func (r *kvPool) Insert(bucket, key string, value interface{}, expiry uint32) (r
 	}
 	atomic.AddUint64(&r.insertCounter, 1)
 	rcas, err = conn.Insert(key, value, expiry)
-	if err != nil && gocb.IsKeyNotFoundError(err) {
-		mismatch = true
+	if err != nil && gocb.IsKeyExistsError(err) {
+		exists = true
 		err = nil
 	}
 	return
@@ -229,7 +265,7 @@ func (r *kvPool) Remove(bucket, key string, cas gocb.Cas) (rcas gocb.Cas, absent
 	return
 }
 
-func (r *kvPool) MustUpsert(bucket, key string, value interface{}, expiry uint32) (cas gocb.Cas, err error) {
+func (r mustKV) MustUpsert(bucket, key string, value interface{}, expiry uint32) (cas gocb.Cas, err error) {
 	err = MustRun(func() (e error) {
 		cas, e = r.Upsert(bucket, key, value, expiry)
 		return
@@ -237,7 +273,7 @@ func (r *kvPool) MustUpsert(bucket, key string, value interface{}, expiry uint32
 	return
 }
 
-func (r *kvPool) MustCounter(bucket, key string, delta, initial int64, expiry uint32) (val int64, cas gocb.Cas, err error) {
+func (r mustKV) MustCounter(bucket, key string, delta, initial int64, expiry uint32) (val int64, cas gocb.Cas, err error) {
 	err = MustRun(func() (e error) {
 		val, cas, e = r.Counter(bucket, key, delta, initial, expiry)
 		return
@@ -245,7 +281,7 @@ func (r *kvPool) MustCounter(bucket, key string, delta, initial int64, expiry ui
 	return
 }
 
-func (r *kvPool) MustGet(bucket, key string, valuePtr interface{}) (cas gocb.Cas, absent bool, err error) {
+func (r mustKV) MustGet(bucket, key string, valuePtr interface{}) (cas gocb.Cas, absent bool, err error) {
 	err = MustRun(func() (e error) {
 		cas, absent, e = r.Get(bucket, key, valuePtr)
 		return
@@ -253,21 +289,23 @@ func (r *kvPool) MustGet(bucket, key string, valuePtr interface{}) (cas gocb.Cas
 	return
 }
 
-func (r *kvPool) MustReplace(bucket, key string, value interface{}, cas gocb.Cas, expiry uint32) (rcas gocb.Cas, absent, mismatch bool, err error) {
+func (r mustKV) MustReplace(bucket, key string, value interface{}, cas gocb.Cas, expiry uint32) (rcas gocb.Cas, absent, mismatch bool, err error) {
 	err = MustRun(func() (e error) {
 		rcas, absent, mismatch, e = r.Replace(bucket, key, value, cas, expiry)
 		return
 	})
 	return
 }
-func (r *kvPool) MustInsert(bucket, key string, value interface{}, expiry uint32) (rcas gocb.Cas, absent bool, err error) {
+
+func (r mustKV) MustInsert(bucket, key string, value interface{}, expiry uint32) (rcas gocb.Cas, exists bool, err error) {
 	err = MustRun(func() (e error) {
-		rcas, absent, e = r.Insert(bucket, key, value, expiry)
+		rcas, exists, e = r.Insert(bucket, key, value, expiry)
 		return
 	})
 	return
 }
-func (r *kvPool) MustRemove(bucket, key string, cas gocb.Cas) (rcas gocb.Cas, absent bool, mismatch bool, err error) {
+
+func (r mustKV) MustRemove(bucket, key string, cas gocb.Cas) (rcas gocb.Cas, absent bool, mismatch bool, err error) {
 	err = MustRun(func() (e error) {
 		rcas, absent, mismatch, e = r.Remove(bucket, key, cas)
 		return
@@ -0,0 +1,173 @@
+package timers
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/couchbase/gocb"
+)
+
+// MemKV is an in-memory KV, to exercise timer stores without a cluster. As on the
+// server, every mutation assigns the document a new cas, so conditional replace and
+// remove fail with a cas mismatch once the document changed after it was read.
+// Expiry is ignored.
+type MemKV struct {
+	lock    sync.Mutex
+	buckets map[string]map[string]*memDoc
+	lastCas uint64
+	hook    func(op, bucket, key string)
+}
+
+type memDoc struct {
+	value []byte
+	cas   gocb.Cas
+}
+
+func NewMemKV() *MemKV {
+	return &MemKV{buckets: make(map[string]map[string]*memDoc)}
+}
+
+// SetHook registers fn to be called ahead of every operation, with op being the name
+// of the KV method. It runs outside of the lock, so that it can mutate documents to
+// simulate a write racing with the operation at that exact point.
+func (m *MemKV) SetHook(fn func(op, bucket, key string)) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.hook = fn
+}
+
+// Keys returns keys present in bucket, in sorted order
+func (m *MemKV) Keys(bucket string) []string {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	keys := make([]string, 0, len(m.buckets[bucket]))
+	for key := range m.buckets[bucket] {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (m *MemKV) Get(bucket, key string, valuePtr interface{}) (cas gocb.Cas, absent bool, err error) {
+	m.runHook("Get", bucket, key)
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	doc, found := m.buckets[bucket][key]
+	if !found {
+		return 0, true, nil
+	}
+
+	if err = json.Unmarshal(doc.value, valuePtr); err != nil {
+		return 0, false, err
+	}
+	return doc.cas, false, nil
+}
+
+func (m *MemKV) Insert(bucket, key string, value interface{}, expiry uint32) (rcas gocb.Cas, exists bool, err error) {
+	m.runHook("Insert", bucket, key)
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if _, found := m.buckets[bucket][key]; found {
+		return 0, true, nil
+	}
+
+	rcas, err = m.store(bucket, key, value)
+	return
+}
+
+func (m *MemKV) Upsert(bucket, key string, value interface{}, expiry uint32) (cas gocb.Cas, err error) {
+	m.runHook("Upsert", bucket, key)
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	return m.store(bucket, key, value)
+}
+
+func (m *MemKV) Replace(bucket, key string, value interface{}, cas gocb.Cas, expiry uint32) (rcas gocb.Cas, absent, mismatch bool, err error) {
+	m.runHook("Replace", bucket, key)
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	doc, found := m.buckets[bucket][key]
+	if !found {
+		return 0, true, false, nil
+	}
+	if cas != 0 && cas != doc.cas {
+		return 0, false, true, nil
+	}
+
+	rcas, err = m.store(bucket, key, value)
+	return
+}
+
+func (m *MemKV) Remove(bucket, key string, cas gocb.Cas) (rcas gocb.Cas, absent, mismatch bool, err error) {
+	m.runHook("Remove", bucket, key)
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	doc, found := m.buckets[bucket][key]
+	if !found {
+		return 0, true, false, nil
+	}
+	if cas != 0 && cas != doc.cas {
+		return 0, false, true, nil
+	}
+
+	delete(m.buckets[bucket], key)
+	return m.nextCas(), false, false, nil
+}
+
+// Counter creates the document with initial value if absent, else adds delta to it
+func (m *MemKV) Counter(bucket, key string, delta, initial int64, expiry uint32) (val int64, cas gocb.Cas, err error) {
+	m.runHook("Counter", bucket, key)
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	val = initial
+	if doc, found := m.buckets[bucket][key]; found {
+		if val, err = strconv.ParseInt(string(doc.value), 10, 64); err != nil {
+			return 0, 0, fmt.Errorf("counter %s in bucket %s has non numeric value: %s", key, bucket, doc.value)
+		}
+		val += delta
+	}
+
+	cas, err = m.store(bucket, key, val)
+	return
+}
+
+func (m *MemKV) runHook(op, bucket, key string) {
+	m.lock.Lock()
+	hook := m.hook
+	m.lock.Unlock()
+
+	if hook != nil {
+		hook(op, bucket, key)
+	}
+}
+
+func (m *MemKV) nextCas() gocb.Cas {
+	m.lastCas++
+	return gocb.Cas(m.lastCas)
+}
+
+// store writes value to key, to be called with lock held
+func (m *MemKV) store(bucket, key string, value interface{}) (gocb.Cas, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return 0, err
+	}
+
+	if _, found := m.buckets[bucket]; !found {
+		m.buckets[bucket] = make(map[string]*memDoc)
+	}
+
+	doc := &memDoc{value: data, cas: m.nextCas()}
+	m.buckets[bucket][key] = doc
+	return doc.cas, nil
+}
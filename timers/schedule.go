@@ -0,0 +1,209 @@
+package timers
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cron expressions with no firing within this many years are rejected, e.g. "0 0 31 2 *"
+const cronSearchYears = 5
+
+var ErrNoCronFiring = errors.New("cron expression never fires")
+
+// Schedule of a recurring timer, either a standard 5 field cron expression
+// (minute hour day-of-month month day-of-week) evaluated in UTC, or a fixed
+// interval in seconds. Exactly one of them is set.
+type Schedule struct {
+	Cron     string `json:"crn,omitempty"`
+	Interval int64  `json:"ivl,omitempty"`
+}
+
+type cronSpec struct {
+	minute, hour, dom, month, dow uint64 // Bit set of matching values
+	domStar, dowStar              bool
+}
+
+type cronField struct {
+	name     string
+	min, max int
+}
+
+var cronFields = []cronField{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7},
+}
+
+func (s *Schedule) validate(resolution int64) error {
+	if (s.Cron == "") == (s.Interval == 0) {
+		return fmt.Errorf("either cron or interval must be specified for recurring timer")
+	}
+
+	if s.Interval != 0 && s.Interval < resolution {
+		return fmt.Errorf("interval: %d should be at least timer resolution of %d seconds", s.Interval, resolution)
+	}
+
+	if s.Cron != "" {
+		spec, err := parseCron(s.Cron)
+		if err != nil {
+			return err
+		}
+		if _, err = spec.next(time.Now().Unix()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Next returns due time of the firing following one due at prev. Missed firings
+// are skipped, so that a series that fell behind doesn't fire in a burst.
+func (s *Schedule) Next(prev, now int64) (int64, error) {
+	if s.Interval > 0 {
+		next := prev + s.Interval
+		if next <= now {
+			next += ((now-next)/s.Interval + 1) * s.Interval
+		}
+		return next, nil
+	}
+
+	spec, err := parseCron(s.Cron)
+	if err != nil {
+		return 0, err
+	}
+
+	if prev < now {
+		prev = now
+	}
+	return spec.next(prev)
+}
+
+func parseCron(expr string) (*cronSpec, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != len(cronFields) {
+		return nil, fmt.Errorf("cron expression: %q should have %d fields", expr, len(cronFields))
+	}
+
+	bits := make([]uint64, len(fields))
+	for i, field := range fields {
+		var err error
+		if bits[i], err = parseCronField(field, cronFields[i]); err != nil {
+			return nil, fmt.Errorf("cron expression: %q %v", expr, err)
+		}
+	}
+
+	spec := &cronSpec{
+		minute:  bits[0],
+		hour:    bits[1],
+		dom:     bits[2],
+		month:   bits[3],
+		dow:     bits[4],
+		domStar: fields[2] == "*",
+		dowStar: fields[4] == "*",
+	}
+
+	// Both 0 and 7 stand for Sunday
+	if spec.dow&(1<<7) != 0 {
+		spec.dow |= 1
+	}
+	return spec, nil
+}
+
+// parseCronField accepts comma separated list of '*', 'n' or 'n-m', each optionally followed by '/step'
+func parseCronField(field string, f cronField) (uint64, error) {
+	var bits uint64
+
+	for _, part := range strings.Split(field, ",") {
+		rng, step := part, 1
+
+		if i := strings.Index(part, "/"); i >= 0 {
+			var err error
+			rng = part[:i]
+			if step, err = strconv.Atoi(part[i+1:]); err != nil || step <= 0 {
+				return 0, fmt.Errorf("has invalid step in %s field: %q", f.name, part)
+			}
+		}
+
+		lo, hi := f.min, f.max
+		if rng != "*" {
+			bounds := strings.SplitN(rng, "-", 2)
+
+			var err error
+			if lo, err = strconv.Atoi(bounds[0]); err != nil {
+				return 0, fmt.Errorf("has invalid value in %s field: %q", f.name, part)
+			}
+
+			hi = lo
+			if len(bounds) == 2 {
+				if hi, err = strconv.Atoi(bounds[1]); err != nil {
+					return 0, fmt.Errorf("has invalid value in %s field: %q", f.name, part)
+				}
+			} else if step > 1 {
+				hi = f.max
+			}
+		}
+
+		if lo < f.min || hi > f.max || lo > hi {
+			return 0, fmt.Errorf("has %s field: %q out of range %d-%d", f.name, part, f.min, f.max)
+		}
+
+		for val := lo; val <= hi; val += step {
+			bits |= 1 << uint(val)
+		}
+	}
+
+	return bits, nil
+}
+
+func (c *cronSpec) matchDay(t time.Time) bool {
+	domMatch := c.dom&(1<<uint(t.Day())) != 0
+	dowMatch := c.dow&(1<<uint(t.Weekday())) != 0
+
+	// As in crontab, restricting both day fields fires on days matching either one
+	switch {
+	case c.domStar && c.dowStar:
+		return true
+	case c.domStar:
+		return dowMatch
+	case c.dowStar:
+		return domMatch
+	default:
+		return domMatch || dowMatch
+	}
+}
+
+// next returns the first minute matching the spec strictly after the given time
+func (c *cronSpec) next(after int64) (int64, error) {
+	t := time.Unix(after, 0).UTC().Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(cronSearchYears, 0, 0)
+
+	for t.Before(limit) {
+		if c.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+
+		if !c.matchDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+
+		if c.hour&(1<<uint(t.Hour())) == 0 {
+			t = t.Truncate(time.Hour).Add(time.Hour)
+			continue
+		}
+
+		if c.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+
+		return t.Unix(), nil
+	}
+
+	return 0, ErrNoCronFiring
+}
@@ -0,0 +1,196 @@
+package timers
+
+import (
+	"testing"
+	"time"
+)
+
+func cronBits(vals ...int) uint64 {
+	var bits uint64
+	for _, val := range vals {
+		bits |= 1 << uint(val)
+	}
+	return bits
+}
+
+func cronRange(lo, hi, step int) uint64 {
+	var bits uint64
+	for val := lo; val <= hi; val += step {
+		bits |= 1 << uint(val)
+	}
+	return bits
+}
+
+func utcUnix(t *testing.T, value string) int64 {
+	ts, err := time.Parse("2006-01-02 15:04", value)
+	if err != nil {
+		t.Fatalf("Unable to parse time %q: %v", value, err)
+	}
+	return ts.Unix()
+}
+
+func TestParseCronField(t *testing.T) {
+	minute, dom, dow := cronFields[0], cronFields[2], cronFields[4]
+
+	tests := []struct {
+		field string
+		spec  cronField
+		want  uint64
+		fail  bool
+	}{
+		{"*", minute, cronRange(0, 59, 1), false},
+		{"5", minute, cronBits(5), false},
+		{"1-3", minute, cronBits(1, 2, 3), false},
+		{"*/15", minute, cronBits(0, 15, 30, 45), false},
+		{"10-20/5", minute, cronBits(10, 15, 20), false},
+		{"5/20", minute, cronBits(5, 25, 45), false},
+		{"1,3-4,59", minute, cronBits(1, 3, 4, 59), false},
+		{"*", dom, cronRange(1, 31, 1), false},
+		{"*/10", dom, cronBits(1, 11, 21, 31), false},
+		{"0,7", dow, cronBits(0, 7), false},
+		{"60", minute, 0, true},
+		{"0", dom, 0, true},
+		{"8", dow, 0, true},
+		{"5-1", minute, 0, true},
+		{"*/0", minute, 0, true},
+		{"*/x", minute, 0, true},
+		{"a", minute, 0, true},
+		{"1-b", minute, 0, true},
+		{"", minute, 0, true},
+	}
+
+	for _, test := range tests {
+		got, err := parseCronField(test.field, test.spec)
+		if test.fail {
+			if err == nil {
+				t.Errorf("%s field %q: expected error, got bits %b", test.spec.name, test.field, got)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s field %q: unexpected error: %v", test.spec.name, test.field, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("%s field %q: got bits %b, want %b", test.spec.name, test.field, got, test.want)
+		}
+	}
+}
+
+func TestCronDayMatching(t *testing.T) {
+	tests := []struct {
+		expr string
+		day  string
+		want bool
+	}{
+		// Both day fields restricted, either one matching fires
+		{"0 0 13 * 5", "2026-10-13 00:00", true},  // Tuesday 13th
+		{"0 0 13 * 5", "2026-10-16 00:00", true},  // Friday 16th
+		{"0 0 13 * 5", "2026-11-13 00:00", true},  // Friday 13th
+		{"0 0 13 * 5", "2026-10-14 00:00", false}, // Wednesday 14th
+
+		// Only one day field restricted, the other doesn't widen it
+		{"0 0 13 * *", "2026-10-13 00:00", true},
+		{"0 0 13 * *", "2026-10-16 00:00", false},
+		{"0 0 * * 5", "2026-10-16 00:00", true},
+		{"0 0 * * 5", "2026-10-13 00:00", false},
+		{"0 0 * * *", "2026-10-14 00:00", true},
+
+		// Sunday is both 0 and 7
+		{"0 0 * * 7", "2026-10-18 00:00", true},
+		{"0 0 * * 0", "2026-10-18 00:00", true},
+		{"0 0 * * 7", "2026-10-19 00:00", false},
+	}
+
+	for _, test := range tests {
+		spec, err := parseCron(test.expr)
+		if err != nil {
+			t.Fatalf("Unable to parse %q: %v", test.expr, err)
+		}
+
+		day := time.Unix(utcUnix(t, test.day), 0).UTC()
+		if got := spec.matchDay(day); got != test.want {
+			t.Errorf("%q on %s: got %v, want %v", test.expr, test.day, got, test.want)
+		}
+	}
+}
+
+func TestScheduleNext(t *testing.T) {
+	tests := []struct {
+		name     string
+		schedule Schedule
+		prev     string
+		now      string
+		want     string
+	}{
+		{"interval on time", Schedule{Interval: 600}, "2026-10-16 10:00", "2026-10-16 10:00", "2026-10-16 10:10"},
+		{"interval ahead of now", Schedule{Interval: 600}, "2026-10-16 10:00", "2026-10-16 09:00", "2026-10-16 10:10"},
+		{"interval skips missed firings", Schedule{Interval: 600}, "2026-10-16 10:00", "2026-10-16 10:35", "2026-10-16 10:40"},
+		{"interval missed up to now", Schedule{Interval: 600}, "2026-10-16 10:00", "2026-10-16 10:40", "2026-10-16 10:50"},
+		{"cron on time", Schedule{Cron: "*/15 * * * *"}, "2026-10-16 10:00", "2026-10-16 10:00", "2026-10-16 10:15"},
+		{"cron skips missed firings", Schedule{Cron: "*/15 * * * *"}, "2026-10-16 10:00", "2026-10-16 11:07", "2026-10-16 11:15"},
+		{"cron over weekend", Schedule{Cron: "0 9 * * 1-5"}, "2026-10-16 09:00", "2026-10-16 09:00", "2026-10-19 09:00"},
+		{"cron over month end", Schedule{Cron: "30 6 1 * *"}, "2026-10-01 06:30", "2026-10-16 10:00", "2026-11-01 06:30"},
+		{"cron over year end", Schedule{Cron: "0 0 13 * 5"}, "2026-11-13 00:00", "2026-12-31 12:00", "2027-01-01 00:00"}, // Friday 1st
+	}
+
+	for _, test := range tests {
+		got, err := test.schedule.Next(utcUnix(t, test.prev), utcUnix(t, test.now))
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+			continue
+		}
+
+		if want := utcUnix(t, test.want); got != want {
+			t.Errorf("%s: got %s, want %s", test.name,
+				time.Unix(got, 0).UTC().Format("2006-01-02 15:04"), test.want)
+		}
+	}
+}
+
+func TestCronNeverFires(t *testing.T) {
+	for _, expr := range []string{"0 0 31 2 *", "0 0 30 2 *", "0 0 31 4,6,9,11 *"} {
+		schedule := &Schedule{Cron: expr}
+
+		if _, err := schedule.Next(0, time.Now().Unix()); err != ErrNoCronFiring {
+			t.Errorf("%q: expected ErrNoCronFiring from Next, got: %v", expr, err)
+		}
+		if err := schedule.validate(1); err != ErrNoCronFiring {
+			t.Errorf("%q: expected ErrNoCronFiring from validate, got: %v", expr, err)
+		}
+	}
+
+	// Day of week widens an impossible day of month
+	schedule := &Schedule{Cron: "0 0 31 2 1"}
+	if err := schedule.validate(1); err != nil {
+		t.Errorf("%q: unexpected error: %v", schedule.Cron, err)
+	}
+}
+
+func TestScheduleValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		schedule Schedule
+		fail     bool
+	}{
+		{"cron", Schedule{Cron: "0 9 * * 1-5"}, false},
+		{"interval", Schedule{Interval: 60}, false},
+		{"interval at resolution", Schedule{Interval: 7}, false},
+		{"interval below resolution", Schedule{Interval: 6}, true},
+		{"neither", Schedule{}, true},
+		{"both", Schedule{Cron: "* * * * *", Interval: 60}, true},
+		{"too few fields", Schedule{Cron: "0 9 * *"}, true},
+		{"out of range", Schedule{Cron: "0 24 * * *"}, true},
+	}
+
+	for _, test := range tests {
+		err := test.schedule.validate(7)
+		if test.fail && err == nil {
+			t.Errorf("%s: expected error", test.name)
+		}
+		if !test.fail && err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+		}
+	}
+}
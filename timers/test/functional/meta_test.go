@@ -86,7 +86,7 @@ func scanTimers(store *timers.TimerStore, scanSleep int, fired *uint64, wg *sync
 			return
 		}
 
-		delta := timers.Resolution - int64(time.Now().Sub(startTs).Seconds())
+		delta := timers.DefaultResolution - int64(time.Now().Sub(startTs).Seconds())
 		if delta > 0 {
 			time.Sleep(time.Duration(delta) * time.Second)
 		}
@@ -120,7 +120,7 @@ func createStores() []*timers.TimerStore {
 
 	uid := "timertest-" + strconv.FormatInt(time.Now().Unix(), 36)
 	for partn := 0; partn < partitions; partn++ {
-		err := timers.Create(uid, partn, connStr, "default")
+		err := timers.Create(uid, partn, connStr, "default", timers.DefaultResolution)
 		if err != nil {
 			log.Printf("partition: %d failed to create store handle, err: %v\n", partn, err)
 		} else {
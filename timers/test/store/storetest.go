@@ -31,7 +31,7 @@ func run(partn int, load int, pwg *sync.WaitGroup) {
 	if len(os.Args) == 2 {
 		cstr = os.Args[1]
 	}
-	timers.Create(uid, partn, cstr, "default")
+	timers.Create(uid, partn, cstr, "default", timers.DefaultResolution)
 	store, present := timers.Fetch(uid, partn)
 	if !present {
 		panic("store was absent")
@@ -48,7 +48,7 @@ func run(partn int, load int, pwg *sync.WaitGroup) {
 	go func() {
 		defer wait.Done()
 		for i := 0; i < load; i++ {
-			due := time.Now().Add(time.Duration(rand.Intn(5)+1) * time.Second).Add(time.Second * time.Duration(timers.Resolution))
+			due := time.Now().Add(time.Duration(rand.Intn(5)+1) * time.Second).Add(time.Second * time.Duration(timers.DefaultResolution))
 			ref := "item-" + strconv.Itoa(i)
 			ctx := make(map[string]interface{})
 			ctx["Ref"] = ref
@@ -114,7 +114,7 @@ func run(partn int, load int, pwg *sync.WaitGroup) {
 				if dbe.Unix() > time.Now().Unix() {
 					logging.Errorf("Timer %v fired too early at %v", entry, time.Now().Unix())
 				}
-				if time.Now().Unix()-dbe.Unix() > 10*timers.Resolution {
+				if time.Now().Unix()-dbe.Unix() > 10*timers.DefaultResolution {
 					logging.Errorf("Timer %v was too late: %+v", dbe.Unix(), entry)
 				}
 				if dbe.Unix() != due {
@@ -127,7 +127,7 @@ func run(partn int, load int, pwg *sync.WaitGroup) {
 				fired++
 			}
 			time.Sleep(1 * time.Second)
-			if finish.Add(time.Second * time.Duration(timers.Resolution) * 2).Before(time.Now()) {
+			if finish.Add(time.Second * time.Duration(timers.DefaultResolution) * 2).Before(time.Now()) {
 				break
 			}
 		}
@@ -135,7 +135,7 @@ func run(partn int, load int, pwg *sync.WaitGroup) {
 
 	done := make(chan struct{})
 	go func(store *timers.TimerStore, done chan struct{}) {
-		ticker := time.NewTicker(time.Duration(timers.Resolution) * time.Second)
+		ticker := time.NewTicker(time.Duration(timers.DefaultResolution) * time.Second)
 
 		for {
 			select {
@@ -0,0 +1,117 @@
+package timers
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"github.com/couchbase/eventing/logging"
+	"github.com/couchbase/gocb"
+)
+
+// Open returns store of a partition to inspect or cancel its timers. Unlike Create, the
+// store isn't registered for span sync and a missing span isn't persisted, so partitions
+// owned by other nodes can be opened as well.
+func Open(uid string, partn int, connstr string, bucket string) (*TimerStore, error) {
+	store := &TimerStore{
+		connstr:    connstr,
+		bucket:     bucket,
+		uid:        uid,
+		partn:      partn,
+		log:        fmt.Sprintf("timerstore:%v:%v", uid, partn),
+		span:       storeSpan{empty: true, dirty: false},
+		resolution: DefaultResolution,
+		kv:         getKV(connstr),
+	}
+
+	span := Span{}
+	rcas, absent, err := store.kv.MustGet(bucket, store.kvLocatorSpan(), &span)
+	if err != nil {
+		return nil, err
+	}
+
+	if !absent {
+		store.span.Span = span
+		store.span.spanCas = rcas
+		store.span.empty = false
+		if span.Resolution > 0 {
+			store.resolution = span.Resolution
+		}
+	}
+
+	logging.Tracef("%v Opened timerdata store with span %+v", store.log, store.span)
+	return store, nil
+}
+
+// ScanRange iterates over timers due within [from, to], clamped to span of the store.
+// Zero from or to leave that end unbounded. Unlike ScanDue, superseded alarms aren't
+// deleted and span isn't shrunk, as timers may not be due yet.
+func (r *TimerStore) ScanRange(from, to int64) *TimerIter {
+	r.span.lock.Lock()
+	span, empty := r.span.Span, r.span.empty
+	r.span.lock.Unlock()
+
+	if empty {
+		return nil
+	}
+
+	start := span.Start
+	if from > 0 && r.roundUp(from) > start {
+		start = r.roundUp(from)
+	}
+
+	stop := span.Stop
+	if to > 0 && r.roundDown(to) < stop {
+		stop = r.roundDown(to)
+	}
+
+	if start > stop {
+		return nil
+	}
+
+	// Rows are looked up past current, hence iteration begins a row ahead of start
+	iter := TimerIter{
+		store: r,
+		entry: nil,
+		row: rowIter{
+			start:   start,
+			current: start - r.resolution,
+			stop:    stop,
+		},
+		col:      nil,
+		readOnly: true,
+	}
+
+	logging.Tracef("%v Created range iterator: %+v", r.log, iter)
+	return &iter
+}
+
+// CancelToken cancels the timer a token was issued for, as described on DeleteToken
+func (r *TimerStore) CancelToken(token *DeleteToken) error {
+	atomic.AddUint64(&r.stats.cancelCounter, 1)
+	logging.Tracef("%v Cancelling timer by token %+v", r.log, token)
+
+	kv := r.kv
+
+	if token.ContextKey != "" {
+		_, absent, mismatch, err := kv.MustRemove(token.Bucket, token.ContextKey, gocb.Cas(token.ContextCas))
+		if err != nil {
+			return err
+		}
+		if absent || mismatch {
+			logging.Debugf("%v Timer cancel %v context fired or overridden concurrently", r.log, token.ContextKey)
+		}
+	}
+
+	_, absent, mismatch, err := kv.MustRemove(token.Bucket, token.AlarmKey, gocb.Cas(token.AlarmCas))
+	if err != nil {
+		return err
+	}
+	if absent || mismatch {
+		atomic.AddUint64(&r.stats.cancelAlarmMissingCounter, 1)
+		logging.Debugf("%v Timer cancel %v unexpected concurrency on alarm", r.log, token.AlarmKey)
+		return nil
+	}
+
+	atomic.AddUint64(&r.stats.cancelSuccessCounter, 1)
+	return nil
+}
@@ -19,10 +19,12 @@ import (
 
 // Constants
 const (
-	Resolution  = int64(7) // seconds
-	init_seq    = int64(128)
-	dict        = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789*&"
-	encode_base = 10 // TODO: Change to 36 before GA
+	DefaultResolution = int64(7) // seconds, also of stores persisted before resolution was configurable
+	MaxResolution     = int64(60)
+	init_seq          = int64(128)
+	censusRows        = 16 // Row counter lookups per store and round of the pending census
+	dict              = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789*&"
+	encode_base       = 10 // TODO: Change to 36 before GA
 )
 
 // Globals
@@ -43,18 +45,21 @@ type AlarmRecord struct {
 type ContextRecord struct {
 	Context  interface{} `json:"ctx"`
 	AlarmRef string      `json:"alr"`
+	Schedule *Schedule   `json:"sch,omitempty"` // Set for recurring timers
 }
 
 type TimerEntry struct {
 	AlarmRecord
 	ContextRecord
 
-	alarmSeq int64
-	ctxCas   gocb.Cas
-	alrCas   gocb.Cas
+	alarmSeq    int64
+	ctxCas      gocb.Cas
+	alrCas      gocb.Cas
+	rescheduled bool
 }
 
-// This can be used to delete a timer from outside this project, as follows:
+// This can be used to delete a timer from outside this project, as follows, skipping
+// the first step if context_key is empty as for a rescheduled recurring timer:
 //  1. Delete context_key from bucket with context_cas, ignore any absent/mismatch error
 //  2. Delete alarm_key from bucket with alarm_cas, log any absent/mismatch error
 type DeleteToken struct {
@@ -80,8 +85,9 @@ type colIter struct {
 }
 
 type Span struct {
-	Start int64 `json:"sta"`
-	Stop  int64 `json:"stp"`
+	Start      int64 `json:"sta"`
+	Stop       int64 `json:"stp"`
+	Resolution int64 `json:"res,omitempty"` // Width of rows alarms are bucketed into
 }
 
 type storeSpan struct {
@@ -93,13 +99,16 @@ type storeSpan struct {
 }
 
 type TimerStore struct {
-	connstr string
-	bucket  string
-	uid     string
-	partn   int
-	log     string
-	span    storeSpan
-	stats   timerStats
+	connstr    string
+	bucket     string
+	uid        string
+	partn      int
+	log        string
+	span       storeSpan
+	stats      timerStats
+	backlog    timerBacklog
+	resolution int64
+	kv         mustKV
 }
 
 type TimerIter struct {
@@ -107,6 +116,20 @@ type TimerIter struct {
 	row   rowIter
 	col   *colIter
 	entry *TimerEntry
+
+	// Set for iterators only inspecting timers, which leave span and stale alarms as is
+	readOnly bool
+}
+
+// Timers pending in a partition: those overdue, i.e. handed out by due scans and yet to fire,
+// and those in rows not scanned yet, counted off row counters as seen by Set and by the census
+// walking the span a few rows per round. Due scans hand the rows they walk over to overdue.
+type timerBacklog struct {
+	sync.Mutex
+	overdue map[string]int64 // Due time keyed by alarm key
+	rows    map[int64]int64  // Alarms allotted by row counters, keyed by due time of the row
+	census  int64            // Row the census reached in its current pass over the span
+	fireLag int64            // Seconds the latest fired timer was late by
 }
 
 type timerStats struct {
@@ -116,6 +139,8 @@ type timerStats struct {
 	delSuccessCounter           uint64 `json:"meta_del_success"`
 	setCounter                  uint64 `json:"meta_set"`
 	setSuccessCounter           uint64 `json:"meta_set_success"`
+	rescheduleCounter           uint64 `json:"meta_reschedule"`
+	rescheduleSuccessCounter    uint64 `json:"meta_reschedule_success"`
 	timerInPastCounter          uint64 `json:"meta_timer_in_past"`
 	timerInFutureFiredCounter   uint64 `json:"meta_timer_in_future_fired"`
 	alarmMissingCounter         uint64 `json:"meta_alarm_missing"`
@@ -134,7 +159,9 @@ type timerStats struct {
 	spanCasMismatchCounter      uint64 `json:"meta_span_cas_mismatch"`
 }
 
-func Create(uid string, partn int, connstr string, bucket string) error {
+// Create sets up store of a partition. Resolution applies only if the partition has no
+// store persisted yet, as existing alarms are bucketed by the resolution they were set with.
+func Create(uid string, partn int, connstr string, bucket string, resolution int64) error {
 	stores.lock.Lock()
 	defer stores.lock.Unlock()
 
@@ -143,7 +170,7 @@ func Create(uid string, partn int, connstr string, bucket string) error {
 		logging.Warnf("Asked to create store %v:%v which exists. Reusing", uid, partn)
 		return nil
 	}
-	store, err := newTimerStore(uid, partn, connstr, bucket)
+	store, err := newTimerStore(uid, partn, connstr, bucket, resolution)
 	if err != nil {
 		return err
 	}
@@ -170,33 +197,39 @@ func (r *TimerStore) Free() {
 }
 
 func (r *TimerStore) Set(due int64, ref string, context interface{}) error {
-	now := time.Now().Unix()
-	atomic.AddUint64(&r.stats.setCounter, 1)
+	return r.set(due, ref, context, nil)
+}
 
-	if due-now <= Resolution {
-		atomic.AddUint64(&r.stats.timerInPastCounter, 1)
-		logging.Debugf("%v Moving too close/past timer to next period: %v context %ru", r.log, formatInt(due), context)
-		due = now + Resolution
+// SetRecurring creates a timer firing as per schedule, first at due or when due is zero,
+// at the schedule's next firing. Cancel with the same ref stops the whole series.
+func (r *TimerStore) SetRecurring(due int64, ref string, context interface{}, schedule *Schedule) error {
+	if err := schedule.validate(r.resolution); err != nil {
+		return err
 	}
-	due = roundUp(due)
 
-	kv := Pool(r.connstr)
-	pos := r.kvLocatorRoot(due)
-	seq, _, err := kv.MustCounter(r.bucket, pos, 1, init_seq, 0)
-	if err != nil {
-		return err
+	if due == 0 {
+		now := time.Now().Unix()
+		next, err := schedule.Next(now, now)
+		if err != nil {
+			return err
+		}
+		due = next
 	}
+	return r.set(due, ref, context, schedule)
+}
 
-	akey := r.kvLocatorAlarm(due, seq)
-	ckey := r.kvLocatorContext(ref)
+func (r *TimerStore) set(due int64, ref string, context interface{}, schedule *Schedule) error {
+	atomic.AddUint64(&r.stats.setCounter, 1)
+	due = r.adjustDue(due, context)
 
-	arecord := AlarmRecord{AlarmDue: due, ContextRef: ckey}
-	_, err = kv.MustUpsert(r.bucket, akey, arecord, 0)
+	kv := r.kv
+	ckey := r.kvLocatorContext(ref)
+	akey, seq, _, err := r.addAlarm(due, ckey)
 	if err != nil {
 		return err
 	}
 
-	crecord := ContextRecord{Context: context, AlarmRef: akey}
+	crecord := ContextRecord{Context: context, AlarmRef: akey, Schedule: schedule}
 	_, err = kv.MustUpsert(r.bucket, ckey, crecord, 0)
 	if err != nil {
 		return err
@@ -208,10 +241,78 @@ func (r *TimerStore) Set(due int64, ref string, context interface{}) error {
 	return nil
 }
 
+func (r *TimerStore) adjustDue(due int64, context interface{}) int64 {
+	now := time.Now().Unix()
+	if due-now <= r.resolution {
+		atomic.AddUint64(&r.stats.timerInPastCounter, 1)
+		logging.Debugf("%v Moving too close/past timer to next period: %v context %ru", r.log, formatInt(due), context)
+		due = now + r.resolution
+	}
+	return r.roundUp(due)
+}
+
+func (r *TimerStore) addAlarm(due int64, ckey string) (akey string, seq int64, acas gocb.Cas, err error) {
+	kv := r.kv
+	pos := r.kvLocatorRoot(due)
+	seq, _, err = kv.MustCounter(r.bucket, pos, 1, init_seq, 0)
+	if err != nil {
+		return
+	}
+
+	r.countRow(due, seq)
+	akey = r.kvLocatorAlarm(due, seq)
+	arecord := AlarmRecord{AlarmDue: due, ContextRef: ckey}
+	acas, err = kv.MustUpsert(r.bucket, akey, arecord, 0)
+	return
+}
+
+// Reschedule inserts the next alarm of a recurring timer that is being fired, and points
+// its context to it, so that the series survives deletion of the fired alarm. Returns
+// false for one-shot timers, and for those cancelled or overridden since they were scanned.
+func (r *TimerStore) Reschedule(entry *TimerEntry) (bool, error) {
+	if entry.Schedule == nil {
+		return false, nil
+	}
+
+	atomic.AddUint64(&r.stats.rescheduleCounter, 1)
+	due, err := entry.Schedule.Next(entry.AlarmDue, time.Now().Unix())
+	if err != nil {
+		logging.Errorf("%v Unable to compute next firing of %ru, ending the series, err: %v", r.log, *entry, err)
+		return false, nil
+	}
+	due = r.adjustDue(due, entry.Context)
+
+	kv := r.kv
+	akey, seq, acas, err := r.addAlarm(due, entry.ContextRef)
+	if err != nil {
+		return false, err
+	}
+
+	crecord := ContextRecord{Context: entry.Context, AlarmRef: akey, Schedule: entry.Schedule}
+	_, absent, mismatch, err := kv.MustReplace(r.bucket, entry.ContextRef, crecord, entry.ctxCas, 0)
+	if err != nil {
+		return false, err
+	}
+	if absent || mismatch {
+		logging.Debugf("%v Recurring timer %ru was cancelled or overridden while firing", r.log, *entry)
+		if _, _, _, err = kv.MustRemove(r.bucket, akey, acas); err != nil {
+			return false, err
+		}
+		return false, nil
+	}
+
+	logging.Tracef("%v Rescheduled timer %v to %v seq %v", r.log, entry.ContextRef, formatInt(due), seq)
+	entry.rescheduled = true
+	r.expandSpan(due)
+	atomic.AddUint64(&r.stats.rescheduleSuccessCounter, 1)
+	return true, nil
+}
+
 func (r *TimerStore) Delete(entry *TimerEntry) error {
 	logging.Tracef("%v Deleting timer %+v", r.log, entry)
 	atomic.AddUint64(&r.stats.delCounter, 1)
-	kv := Pool(r.connstr)
+	r.Fired(entry)
+	kv := r.kv
 
 	_, absent, mismatch, err := kv.MustRemove(r.bucket, entry.AlarmRef, entry.alrCas)
 	if err != nil {
@@ -223,6 +324,10 @@ func (r *TimerStore) Delete(entry *TimerEntry) error {
 
 	atomic.AddUint64(&r.stats.delSuccessCounter, 1)
 
+	if entry.rescheduled {
+		return nil
+	}
+
 	_, absent, mismatch, err = kv.MustRemove(r.bucket, entry.ContextRef, entry.ctxCas)
 	if err != nil {
 		return err
@@ -241,20 +346,25 @@ func (r *TimerStore) Delete(entry *TimerEntry) error {
 
 func (r *TimerStore) GetToken(e *TimerEntry) *DeleteToken {
 	util.Assert(func() bool { return e.ctxCas != 0 && e.alrCas != 0 })
-	return &DeleteToken{
+	token := &DeleteToken{
 		Bucket:     r.bucket,
 		ContextKey: e.ContextRef,
 		ContextCas: uint64(e.ctxCas),
 		AlarmKey:   e.AlarmRef,
 		AlarmCas:   uint64(e.alrCas),
 	}
+
+	if e.rescheduled {
+		token.ContextKey, token.ContextCas = "", 0
+	}
+	return token
 }
 
 func (r *TimerStore) Cancel(ref string) error {
 	atomic.AddUint64(&r.stats.cancelCounter, 1)
 	logging.Tracef("%v Cancelling timer ref %ru", r.log, ref)
 
-	kv := Pool(r.connstr)
+	kv := r.kv
 	cpos := r.kvLocatorContext(ref)
 
 	crecord := ContextRecord{}
@@ -305,9 +415,15 @@ func (r *TimerStore) Partition() int {
 	return r.partn
 }
 
+// Resolution is width in seconds of the rows alarms are bucketed into, timers fire
+// no earlier than their due time and at most this much later
+func (r *TimerStore) Resolution() int64 {
+	return r.resolution
+}
+
 func (r *TimerStore) ScanDue() *TimerIter {
 	span := r.readSpan()
-	now := roundDown(time.Now().Unix())
+	now := r.roundDown(time.Now().Unix())
 
 	atomic.AddUint64(&r.stats.scanDueCounter, 1)
 	if span.Start > now {
@@ -347,6 +463,9 @@ func (r *TimerIter) ScanNext() (*TimerEntry, error) {
 			return nil, err
 		}
 		if found {
+			if !r.readOnly {
+				r.store.trackOverdue(r.entry)
+			}
 			return r.entry, nil
 		}
 
@@ -360,6 +479,128 @@ func (r *TimerIter) ScanNext() (*TimerEntry, error) {
 	}
 }
 
+// trackOverdue accounts a timer handed out by a due scan as overdue, until it fires
+func (r *TimerStore) trackOverdue(entry *TimerEntry) {
+	r.backlog.Lock()
+	defer r.backlog.Unlock()
+	if r.backlog.overdue == nil {
+		r.backlog.overdue = make(map[string]int64)
+	}
+	r.backlog.overdue[entry.AlarmRef] = entry.AlarmDue
+}
+
+// countRow accounts alarms of a row not scanned yet from its counter
+func (r *TimerStore) countRow(due, seqEnd int64) {
+	r.backlog.Lock()
+	defer r.backlog.Unlock()
+	if r.backlog.rows == nil {
+		r.backlog.rows = make(map[int64]int64)
+	}
+	r.backlog.rows[due] = seqEnd - init_seq + 1
+}
+
+// forgetRow drops a row from pending timers, once scanned or found gone
+func (r *TimerStore) forgetRow(due int64) {
+	r.backlog.Lock()
+	defer r.backlog.Unlock()
+	delete(r.backlog.rows, due)
+}
+
+// countPending advances the census of pending timers by up to censusRows rows of the span,
+// recounting them from their counters, and starts over once it passes the end of the span.
+// Only rows not due yet are walked, as due scans account the others.
+func (r *TimerStore) countPending() {
+	span := r.readSpan()
+	now := r.roundDown(time.Now().Unix())
+
+	r.backlog.Lock()
+	row := r.backlog.census
+	r.backlog.Unlock()
+	if row < now {
+		row = now
+	}
+
+	for lookups := 0; lookups < censusRows; lookups++ {
+		if row >= span.Stop {
+			row = 0
+			break
+		}
+		row += r.resolution
+
+		seqEnd := int64(0)
+		_, absent, err := r.kv.Get(r.bucket, r.kvLocatorRoot(row), &seqEnd)
+		if err != nil {
+			logging.Debugf("%v Pending census failed reading row %v: %v", r.log, row, err)
+			return
+		}
+		if absent {
+			r.forgetRow(row)
+		} else {
+			r.countRow(row, seqEnd)
+		}
+	}
+
+	r.backlog.Lock()
+	r.backlog.census = row
+	r.backlog.Unlock()
+}
+
+// Dropped accounts a timer handed out by a due scan that won't be fired, e.g. when it
+// couldn't be queued for execution
+func (r *TimerStore) Dropped(entry *TimerEntry) {
+	r.backlog.Lock()
+	defer r.backlog.Unlock()
+	delete(r.backlog.overdue, entry.AlarmRef)
+}
+
+// Fired accounts a timer handed out by a due scan as fired, to be called once it's sent for
+// execution. Delete calls it as well, hence callers deleting fired timers needn't.
+func (r *TimerStore) Fired(entry *TimerEntry) {
+	lag := time.Now().Unix() - entry.AlarmDue
+	if lag < 0 {
+		lag = 0
+	}
+
+	r.backlog.Lock()
+	defer r.backlog.Unlock()
+	delete(r.backlog.overdue, entry.AlarmRef)
+	r.backlog.fireLag = lag
+}
+
+// BacklogStats returns gauges of timers overdue in the partition, i.e. found due by scans
+// but yet to fire: their count, due time of the oldest one, lag, which is how long the oldest
+// one has been overdue for, and how late the latest fired one was. Alongside is the count of
+// all pending timers, overdue or not, where rows not scanned yet count every alarm allotted
+// by their counter, including ones since cancelled or superseded, until the row is due.
+func (r *TimerStore) BacklogStats() map[string]int64 {
+	r.backlog.Lock()
+	defer r.backlog.Unlock()
+
+	oldestDue := int64(0)
+	for _, due := range r.backlog.overdue {
+		if oldestDue == 0 || due < oldestDue {
+			oldestDue = due
+		}
+	}
+
+	pending := int64(len(r.backlog.overdue))
+	for _, count := range r.backlog.rows {
+		pending += count
+	}
+
+	stats := make(map[string]int64)
+	stats["pending_alarms"] = pending
+	stats["overdue_alarms"] = int64(len(r.backlog.overdue))
+	stats["oldest_overdue"] = oldestDue
+	stats["fire_lag"] = r.backlog.fireLag
+
+	stats["lag"] = 0
+	if now := time.Now().Unix(); oldestDue > 0 && now > oldestDue {
+		stats["lag"] = now - oldestDue
+	}
+	return stats
+}
+
 func (t *DeleteToken) Size() uint64 {
 	return uint64(unsafe.Sizeof(*t)) + uint64(len(t.ContextKey)) +
 		uint64(unsafe.Sizeof(t.AlarmCas)) + uint64(len(t.AlarmKey)) +
@@ -369,13 +610,16 @@ func (t *DeleteToken) Size() uint64 {
 func (r *TimerIter) nextRow() (bool, error) {
 	atomic.AddUint64(&r.store.stats.scanRowCounter, 1)
 	logging.Tracef("%v Looking for row after %+v", r.store.log, r.row)
-	kv := Pool(r.store.connstr)
+	kv := r.store.kv
 
 	r.col = nil
 	r.entry = nil
 
 	for r.row.current < r.row.stop {
-		r.row.current += Resolution
+		r.row.current += r.store.resolution
+		if !r.readOnly {
+			r.store.forgetRow(r.row.current)
+		}
 
 		pos := r.store.kvLocatorRoot(r.row.current)
 		seq_end := int64(0)
@@ -390,7 +634,9 @@ func (r *TimerIter) nextRow() (bool, error) {
 			return true, nil
 		}
 		// below handles shrink when row counter never existed. all others cases go to nextColumn
-		r.store.shrinkSpan(r.row.current)
+		if !r.readOnly {
+			r.store.shrinkSpan(r.row.current)
+		}
 	}
 
 	logging.Tracef("%v Found no more rows looking until %v", r.store.log, r.row.stop)
@@ -406,14 +652,16 @@ func (r *TimerIter) nextColumn() (bool, error) {
 		return false, nil
 	}
 
-	kv := Pool(r.store.connstr)
-	alarm := AlarmRecord{}
-	context := ContextRecord{}
+	kv := r.store.kv
 
 	for r.col.current <= r.col.stop {
 		current := r.col.current
 		r.col.current++
 
+		// Fresh records each time, as optional fields absent in the document are left as is
+		alarm := AlarmRecord{}
+		context := ContextRecord{}
+
 		key := r.store.kvLocatorAlarm(r.row.current, current)
 
 		atomic.AddUint64(&r.store.stats.scanColumnLookupCounter, 1)
@@ -431,6 +679,9 @@ func (r *TimerIter) nextColumn() (bool, error) {
 		if err != nil {
 			return false, err
 		}
+		if (absent || context.AlarmRef != key) && r.readOnly {
+			continue
+		}
 		if absent || context.AlarmRef != key {
 			logging.Debugf("%v Alarm canceled or superseded %v by context %ru, deleting it", r.store.log, alarm, context)
 			_, absent, mismatch, err := kv.MustRemove(r.store.bucket, key, acas)
@@ -444,7 +695,7 @@ func (r *TimerIter) nextColumn() (bool, error) {
 		}
 
 		r.entry = &TimerEntry{AlarmRecord: alarm, ContextRecord: context, alarmSeq: current, ctxCas: ccas, alrCas: acas}
-		if r.entry.AlarmDue > time.Now().Unix() {
+		if r.entry.AlarmDue > time.Now().Unix() && !r.readOnly {
 			atomic.AddUint64(&r.store.stats.timerInFutureFiredCounter, 1)
 		}
 
@@ -454,7 +705,7 @@ func (r *TimerIter) nextColumn() (bool, error) {
 
 	// row counter exists and but has no timers. shrink logic depends on all chains reducing to this eventually
 	logging.Tracef("%v Column scan finished for %+v at %+v", r.store.log, r, *r.col)
-	if r.col.empty == true && r.col.topCas != 0 {
+	if r.col.empty == true && r.col.topCas != 0 && !r.readOnly {
 		logging.Debugf("%v Row %v was empty, so removing counter", r.store.log, r.col.topKey)
 		_, absent, mismatch, err := kv.MustRemove(r.store.bucket, r.col.topKey, r.col.topCas)
 		if err != nil {
@@ -482,7 +733,7 @@ func (r *TimerStore) readSpan() Span {
 func (r *TimerStore) expandSpan(point int64) {
 	r.span.lock.Lock()
 	defer r.span.lock.Unlock()
-	util.Assert(func() bool { return point >= roundDown(time.Now().Unix()) })
+	util.Assert(func() bool { return point >= r.roundDown(time.Now().Unix()) })
 
 	if r.span.Start > point {
 		logging.Tracef("Expanding span start to %v", r.span)
@@ -499,7 +750,7 @@ func (r *TimerStore) expandSpan(point int64) {
 func (r *TimerStore) shrinkSpan(start int64) {
 	r.span.lock.Lock()
 	defer r.span.lock.Unlock()
-	util.Assert(func() bool { return start <= roundDown(time.Now().Unix()) })
+	util.Assert(func() bool { return start <= r.roundDown(time.Now().Unix()) })
 
 	if r.span.Start < start {
 		r.span.Start = start
@@ -520,7 +771,7 @@ func (r *TimerStore) syncSpan() error {
 	}
 
 	r.span.dirty = false
-	kv := Pool(r.connstr)
+	kv := r.kv
 	pos := r.kvLocatorSpan()
 	extspan := Span{}
 
@@ -535,12 +786,23 @@ func (r *TimerStore) syncSpan() error {
 	// new, not on disk, not on node
 	case absent && r.span.empty:
 		now := time.Now().Unix()
-		r.span.Span = Span{Start: roundDown(now), Stop: roundUp(now)}
-		wcas, mismatch, err := kv.MustInsert(r.bucket, pos, r.span.Span, 0)
-		if err != nil || mismatch {
-			logging.Debugf("%v Error initializing span %+v: mismatch=%v err=%v", r.log, r.span, mismatch, err)
+		r.span.Span = Span{Start: r.roundDown(now), Stop: r.roundUp(now), Resolution: r.resolution}
+		wcas, exists, err := kv.MustInsert(r.bucket, pos, r.span.Span, 0)
+		if err != nil {
+			logging.Debugf("%v Error initializing span %+v: err=%v", r.log, r.span, err)
 			return err
 		}
+		if exists {
+			// Created concurrently, so adopt it in place of the one just made up
+			logging.Debugf("%v Span initialized concurrently, reading it instead of %+v", r.log, r.span)
+			rcas, absent, err = kv.MustGet(r.bucket, pos, &extspan)
+			if err != nil || absent {
+				r.span.dirty = true
+				return err
+			}
+			r.adoptSpan(extspan, rcas)
+			return nil
+		}
 		r.span.spanCas = wcas
 		r.span.empty = false
 		logging.Tracef("%v Span initialized as %+v", r.log, r.span)
@@ -548,21 +810,19 @@ func (r *TimerStore) syncSpan() error {
 
 	// new, not persisted, but we have data locally
 	case absent && !r.span.empty:
-		wcas, mismatch, err := kv.MustInsert(r.bucket, pos, r.span.Span, 0)
-		if err != nil || mismatch {
-			logging.Debugf("%v Error initializing span %+v: mismatch=%v err=%v", r.log, r.span, mismatch, err)
+		wcas, exists, err := kv.MustInsert(r.bucket, pos, r.span.Span, 0)
+		if err != nil || exists {
+			logging.Debugf("%v Error initializing span %+v: exists=%v err=%v", r.log, r.span, exists, err)
+			r.span.dirty = true
 			return err
 		}
 		r.span.spanCas = wcas
 		logging.Tracef("%v Span created as %+v", r.log, r.span)
 		return nil
 
-	// we have no data, but some data has been persisted earlier
+	// we have no data, but some data has been persisted earlier, whose resolution prevails
 	case r.span.empty:
-		r.span.empty = false
-		r.span.Span = extspan
-		r.span.spanCas = rcas
-		logging.Tracef("%v Span read and initialized to %+v", r.log, r.span)
+		r.adoptSpan(extspan, rcas)
 		return nil
 	}
 
@@ -580,6 +840,7 @@ func (r *TimerStore) syncSpan() error {
 		wcas, absent, mismatch, err := kv.MustReplace(r.bucket, pos, r.span.Span, rcas, 0)
 		if err != nil || absent || mismatch {
 			logging.Debugf("%v Overwriting span %+v failed: absent=%v mismatch=%v err=%v", r.log, r.span, absent, mismatch, err)
+			r.span.dirty = true
 			return err
 		}
 		r.span.spanCas = wcas
@@ -589,18 +850,19 @@ func (r *TimerStore) syncSpan() error {
 	// Merge conflict
 	atomic.AddUint64(&r.stats.spanCasMismatchCounter, 1)
 	if r.span.Start > extspan.Start {
-		logging.Debugf("%v Span conflict external write, moving Start: span=%+v extspan=%+v", r.span, extspan)
+		logging.Debugf("%v Span conflict external write, moving Start: span=%+v extspan=%+v", r.log, r.span, extspan)
 		atomic.AddUint64(&r.stats.spanStartChangeCounter, 1)
 		r.span.Start = extspan.Start
 	}
 	if r.span.Stop < extspan.Stop {
-		logging.Debugf("%v Span conflict external write, moving Stop: span=%+v extspan=%+v", r.span, extspan)
+		logging.Debugf("%v Span conflict external write, moving Stop: span=%+v extspan=%+v", r.log, r.span, extspan)
 		atomic.AddUint64(&r.stats.spanStopChangeCounter, 1)
 		r.span.Stop = extspan.Stop
 	}
 	wcas, absent, mismatch, err := kv.MustReplace(r.bucket, pos, r.span.Span, rcas, 0)
 	if err != nil || absent || mismatch {
 		logging.Debugf("%v Overwriting span %+v failed: absent=%v mismatch=%v err=%v", r.log, r.span, absent, mismatch, err)
+		r.span.dirty = true
 		return err
 	}
 	r.span.spanCas = wcas
@@ -608,6 +870,24 @@ func (r *TimerStore) syncSpan() error {
 	return nil
 }
 
+// adoptSpan takes over a persisted span, to be called with span lock held. Resolution of the
+// persisted span overrides the configured one, as its alarms are bucketed by it.
+func (r *TimerStore) adoptSpan(extspan Span, rcas gocb.Cas) {
+	r.span.empty = false
+	r.span.Span = extspan
+	r.span.spanCas = rcas
+	if r.span.Resolution == 0 {
+		r.span.Resolution = DefaultResolution
+		r.span.dirty = true
+	}
+	if r.resolution != r.span.Resolution {
+		logging.Warnf("%v Using resolution %vs of persisted timers instead of configured %vs until function is undeployed",
+			r.log, r.span.Resolution, r.resolution)
+	}
+	r.resolution = r.span.Resolution
+	logging.Tracef("%v Span read and initialized to %+v", r.log, r.span)
+}
+
 func (r *storeMap) syncRoutine() {
 	for {
 		dirty := make([]*TimerStore, 0)
@@ -621,18 +901,39 @@ func (r *storeMap) syncRoutine() {
 		for _, store := range dirty {
 			store.syncSpan()
 		}
-		time.Sleep(time.Duration(Resolution) * time.Second)
+		time.Sleep(time.Duration(DefaultResolution) * time.Second)
+	}
+}
+
+func (r *storeMap) censusRoutine() {
+	for {
+		owned := make([]*TimerStore, 0)
+		r.lock.RLock()
+		for _, store := range r.entries {
+			owned = append(owned, store)
+		}
+		r.lock.RUnlock()
+		for _, store := range owned {
+			store.countPending()
+		}
+		time.Sleep(time.Duration(DefaultResolution) * time.Second)
 	}
 }
 
-func newTimerStore(uid string, partn int, connstr string, bucket string) (*TimerStore, error) {
+func newTimerStore(uid string, partn int, connstr string, bucket string, resolution int64) (*TimerStore, error) {
+	if resolution <= 0 || resolution > MaxResolution {
+		return nil, fmt.Errorf("timer resolution: %d should be between 1 and %d seconds", resolution, MaxResolution)
+	}
+
 	timerstore := TimerStore{
-		connstr: connstr,
-		bucket:  bucket,
-		uid:     uid,
-		partn:   partn,
-		log:     fmt.Sprintf("timerstore:%v:%v", uid, partn),
-		span:    storeSpan{empty: true, dirty: false},
+		connstr:    connstr,
+		bucket:     bucket,
+		uid:        uid,
+		partn:      partn,
+		log:        fmt.Sprintf("timerstore:%v:%v", uid, partn),
+		span:       storeSpan{empty: true, dirty: false},
+		resolution: resolution,
+		kv:         getKV(connstr),
 	}
 
 	err := timerstore.syncSpan()
@@ -640,7 +941,7 @@ func newTimerStore(uid string, partn int, connstr string, bucket string) (*Timer
 		return nil, err
 	}
 
-	logging.Tracef("%v Initialized timerdata store", timerstore.log)
+	logging.Tracef("%v Initialized timerdata store with resolution %v", timerstore.log, timerstore.resolution)
 	return &timerstore, nil
 }
 
@@ -709,19 +1010,19 @@ func newStores() *storeMap {
 		lock:    sync.RWMutex{},
 	}
 	go smap.syncRoutine()
+	go smap.censusRoutine()
 	return smap
 }
 
-func roundUp(val int64) int64 {
-	q := val / Resolution
-	r := val % Resolution
-	if r > 0 {
+func (r *TimerStore) roundUp(val int64) int64 {
+	q := val / r.resolution
+	if val%r.resolution > 0 {
 		q++
 	}
-	return q * Resolution
+	return q * r.resolution
 }
 
-func roundDown(val int64) int64 {
-	q := val / Resolution
-	return q * Resolution
+func (r *TimerStore) roundDown(val int64) int64 {
+	q := val / r.resolution
+	return q * r.resolution
 }
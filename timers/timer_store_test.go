@@ -0,0 +1,387 @@
+package timers
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testBucket = "meta"
+
+func newTestStore(t *testing.T, kv *MemKV) *TimerStore {
+	prev := SetKVProvider(func(string) KV { return kv })
+	defer SetKVProvider(prev)
+
+	store, err := newTimerStore("test", 0, "mem://", testBucket, 1)
+	if err != nil {
+		t.Fatalf("Unable to create store: %v", err)
+	}
+	return store
+}
+
+// onceHook runs fn ahead of the first op on key, ops issued by fn itself included
+func onceHook(kv *MemKV, op, key string, fn func()) {
+	fired := false
+	kv.SetHook(func(hop, bucket, hkey string) {
+		if fired || hop != op || hkey != key {
+			return
+		}
+		fired = true
+		fn()
+	})
+}
+
+func scanAll(t *testing.T, store *TimerStore) []*TimerEntry {
+	entries := make([]*TimerEntry, 0)
+	iter := store.ScanRange(0, 0)
+	for iter != nil {
+		entry, err := iter.ScanNext()
+		if err != nil {
+			t.Fatalf("Scan failed: %v", err)
+		}
+		if entry == nil {
+			break
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func alarmKeys(kv *MemKV) []string {
+	keys := make([]string, 0)
+	for _, key := range kv.Keys(testBucket) {
+		if strings.Contains(key, ":al:") {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+func TestMemKV(t *testing.T) {
+	kv := NewMemKV()
+
+	cas, exists, err := kv.Insert(testBucket, "doc", "a", 0)
+	if err != nil || exists || cas == 0 {
+		t.Fatalf("Insert failed: cas=%v exists=%v err=%v", cas, exists, err)
+	}
+	if _, exists, _ = kv.Insert(testBucket, "doc", "b", 0); !exists {
+		t.Errorf("Insert of present document should report exists")
+	}
+
+	ncas, absent, mismatch, err := kv.Replace(testBucket, "doc", "c", cas, 0)
+	if err != nil || absent || mismatch || ncas == cas {
+		t.Fatalf("Replace failed: cas=%v absent=%v mismatch=%v err=%v", ncas, absent, mismatch, err)
+	}
+	if _, _, mismatch, _ = kv.Replace(testBucket, "doc", "d", cas, 0); !mismatch {
+		t.Errorf("Replace with stale cas should report mismatch")
+	}
+	if _, _, mismatch, _ = kv.Remove(testBucket, "doc", cas); !mismatch {
+		t.Errorf("Remove with stale cas should report mismatch")
+	}
+
+	var val string
+	if rcas, _, _ := kv.Get(testBucket, "doc", &val); rcas != ncas || val != "c" {
+		t.Errorf("Get returned %v with cas %v, expected c with cas %v", val, rcas, ncas)
+	}
+
+	if _, absent, _, _ = kv.Remove(testBucket, "doc", ncas); absent {
+		t.Errorf("Remove with current cas failed")
+	}
+	if _, absent, _ = kv.Get(testBucket, "doc", &val); !absent {
+		t.Errorf("Get of removed document should report absent")
+	}
+	if _, absent, _, _ = kv.Replace(testBucket, "doc", "e", 0, 0); !absent {
+		t.Errorf("Replace of removed document should report absent")
+	}
+
+	for _, expected := range []int64{128, 129, 130} {
+		if count, _, err := kv.Counter(testBucket, "counter", 1, 128, 0); err != nil || count != expected {
+			t.Errorf("Counter returned %v, err: %v, expected %v", count, err, expected)
+		}
+	}
+}
+
+func TestSpanInitRace(t *testing.T) {
+	kv := NewMemKV()
+	now := time.Now().Unix()
+	other := Span{Start: now - 100, Stop: now, Resolution: 1}
+
+	// Span gets created by another node between the lookup and insert of this one
+	onceHook(kv, "Insert", "test:tm:0:sp", func() {
+		kv.Insert(testBucket, "test:tm:0:sp", other, 0)
+	})
+	store := newTestStore(t, kv)
+
+	if store.span.empty || store.span.Span != other {
+		t.Fatalf("Store should adopt concurrently created span %+v, has %+v", other, store.span.Span)
+	}
+
+	if err := store.Set(now+100, "ref", "ctx"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if err := store.syncSpan(); err != nil || store.span.dirty {
+		t.Fatalf("Sync after adopting span failed: dirty=%v err=%v", store.span.dirty, err)
+	}
+
+	persisted := Span{}
+	kv.Get(testBucket, "test:tm:0:sp", &persisted)
+	if persisted.Start != other.Start || persisted.Stop != now+100 {
+		t.Errorf("Persisted span %+v should cover %v to %v", persisted, other.Start, now+100)
+	}
+}
+
+func TestSpanSyncMerge(t *testing.T) {
+	kv := NewMemKV()
+	first := newTestStore(t, kv)
+	second := newTestStore(t, kv)
+
+	now := time.Now().Unix()
+	if err := first.Set(now+200, "first", "ctx"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if err := second.Set(now+100, "second", "ctx"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+
+	for _, store := range []*TimerStore{first, second} {
+		if err := store.syncSpan(); err != nil {
+			t.Fatalf("Sync failed: %v", err)
+		}
+	}
+
+	persisted := Span{}
+	kv.Get(testBucket, "test:tm:0:sp", &persisted)
+	if persisted.Stop != now+200 {
+		t.Errorf("Persisted span %+v lost stop %v written by the other store", persisted, now+200)
+	}
+
+	// Losing a race for the span leaves it dirty, so the next sync retries
+	second.expandSpan(now + 300)
+	onceHook(kv, "Replace", "test:tm:0:sp", func() {
+		kv.Replace(testBucket, "test:tm:0:sp", persisted, 0, 0)
+	})
+	if err := second.syncSpan(); err != nil || !second.span.dirty {
+		t.Fatalf("Sync losing a race should leave span dirty: dirty=%v err=%v", second.span.dirty, err)
+	}
+	if err := second.syncSpan(); err != nil || second.span.dirty {
+		t.Fatalf("Retried sync failed: dirty=%v err=%v", second.span.dirty, err)
+	}
+	kv.Get(testBucket, "test:tm:0:sp", &persisted)
+	if persisted.Stop != now+300 {
+		t.Errorf("Persisted span %+v should stop at %v", persisted, now+300)
+	}
+}
+
+func TestScanAndCancel(t *testing.T) {
+	kv := NewMemKV()
+	store := newTestStore(t, kv)
+
+	now := time.Now().Unix()
+	for i, ref := range []string{"a", "b", "c"} {
+		if err := store.Set(now+int64(100*(i+1)), ref, ref); err != nil {
+			t.Fatalf("Set failed: %v", err)
+		}
+	}
+
+	// Overriding a timer leaves its earlier alarm behind, which scans must skip
+	if err := store.Set(now+400, "a", "a"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+
+	entries := scanAll(t, store)
+	if len(entries) != 3 {
+		t.Fatalf("Scan returned %d timers, expected 3", len(entries))
+	}
+	for i, expected := range []string{"b", "c", "a"} {
+		if entries[i].Context != expected || entries[i].AlarmDue != now+int64(100*(i+2)) {
+			t.Errorf("Scan returned %+v at %d, expected %v due %v", *entries[i], i, expected, now+int64(100*(i+2)))
+		}
+	}
+
+	if err := store.Cancel("b"); err != nil {
+		t.Fatalf("Cancel failed: %v", err)
+	}
+	if entries = scanAll(t, store); len(entries) != 2 || entries[0].Context != "c" {
+		t.Fatalf("Cancelled timer is still scanned: %+v", entries)
+	}
+
+	// Timer overridden after it was scanned must survive cancelling by the stale token
+	token := store.GetToken(entries[0])
+	if err := store.Set(now+500, "c", "c"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if err := store.CancelToken(token); err != nil {
+		t.Fatalf("Cancel by token failed: %v", err)
+	}
+
+	entries = scanAll(t, store)
+	if len(entries) != 2 || entries[1].Context != "c" || entries[1].AlarmDue != now+500 {
+		t.Fatalf("Overridden timer should remain due at %v: %+v", now+500, entries)
+	}
+	if len(alarmKeys(kv)) != 3 {
+		t.Errorf("Stale alarm of cancelled token should be removed, alarms: %v", alarmKeys(kv))
+	}
+}
+
+func TestReschedule(t *testing.T) {
+	kv := NewMemKV()
+	store := newTestStore(t, kv)
+
+	now := time.Now().Unix()
+	schedule := &Schedule{Interval: 60}
+	if err := store.SetRecurring(now+100, "rec", "ctx", schedule); err != nil {
+		t.Fatalf("SetRecurring failed: %v", err)
+	}
+
+	entries := scanAll(t, store)
+	if len(entries) != 1 {
+		t.Fatalf("Scan returned %d timers, expected 1", len(entries))
+	}
+
+	rescheduled, err := store.Reschedule(entries[0])
+	if err != nil || !rescheduled {
+		t.Fatalf("Reschedule failed: rescheduled=%v err=%v", rescheduled, err)
+	}
+	if err = store.Delete(entries[0]); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+
+	entries = scanAll(t, store)
+	if len(entries) != 1 || entries[0].AlarmDue != now+160 || entries[0].Schedule == nil {
+		t.Fatalf("Series should continue due at %v: %+v", now+160, entries)
+	}
+
+	// Series cancelled while its timer was firing must not be rescheduled
+	onceHook(kv, "Replace", entries[0].ContextRef, func() {
+		if err := store.Cancel("rec"); err != nil {
+			t.Errorf("Cancel failed: %v", err)
+		}
+	})
+	if rescheduled, err = store.Reschedule(entries[0]); err != nil || rescheduled {
+		t.Fatalf("Cancelled series was rescheduled: rescheduled=%v err=%v", rescheduled, err)
+	}
+
+	if entries = scanAll(t, store); len(entries) != 0 {
+		t.Errorf("Cancelled series is still scanned: %+v", entries)
+	}
+	if len(alarmKeys(kv)) != 0 {
+		t.Errorf("Alarms left behind by cancelled series: %v", alarmKeys(kv))
+	}
+}
+
+func TestBacklogStats(t *testing.T) {
+	kv := NewMemKV()
+	store := newTestStore(t, kv)
+
+	// Set only accepts future timers, so overdue ones are written as it would have
+	now := time.Now().Unix()
+	for _, due := range []int64{now - 30, now - 20} {
+		ckey := store.kvLocatorContext(strconv.FormatInt(due, 10))
+		akey, _, _, err := store.addAlarm(due, ckey)
+		if err != nil {
+			t.Fatalf("Adding alarm failed: %v", err)
+		}
+		if _, err = kv.Upsert(testBucket, ckey, ContextRecord{Context: "ctx", AlarmRef: akey}, 0); err != nil {
+			t.Fatalf("Adding context failed: %v", err)
+		}
+	}
+	store.span.Start = now - 31
+
+	iter := store.ScanDue()
+	entries := make([]*TimerEntry, 0)
+	for entry, err := iter.ScanNext(); entry != nil; entry, err = iter.ScanNext() {
+		if err != nil {
+			t.Fatalf("Scan failed: %v", err)
+		}
+		if due := store.BacklogStats()["oldest_overdue"]; due != now-30 {
+			t.Errorf("Oldest overdue during scan is %v, expected %v", due, now-30)
+		}
+		entries = append(entries, entry)
+	}
+
+	stats := store.BacklogStats()
+	if stats["overdue_alarms"] != 2 || stats["lag"] < 30 || stats["fire_lag"] != 0 {
+		t.Errorf("Backlog stats %v should report 2 timers lagging 30s, none fired", stats)
+	}
+
+	// Fired timers leave the backlog right away, ahead of the next scan
+	store.Fired(entries[0])
+	stats = store.BacklogStats()
+	if stats["overdue_alarms"] != 1 || stats["oldest_overdue"] != now-20 || stats["fire_lag"] < 30 {
+		t.Errorf("Backlog stats %v should report 1 timer due at %v, latest fired 30s late", stats, now-20)
+	}
+
+	// Scans past the rows of handed out timers leave them accounted until they fire
+	if entry, err := store.ScanDue().ScanNext(); err != nil || entry != nil {
+		t.Fatalf("Handed out timer scanned again: %+v, err: %v", entry, err)
+	}
+	if overdue := store.BacklogStats()["overdue_alarms"]; overdue != 1 {
+		t.Errorf("Overdue alarms after rescan are %v, expected 1", overdue)
+	}
+
+	for _, entry := range entries {
+		if err := store.Delete(entry); err != nil {
+			t.Fatalf("Delete failed: %v", err)
+		}
+	}
+
+	stats = store.BacklogStats()
+	if stats["overdue_alarms"] != 0 || stats["oldest_overdue"] != 0 || stats["lag"] != 0 {
+		t.Errorf("Backlog stats %v should be cleared once timers fired", stats)
+	}
+
+	if entry, err := store.ScanDue().ScanNext(); err != nil || entry != nil {
+		t.Fatalf("Deleted timer scanned again: %+v, err: %v", entry, err)
+	}
+}
+
+func TestPendingStats(t *testing.T) {
+	kv := NewMemKV()
+	store := newTestStore(t, kv)
+
+	now := time.Now().Unix()
+	for i, due := range []int64{now + 60, now + 60, now + 120} {
+		if err := store.Set(due, "ref"+strconv.Itoa(i), "ctx"); err != nil {
+			t.Fatalf("Set failed: %v", err)
+		}
+	}
+	if pending := store.BacklogStats()["pending_alarms"]; pending != 3 {
+		t.Errorf("Pending alarms after set are %v, expected 3", pending)
+	}
+
+	// Census recounts rows from their counters, as for timers set by an earlier owner
+	store.backlog.rows = nil
+	for round := 0; round < 10; round++ {
+		store.countPending()
+	}
+	if pending := store.BacklogStats()["pending_alarms"]; pending != 3 {
+		t.Errorf("Pending alarms after census are %v, expected 3", pending)
+	}
+
+	// A due row handed out by a scan moves to overdue, without being counted twice
+	due := now - 30
+	ckey := store.kvLocatorContext("overdue")
+	akey, _, _, err := store.addAlarm(due, ckey)
+	if err != nil {
+		t.Fatalf("Adding alarm failed: %v", err)
+	}
+	if _, err = kv.Upsert(testBucket, ckey, ContextRecord{Context: "ctx", AlarmRef: akey}, 0); err != nil {
+		t.Fatalf("Adding context failed: %v", err)
+	}
+	store.span.Start = due - 1
+
+	entry, err := store.ScanDue().ScanNext()
+	if err != nil || entry == nil {
+		t.Fatalf("Scan found no due timer: %+v, err: %v", entry, err)
+	}
+	stats := store.BacklogStats()
+	if stats["pending_alarms"] != 4 || stats["overdue_alarms"] != 1 {
+		t.Errorf("Backlog stats %v should report 4 pending timers, 1 overdue", stats)
+	}
+
+	store.Fired(entry)
+	if pending := store.BacklogStats()["pending_alarms"]; pending != 3 {
+		t.Errorf("Pending alarms after firing are %v, expected 3", pending)
+	}
+}
@@ -0,0 +1,146 @@
+package util
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/couchbase/cbauth/metakv"
+)
+
+const (
+	secretKeyPath = "/eventing/secretKey"
+	secretKeySize = 32
+	secretPrefix  = "enc:v1:"
+)
+
+var (
+	ErrMalformedSecret  = errors.New("malformed encrypted secret")
+	ErrCorruptSecretKey = errors.New("secret key in metakv is corrupt")
+)
+
+var secretKey struct {
+	sync.RWMutex
+	gcm cipher.AEAD
+}
+
+// InitSecretKey loads the key used to encrypt secrets of function definitions at rest. Key is
+// shared by all eventing nodes of the cluster through metakv, where the first node to start
+// generates it. Encrypting or decrypting secrets retries loading it if this fails.
+func InitSecretKey() error {
+	_, err := secretCipher()
+	return err
+}
+
+func secretCipher() (cipher.AEAD, error) {
+	secretKey.RLock()
+	gcm := secretKey.gcm
+	secretKey.RUnlock()
+
+	if gcm != nil {
+		return gcm, nil
+	}
+
+	secretKey.Lock()
+	defer secretKey.Unlock()
+
+	if secretKey.gcm != nil {
+		return secretKey.gcm, nil
+	}
+
+	key, err := loadSecretKey()
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err = cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	secretKey.gcm = gcm
+	return gcm, nil
+}
+
+func loadSecretKey() ([]byte, error) {
+	key, _, err := metakv.Get(secretKeyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if key == nil {
+		key = make([]byte, secretKeySize)
+		if _, err = io.ReadFull(rand.Reader, key); err != nil {
+			return nil, err
+		}
+
+		err = metakv.AddSensitive(secretKeyPath, key)
+		if err == metakv.ErrRevMismatch {
+			// Lost the race to another node generating the key
+			key, _, err = metakv.Get(secretKeyPath)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(key) != secretKeySize {
+		return nil, ErrCorruptSecretKey
+	}
+	return key, nil
+}
+
+// IsEncryptedSecret reports if value was produced by EncryptSecret
+func IsEncryptedSecret(value string) bool {
+	return strings.HasPrefix(value, secretPrefix)
+}
+
+// EncryptSecret seals value with AES-GCM under the cluster-wide key
+func EncryptSecret(value string) (string, error) {
+	gcm, err := secretCipher()
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+
+	sealed := gcm.Seal(nonce, nonce, []byte(value), nil)
+	return secretPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// DecryptSecret opens value sealed by EncryptSecret, values that aren't encrypted are returned as is
+func DecryptSecret(value string) (string, error) {
+	if !IsEncryptedSecret(value) {
+		return value, nil
+	}
+
+	gcm, err := secretCipher()
+	if err != nil {
+		return "", err
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, secretPrefix))
+	if err != nil || len(sealed) < gcm.NonceSize() {
+		return "", ErrMalformedSecret
+	}
+
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
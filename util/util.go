@@ -220,6 +220,30 @@ func EventingNodesAddresses(auth, hostaddress string) ([]string, error) {
 	return eventingNodes, nil
 }
 
+// EventingNodesServerGroups returns eventing node address to server group mapping
+func EventingNodesServerGroups(auth, hostaddress string) (map[string]string, error) {
+	logPrefix := "util::EventingNodesServerGroups"
+
+	cinfo, err := FetchNewClusterInfoCache(hostaddress)
+	if err != nil {
+		return nil, err
+	}
+
+	eventingAddrs := cinfo.GetNodesByServiceType(EventingAdminService)
+
+	nodeGroups := make(map[string]string)
+	for _, eventingAddr := range eventingAddrs {
+		addr, err := cinfo.GetServiceAddress(eventingAddr, EventingAdminService)
+		if err != nil {
+			logging.Errorf("%s Failed to get eventing node address, err: %v", logPrefix, err)
+			continue
+		}
+		nodeGroups[addr] = cinfo.GetServerGroup(eventingAddr)
+	}
+
+	return nodeGroups, nil
+}
+
 func CurrentEventingNodeAddress(auth, hostaddress string) (string, error) {
 	logPrefix := "util::CurrentEventingNodeAddress"
 
@@ -399,6 +423,44 @@ func GetEventProcessingStats(urlSuffix string, nodeAddrs []string) (map[string]i
 	return pStats, nil
 }
 
+// GetNodeWeight samples cpu count and dcp event processing rate per vbucket of an eventing node
+func GetNodeWeight(nodeAddr string) (*cm.NodeWeight, error) {
+	logPrefix := "util::GetNodeWeight"
+
+	netClient := NewClient(HTTPRequestTimeout)
+	weight := &cm.NodeWeight{}
+
+	var cpuCount float64
+	for urlSuffix, value := range map[string]*float64{
+		"/getCpuCount":                    &cpuCount,
+		"/getDcpEventsProcessedPSecPerVb": &weight.DcpEventsProcessedPSecPerVb,
+	} {
+		endpointURL := fmt.Sprintf("http://%s%s", nodeAddr, urlSuffix)
+
+		res, err := netClient.Get(endpointURL)
+		if err != nil {
+			logging.Errorf("%s Failed to gather node weight from url: %rs, err: %v", logPrefix, endpointURL, err)
+			return nil, err
+		}
+		defer res.Body.Close()
+
+		buf, err := ioutil.ReadAll(res.Body)
+		if err != nil {
+			logging.Errorf("%s Failed to read response body from url: %rs, err: %v", logPrefix, endpointURL, err)
+			return nil, err
+		}
+
+		*value, err = strconv.ParseFloat(strings.TrimSpace(string(buf)), 64)
+		if err != nil {
+			logging.Errorf("%s Failed to parse response from url: %rs, err: %v", logPrefix, endpointURL, err)
+			return nil, err
+		}
+	}
+
+	weight.CPUCount = int(cpuCount)
+	return weight, nil
+}
+
 func GetProgress(urlSuffix string, nodeAddrs []string) (*cm.RebalanceProgress, map[string]interface{}, map[string]error) {
 	logPrefix := "util::GetProgress"
 
@@ -1188,3 +1250,53 @@ func CPUCount(log bool) int {
 
 	return cpuCount
 }
+
+// ConstantsToJSON converts constant bindings, a list of alias and value pairs as saved in
+// function's deployment config or settings, to a JSON object keyed by alias. Encrypted values
+// of secret constants are decrypted, ones that fail to decrypt are bound to null so that the
+// function still deploys. Empty string is returned when there are no constants.
+func ConstantsToJSON(constants interface{}) (string, error) {
+	logPrefix := "util::ConstantsToJSON"
+
+	data, err := json.Marshal(constants)
+	if err != nil {
+		return "", err
+	}
+
+	var bindings []struct {
+		Alias  string          `json:"alias"`
+		Value  json.RawMessage `json:"value"`
+		Secret bool            `json:"secret"`
+	}
+	if err = json.Unmarshal(data, &bindings); err != nil {
+		return "", err
+	}
+
+	if len(bindings) == 0 {
+		return "", nil
+	}
+
+	object := make(map[string]json.RawMessage)
+	for _, b := range bindings {
+		object[b.Alias] = b.Value
+
+		var sealed string
+		if !b.Secret || json.Unmarshal(b.Value, &sealed) != nil || !IsEncryptedSecret(sealed) {
+			continue
+		}
+
+		value, err := DecryptSecret(sealed)
+		if err != nil || !json.Valid([]byte(value)) {
+			logging.Errorf("%s Failed to decrypt value of constant: %s, err: %v", logPrefix, b.Alias, err)
+			object[b.Alias] = json.RawMessage("null")
+			continue
+		}
+		object[b.Alias] = json.RawMessage(value)
+	}
+
+	data, err = json.Marshal(object)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
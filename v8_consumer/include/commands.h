@@ -37,11 +37,12 @@ enum v8_worker_opcode {
   oGetExecutionStats,
   oGetCompileInfo,
   oGetLcbExceptions,
+  oTestRun,
   oVersion,
   V8_Worker_Opcode_Unknown
 };
 
-enum dcp_opcode { oDelete, oMutation, DCP_Opcode_Unknown };
+enum dcp_opcode { oDelete, oMutation, oExpiry, DCP_Opcode_Unknown };
 
 enum filter_opcode {
   oVbFilter,
@@ -55,6 +56,7 @@ enum app_worker_setting_opcode {
   oWorkerThreadCount,
   oWorkerThreadMap,
   oTimerContextSize,
+  oConstants,
   App_Worker_Setting_Opcode_Unknown
 };
 
@@ -77,6 +79,7 @@ enum msg_type {
   mTimer_Response,
   mBucket_Ops_Response,
   mFilterAck,
+  mDead_Letter_Response,
   Msg_Unknown
 };
 
@@ -92,6 +95,7 @@ enum v8_worker_config_opcode {
   oCompileInfo,
   oQueueSize,
   oLcbExceptions,
+  oTestResult,
   V8_Worker_Config_Opcode_Unknown
 };
 
@@ -99,4 +103,6 @@ enum doc_timer_response_opcode { timerResponse };
 
 enum bucket_ops_response_opcode { checkpointResponse };
 
+enum dead_letter_response_opcode { deadLetterEntry };
+
 #endif
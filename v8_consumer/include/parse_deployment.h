@@ -23,9 +23,24 @@
 #include "../../gen/flatbuf/cfg_schema_generated.h"
 #include "../../gen/flatbuf/payload_generated.h"
 
+// Endpoint handler is allowed to reach through curl(), along with credentials
+// applied on its behalf
+typedef struct curl_binding_s {
+  std::string alias;
+  std::string base_url;
+  std::string auth_type;
+  std::string username;
+  std::string password;
+  std::string bearer_key;
+  std::vector<std::string> allowed_methods;
+} curl_binding_t;
+
 typedef struct deployment_config_s {
   std::string metadata_bucket;
   std::string source_bucket;
+  std::string dead_letter_bucket;
+  std::string constants; // JSON object of constant bindings keyed by alias
+  std::vector<curl_binding_t> curl_bindings;
   std::map<std::string, std::map<std::string, std::vector<std::string>>>
       component_configs;
 } deployment_config;
@@ -26,7 +26,7 @@ struct EpochInfo {
 };
 
 struct TimerInfo {
-  TimerInfo() : epoch(0), vb(0), seq_num(0) {}
+  TimerInfo() : epoch(0), vb(0), seq_num(0), interval(0) {}
 
   std::string ToJSON(v8::Isolate *isolate,
                      const v8::Local<v8::Context> &context);
@@ -37,6 +37,10 @@ struct TimerInfo {
   std::string callback;
   std::string reference;
   std::string context;
+
+  // Set for recurring timers, either one of them
+  std::string cron;
+  int64_t interval;
 };
 
 struct TimerEvent {
@@ -65,6 +69,8 @@ public:
 private:
   EpochInfo Epoch(const v8::Local<v8::Value> &date_val);
   bool ValidateArgs(const v8::FunctionCallbackInfo<v8::Value> &args);
+  bool ParseSchedule(const v8::Local<v8::Value> &options_val,
+                     TimerInfo &timer_info);
 
   v8::Isolate *isolate_;
   v8::Persistent<v8::Context> context_;
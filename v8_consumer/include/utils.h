@@ -166,6 +166,7 @@ v8::Local<v8::Array> v8Array(v8::Isolate *isolate,
 
 std::string JSONStringify(v8::Isolate *isolate,
                           const v8::Local<v8::Value> &object);
+std::string EscapeJSONString(const std::string &str);
 
 const char *ToCString(const v8::String::Utf8Value &value);
 
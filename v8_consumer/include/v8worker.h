@@ -26,6 +26,7 @@
 #include <list>
 #include <map>
 #include <regex>
+#include <set>
 #include <sstream>
 #include <string>
 #include <thread>
@@ -41,6 +42,7 @@
 #include "js_exception.h"
 #include "log.h"
 #include "n1ql.h"
+#include "parse_deployment.h"
 #include "queue.h"
 #include "utils.h"
 
@@ -69,6 +71,13 @@ typedef struct timer_msg_s {
   std::string timer_entry;
 } timer_msg_t;
 
+// Failed OnUpdate/OnDelete invocation, to be written to dead letter bucket
+typedef struct dead_letter_msg_s {
+  std::size_t GetSize() const { return entry.length(); }
+
+  std::string entry;
+} dead_letter_msg_t;
+
 // Header frame structure for messages from Go world
 typedef struct header_s {
   std::size_t GetSize() const {
@@ -112,9 +121,13 @@ typedef struct handler_config_s {
   std::string app_name;
   long curl_timeout;
   std::string dep_cfg;
+  std::string constants;
+  // Curl binding alias -> decrypted password and bearer key
+  std::map<std::string, std::pair<std::string, std::string>> curl_credentials;
   int execution_timeout;
   int lcb_inst_capacity;
   bool skip_lcb_bootstrap;
+  bool sandbox;
   int64_t timer_context_size;
   std::vector<std::string> handler_headers;
   std::vector<std::string> handler_footers;
@@ -127,7 +140,9 @@ enum RETURN_CODE {
   kFailedInitBucketHandle,
   kOnUpdateCallFail,
   kOnDeleteCallFail,
-  kToLocalFailed
+  kToLocalFailed,
+  kOnExpiryCallFail,
+  kInvalidVbucket
 };
 
 class Bucket;
@@ -145,6 +160,8 @@ extern std::atomic<int64_t> on_update_success;
 extern std::atomic<int64_t> on_update_failure;
 extern std::atomic<int64_t> on_delete_success;
 extern std::atomic<int64_t> on_delete_failure;
+extern std::atomic<int64_t> on_expiry_success;
+extern std::atomic<int64_t> on_expiry_failure;
 
 extern std::atomic<int64_t> timer_create_failure;
 
@@ -155,10 +172,12 @@ extern std::atomic<int64_t> messages_processed_counter;
 // DCP or Timer event counter
 extern std::atomic<int64_t> dcp_delete_msg_counter;
 extern std::atomic<int64_t> dcp_mutation_msg_counter;
+extern std::atomic<int64_t> dcp_expiry_msg_counter;
 extern std::atomic<int64_t> timer_msg_counter;
 
 extern std::atomic<int64_t> enqueued_dcp_delete_msg_counter;
 extern std::atomic<int64_t> enqueued_dcp_mutation_msg_counter;
+extern std::atomic<int64_t> enqueued_dcp_expiry_msg_counter;
 extern std::atomic<int64_t> enqueued_timer_msg_counter;
 
 class V8Worker {
@@ -201,10 +220,12 @@ public:
   void RouteMessage();
 
   int SendUpdate(std::string value, std::string meta, int vb_no, int64_t seq_no,
-                 std::string doc_type);
+                 std::string doc_type, bool replay = false);
   int SendDelete(std::string meta, int vb_no, int64_t seq_no);
+  int SendExpiry(std::string meta, int vb_no, int64_t seq_no);
   void SendTimer(const TimerEvent &event);
   std::string CompileHandler(std::string handler);
+  std::string TestRun(const std::string &request_id);
   CodeVersion IdentifyVersion(std::string handler);
 
   void StartDebugger();
@@ -223,6 +244,9 @@ public:
 
   void GetBucketOpsMessages(std::vector<uv_buf_t> &messages);
 
+  void GetDeadLetterMessages(std::vector<uv_buf_t> &messages,
+                             size_t window_size);
+
   void SetBucketopFilter(int vb_no, int64_t seq_no);
 
   void SetTimerFilter(int vb_no);
@@ -236,6 +260,8 @@ public:
   void ResetCheckpoint(int vb_no);
 
   int ParseMetadata(const std::string &metadata, int &vb_no, int64_t &seq_no);
+  int ParseMetadata(const std::string &metadata, int &vb_no, int64_t &seq_no,
+                    bool &replay);
 
   void SetThreadExitFlag();
 
@@ -243,12 +269,16 @@ public:
   v8::Persistent<v8::Context> context_;
   v8::Persistent<v8::Function> on_update_;
   v8::Persistent<v8::Function> on_delete_;
+  v8::Persistent<v8::Function> on_expiry_;
 
   std::string app_name_;
   std::string script_to_execute_;
   std::string cb_source_bucket_;
   int64_t max_task_duration_;
 
+  // Endpoints curl() is confined to, unrestricted when empty
+  std::vector<curl_binding_t> curl_bindings_;
+
   server_settings_t *settings_;
 
   volatile bool execute_flag_;
@@ -262,6 +292,7 @@ public:
   std::thread processing_thr_;
   std::thread *terminator_thr_;
   Queue<timer_msg_t> *timer_queue_;
+  Queue<dead_letter_msg_t> *dead_letter_queue_;
   Queue<worker_msg_t> *worker_queue_;
 
   ConnectionPool *conn_pool_;
@@ -277,6 +308,32 @@ private:
   std::vector<uv_buf_t> BuildResponse(const std::string &payload,
                                       int8_t msg_type, int8_t response_opcode);
   bool ExecuteScript(const v8::Local<v8::String> &script);
+  void AddDeadLetter(const std::string &meta, int vb_no, int64_t seq_no,
+                     const std::string &event, v8::TryCatch &try_catch);
+  void GetFailureDetails(v8::TryCatch &try_catch, std::string &category,
+                         std::string &exception);
+  bool InstallSandbox();
+  bool InstallConstants(const std::string &constants);
+  bool DeepFreeze(const v8::Local<v8::Context> &context,
+                  const v8::Local<v8::Value> &value);
+  static void GetConstant(v8::Local<v8::Name> name,
+                          const v8::PropertyCallbackInfo<v8::Value> &info);
+  void SetSandboxException(const std::string &exception);
+
+  bool dead_letter_enabled_;
+
+  // Test invocation related fields
+  bool sandbox_;
+  std::string sandbox_exception_;
+  std::atomic<int64_t> sandbox_events_enqueued_;
+  std::atomic<int64_t> sandbox_events_routed_;
+  // alias -> [bucket name, alias, access]
+  std::map<std::string, std::vector<std::string>> sandbox_bindings_;
+
+  // JSON object of constants exposed to handler as read-only globals
+  std::string constants_;
+  std::set<std::string> constant_aliases_;
+  v8::Persistent<v8::Object> constant_values_;
 
   std::string connstr_;
   std::string meta_connstr_;
@@ -387,7 +387,7 @@ void Bucket::BucketSet<v8::Local<v8::Name>>(
       UnwrapInternalField<bool>(info.Holder(), BLOCK_MUTATION_FIELD_NO);
   if (*block_mutation) {
     auto js_exception = UnwrapData(info.GetIsolate())->js_exception;
-    js_exception->Throw("Writing to source bucket is forbidden");
+    js_exception->Throw("Writing to read-only bucket binding is forbidden");
     ++bucket_op_exception_count;
     return;
   }
@@ -459,7 +459,7 @@ void Bucket::BucketDelete<v8::Local<v8::Name>>(
       UnwrapInternalField<bool>(info.Holder(), BLOCK_MUTATION_FIELD_NO);
   if (*block_mutation) {
     auto js_exception = UnwrapData(info.GetIsolate())->js_exception;
-    js_exception->Throw("Delete from source bucket is forbidden");
+    js_exception->Throw("Delete from read-only bucket binding is forbidden");
     ++bucket_op_exception_count;
     return;
   }
@@ -25,6 +25,7 @@ std::atomic<int64_t> e_v8_worker_lost = {0};
 std::atomic<int64_t> delete_events_lost = {0};
 std::atomic<int64_t> timer_events_lost = {0};
 std::atomic<int64_t> mutation_events_lost = {0};
+std::atomic<int64_t> expiry_events_lost = {0};
 extern std::atomic<int64_t> timer_context_size_exceeded_counter;
 extern std::atomic<int64_t> timer_alarm_delete_failure;
 extern std::atomic<int64_t> timer_context_delete_failure;
@@ -433,9 +434,20 @@ void AppWorker::RouteMessageWithResponse(header_t *parsed_header,
       handler_config->curl_timeout = long(payload->curl_timeout());
       handler_config->timer_context_size = payload->timer_context_size();
       handler_config->dep_cfg.assign(payload->depcfg()->str());
+      if (payload->constants() != nullptr) {
+        handler_config->constants.assign(payload->constants()->str());
+      }
+      if (payload->curl_credentials() != nullptr) {
+        for (const auto *credential : *payload->curl_credentials()) {
+          handler_config->curl_credentials[credential->alias()->str()] =
+              std::make_pair(credential->password()->str(),
+                             credential->bearer_key()->str());
+        }
+      }
       handler_config->execution_timeout = payload->execution_timeout();
       handler_config->lcb_inst_capacity = payload->lcb_inst_capacity();
       handler_config->skip_lcb_bootstrap = payload->skip_lcb_bootstrap();
+      handler_config->sandbox = payload->sandbox();
       handler_config->timer_context_size = payload->timer_context_size();
       handler_config->handler_headers =
           ToStringArray(payload->handler_headers());
@@ -555,6 +567,7 @@ void AppWorker::RouteMessageWithResponse(header_t *parsed_header,
       fstats << R"("timer_context_size_exceeded_counter": )"
              << timer_context_size_exceeded_counter << ",";
       fstats << R"("delete_events_lost": )" << delete_events_lost << ",";
+      fstats << R"("expiry_events_lost": )" << expiry_events_lost << ",";
       fstats << R"("timer_events_lost": )" << timer_events_lost << ",";
       fstats << R"("timestamp" : ")" << GetTimestampNow() << R"(")";
       fstats << "}";
@@ -571,15 +584,20 @@ void AppWorker::RouteMessageWithResponse(header_t *parsed_header,
       estats << on_update_success << R"(, "on_update_failure":)";
       estats << on_update_failure << R"(, "on_delete_success":)";
       estats << on_delete_success << R"(, "on_delete_failure":)";
-      estats << on_delete_failure << R"(, "timer_create_failure":)";
+      estats << on_delete_failure << R"(, "on_expiry_success":)";
+      estats << on_expiry_success << R"(, "on_expiry_failure":)";
+      estats << on_expiry_failure << R"(, "timer_create_failure":)";
       estats << timer_create_failure << R"(, "messages_parsed":)";
       estats << messages_parsed << R"(, "dcp_delete_msg_counter":)";
       estats << dcp_delete_msg_counter << R"(, "dcp_mutation_msg_counter":)";
-      estats << dcp_mutation_msg_counter << R"(, "timer_msg_counter":)";
+      estats << dcp_mutation_msg_counter << R"(, "dcp_expiry_msg_counter":)";
+      estats << dcp_expiry_msg_counter << R"(, "timer_msg_counter":)";
       estats << timer_msg_counter << R"(, "enqueued_dcp_delete_msg_counter":)";
       estats << enqueued_dcp_delete_msg_counter
              << R"(, "enqueued_dcp_mutation_msg_counter":)";
       estats << enqueued_dcp_mutation_msg_counter
+             << R"(, "enqueued_dcp_expiry_msg_counter":)";
+      estats << enqueued_dcp_expiry_msg_counter
              << R"(, "enqueued_timer_msg_counter":)";
       estats << enqueued_timer_msg_counter;
       estats << R"(, "timer_responses_sent":)";
@@ -620,6 +638,13 @@ void AppWorker::RouteMessageWithResponse(header_t *parsed_header,
       resp_msg_->opcode = oCompileInfo;
       msg_priority_ = true;
       break;
+    case oTestRun:
+      LOG(logDebug) << "Collecting result of handler test run" << std::endl;
+      resp_msg_->msg.assign(workers_[0]->TestRun(parsed_header->metadata));
+      resp_msg_->msg_type = mV8_Worker_Config;
+      resp_msg_->opcode = oTestResult;
+      msg_priority_ = true;
+      break;
     case oGetLcbExceptions:
       for (const auto &w : workers_) {
         w.second->ListLcbExceptions(agg_lcb_exceptions);
@@ -687,6 +712,17 @@ void AppWorker::RouteMessageWithResponse(header_t *parsed_header,
         ++mutation_events_lost;
       }
       break;
+    case oExpiry:
+      worker_index = partition_thr_map_[parsed_header->partition];
+      if (workers_[worker_index] != nullptr) {
+        enqueued_dcp_expiry_msg_counter++;
+        workers_[worker_index]->Enqueue(parsed_header, parsed_message);
+      } else {
+        LOG(logError) << "Expiry event lost: worker " << worker_index
+                      << " is null" << std::endl;
+        ++expiry_events_lost;
+      }
+      break;
     default:
       LOG(logError) << "Opcode " << getDCPOpcode(parsed_header->opcode)
                     << "is not implemented for eDCP" << std::endl;
@@ -796,6 +832,17 @@ void AppWorker::RouteMessageWithResponse(header_t *parsed_header,
                    << std::endl;
       msg_priority_ = true;
       break;
+    case oConstants:
+      // Each worker owns its isolate, hence the update is queued to all of
+      // them. Workers free the messages once done.
+      for (const auto &w : workers_) {
+        w.second->Enqueue(new header_t(*parsed_header),
+                          new message_t(*parsed_message));
+      }
+      LOG(logInfo) << "Queued constants update to " << workers_.size()
+                   << " workers" << std::endl;
+      msg_priority_ = true;
+      break;
     default:
       LOG(logError) << "Opcode "
                     << getAppWorkerSettingOpcode(parsed_header->opcode)
@@ -879,6 +926,21 @@ void AppWorker::WriteResponses() {
       }
     }
 
+    // Failed events to be written to dead letter bucket
+    for (const auto &w : workers_) {
+      std::vector<uv_buf_t> messages;
+      w.second->GetDeadLetterMessages(messages, batch_size);
+      if (messages.empty()) {
+        continue;
+      }
+
+      sleep = false;
+      WriteResponseWithRetry(feedback_conn_handle_, messages, batch_size);
+      for (auto &buf : messages) {
+        delete buf.base;
+      }
+    }
+
     if (sleep) {
       std::this_thread::sleep_for(std::chrono::milliseconds(100));
     }
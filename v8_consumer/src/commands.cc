@@ -50,6 +50,8 @@ v8_worker_opcode getV8WorkerOpcode(int8_t opcode) {
     return oGetCompileInfo;
   if (opcode == 11)
     return oGetLcbExceptions;
+  if (opcode == 12)
+    return oTestRun;
   return V8_Worker_Opcode_Unknown;
 }
 
@@ -58,6 +60,8 @@ dcp_opcode getDCPOpcode(int8_t opcode) {
     return oDelete;
   if (opcode == 2)
     return oMutation;
+  if (opcode == 3)
+    return oExpiry;
   return DCP_Opcode_Unknown;
 }
 
@@ -70,6 +74,8 @@ app_worker_setting_opcode getAppWorkerSettingOpcode(int8_t opcode) {
     return oWorkerThreadMap;
   if (opcode == 4)
     return oTimerContextSize;
+  if (opcode == 5)
+    return oConstants;
   return App_Worker_Setting_Opcode_Unknown;
 }
 
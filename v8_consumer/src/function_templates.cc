@@ -100,12 +100,75 @@ size_t WriteMemoryCallback(void *contents, size_t size, size_t nmemb,
   return realsize;
 }
 
+// Decodes percent escapes in str, malformed ones are left as they are
+static std::string PercentDecode(const std::string &str) {
+  std::string decoded;
+  for (std::string::size_type i = 0; i < str.size(); ++i) {
+    if (str[i] == '%' && i + 2 < str.size() && isxdigit(str[i + 1]) &&
+        isxdigit(str[i + 2])) {
+      decoded +=
+          static_cast<char>(std::stoi(str.substr(i + 1, 2), nullptr, 16));
+      i += 2;
+    } else {
+      decoded += str[i];
+    }
+  }
+  return decoded;
+}
+
+// Dot segments would let the path climb out of the base URL. Server decodes
+// the path before resolving them, so encoded ones like %2e%2e count as well.
+static bool HasDotSegments(const std::string &url) {
+  auto path = PercentDecode(url.substr(0, url.find_first_of("?#")));
+  std::replace(path.begin(), path.end(), '\\', '/');
+
+  auto ends_with = [&path](const std::string &suffix) {
+    return path.size() >= suffix.size() &&
+           path.compare(path.size() - suffix.size(), suffix.size(), suffix) ==
+               0;
+  };
+
+  return path.find("/../") != std::string::npos ||
+         path.find("/./") != std::string::npos || ends_with("/..") ||
+         ends_with("/.");
+}
+
+// Finds curl binding whose base URL covers the url. Base URL must be followed
+// by a path, query or fragment delimiter, so that a binding for example.com
+// doesn't admit example.com.evil.org or example.com@evil.org
+static const curl_binding_t *
+MatchCurlBinding(const std::vector<curl_binding_t> &bindings,
+                 const std::string &url) {
+  if (HasDotSegments(url)) {
+    return nullptr;
+  }
+
+  for (const auto &binding : bindings) {
+    const auto &base = binding.base_url;
+    if (base.empty() || url.compare(0, base.size(), base) != 0) {
+      continue;
+    }
+
+    if (url.size() == base.size() || base.back() == '/') {
+      return &binding;
+    }
+
+    auto next = url[base.size()];
+    if (next == '/' || next == '?' || next == '#') {
+      return &binding;
+    }
+  }
+
+  return nullptr;
+}
+
 void Curl(const v8::FunctionCallbackInfo<v8::Value> &args) {
   auto isolate = args.GetIsolate();
   v8::Locker locker(isolate);
   v8::HandleScope handle_scope(isolate);
 
-  std::string auth, data, http_method, mime_type, url, url_suffix;
+  std::string auth, auth_header, data, http_method, mime_type, url, url_suffix;
+  long auth_scheme = CURLAUTH_ANY;
   struct curl_slist *headers = nullptr;
   v8::String::Utf8Value u(args[0]);
 
@@ -202,12 +265,48 @@ void Curl(const v8::FunctionCallbackInfo<v8::Value> &args) {
   if (http_method.empty()) {
     http_method.assign("GET");
   }
+  std::transform(http_method.begin(), http_method.end(), http_method.begin(),
+                 ::toupper);
+
+  // When function has curl bindings, requests are confined to their base URLs
+  // and carry the binding's credentials instead of ones supplied by handler
+  const auto &bindings = UnwrapData(isolate)->v8worker->curl_bindings_;
+  if (!bindings.empty()) {
+    auto binding = MatchCurlBinding(bindings, url);
+    if (binding == nullptr) {
+      curl_slist_free_all(headers);
+      auto js_exception = UnwrapData(isolate)->js_exception;
+      js_exception->Throw("curl request to URL outside of curl bindings of "
+                          "the function is not permitted");
+      return;
+    }
+
+    const auto &methods = binding->allowed_methods;
+    if (std::find(methods.begin(), methods.end(), http_method) ==
+        methods.end()) {
+      curl_slist_free_all(headers);
+      auto js_exception = UnwrapData(isolate)->js_exception;
+      js_exception->Throw("HTTP method " + http_method +
+                          " is not permitted by curl binding " +
+                          binding->alias);
+      return;
+    }
+
+    auth.clear();
+    if (binding->auth_type == "basic" || binding->auth_type == "digest") {
+      auth_scheme = binding->auth_type == "basic" ? CURLAUTH_BASIC
+                                                  : CURLAUTH_DIGEST;
+      auth = binding->username + ":" + binding->password;
+    } else if (binding->auth_type == "bearer") {
+      auth_header = "Authorization: Bearer " + binding->bearer_key;
+    }
+  }
 
   CURLcode res;
   CURL *curl = UnwrapData(isolate)->curl_handle;
 
-  if ((strcmp(http_method.c_str(), "GET") == 0 ||
-       strcmp(http_method.c_str(), "POST") == 0) &&
+  if ((http_method == "GET" || http_method == "POST" || http_method == "PUT" ||
+       http_method == "DELETE") &&
       curl) {
     // Initialize common bootstrap code
     struct CurlResult chunk;
@@ -220,7 +319,7 @@ void Curl(const v8::FunctionCallbackInfo<v8::Value> &args) {
     curl_easy_setopt(curl, CURLOPT_WRITEFUNCTION, WriteMemoryCallback);
 
     if (!auth.empty()) {
-      curl_easy_setopt(curl, CURLOPT_HTTPAUTH, (long)CURLAUTH_ANY);
+      curl_easy_setopt(curl, CURLOPT_HTTPAUTH, auth_scheme);
       curl_easy_setopt(curl, CURLOPT_USERPWD, auth.c_str());
     }
 
@@ -232,17 +331,26 @@ void Curl(const v8::FunctionCallbackInfo<v8::Value> &args) {
     curl_easy_setopt(curl, CURLOPT_URL, url.c_str());
     curl_easy_setopt(curl, CURLOPT_USERAGENT, "couchbase-eventing/5.5");
 
-    if (strcmp(http_method.c_str(), "GET") == 0) {
+    // Binding's auth header goes alongside headers supplied by handler
+    if (!mime_type.empty()) {
+      curl_slist_free_all(headers);
+      headers = curl_slist_append(nullptr, mime_type.c_str());
+    }
+    if (!auth_header.empty()) {
+      headers = curl_slist_append(headers, auth_header.c_str());
+    }
+    if (headers != nullptr) {
+      curl_easy_setopt(curl, CURLOPT_HTTPHEADER, headers);
+    }
+
+    if (http_method == "GET") {
       res = curl_easy_perform(curl);
+      curl_slist_free_all(headers);
 
     } else {
-      if (mime_type.empty()) {
-        curl_easy_setopt(curl, CURLOPT_HTTPHEADER, headers);
-      } else {
-        curl_slist_free_all(headers);
-        headers = curl_slist_append(headers, mime_type.c_str());
+      if (http_method != "POST") {
+        curl_easy_setopt(curl, CURLOPT_CUSTOMREQUEST, http_method.c_str());
       }
-
       curl_easy_setopt(curl, CURLOPT_POSTFIELDS, data.c_str());
       curl_easy_setopt(curl, CURLOPT_POSTFIELDSIZE, (long)data.size());
 
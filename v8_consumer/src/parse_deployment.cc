@@ -20,6 +20,16 @@ deployment_config *ParseDeployment(const char *app_code) {
   config->metadata_bucket = dep_cfg->metadataBucket()->str();
   config->source_bucket = dep_cfg->sourceBucket()->str();
 
+  auto dead_letter = dep_cfg->deadLetter();
+  if (dead_letter != nullptr) {
+    config->dead_letter_bucket = dead_letter->bucketName()->str();
+  }
+
+  auto constants = dep_cfg->constants();
+  if (constants != nullptr) {
+    config->constants = constants->str();
+  }
+
   auto buckets = dep_cfg->buckets();
 
   std::map<std::string, std::vector<std::string>> buckets_info;
@@ -28,11 +38,32 @@ deployment_config *ParseDeployment(const char *app_code) {
     bucket_info.push_back(buckets->Get(i)->bucketName()->str());
     bucket_info.push_back(buckets->Get(i)->alias()->str());
 
+    // Bindings from payloads without access are treated as read-write, write
+    // to source bucket is blocked regardless
+    auto access = buckets->Get(i)->access();
+    bucket_info.push_back(access != nullptr ? access->str() : "rw");
+
     buckets_info[buckets->Get(i)->alias()->str()] = bucket_info;
   }
 
   config->component_configs["buckets"] = buckets_info;
 
+  auto curl = dep_cfg->curl();
+  if (curl != nullptr) {
+    for (flatbuffers::uoffset_t i = 0; i < curl->size(); ++i) {
+      auto entry = curl->Get(i);
+      curl_binding_t binding;
+      binding.alias = entry->alias()->str();
+      binding.base_url = entry->baseUrl()->str();
+      binding.auth_type = entry->authType()->str();
+      binding.username = entry->username()->str();
+      binding.password = entry->password()->str();
+      binding.bearer_key = entry->bearerKey()->str();
+      binding.allowed_methods = ToStringArray(entry->allowedMethods());
+      config->curl_bindings.push_back(binding);
+    }
+  }
+
   return config;
 }
 
@@ -0,0 +1,84 @@
+// Copyright (c) 2017 Couchbase, Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an "AS IS"
+// BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+// or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Stand-ins used while test-invoking a handler. Bucket bindings record writes
+// into an in-memory store, log lines are captured and builtins that would
+// reach outside of the worker throw.
+var __sandbox = (function(global) {
+    var sandbox = {
+        logs: [],
+        mutations: []
+    };
+
+    sandbox.reset = function() {
+        sandbox.logs = [];
+        sandbox.mutations = [];
+    };
+
+    sandbox.bind = function(alias, bucket, readOnly) {
+        global[alias] = new Proxy({}, {
+            get: function(store, key) {
+                if (Object.prototype.hasOwnProperty.call(store, key)) {
+                    return store[key];
+                }
+                return undefined;
+            },
+            set: function(store, key, value) {
+                if (readOnly) {
+                    throw 'Writing to read-only bucket binding is forbidden';
+                }
+                sandbox.mutations.push({
+                    op: 'upsert',
+                    alias: alias,
+                    bucket: bucket,
+                    key: String(key),
+                    value: value
+                });
+                store[key] = value;
+                return true;
+            },
+            deleteProperty: function(store, key) {
+                if (readOnly) {
+                    throw 'Delete from read-only bucket binding is forbidden';
+                }
+                sandbox.mutations.push({
+                    op: 'delete',
+                    alias: alias,
+                    bucket: bucket,
+                    key: String(key)
+                });
+                delete store[key];
+                return true;
+            }
+        });
+    };
+
+    function unsupported(name) {
+        return function() {
+            throw name + ' is not supported while testing a handler';
+        };
+    }
+
+    global.log = function() {
+        var parts = [];
+        for (var i = 0; i < arguments.length; ++i) {
+            parts.push(JSON.stringify(arguments[i]));
+        }
+        sandbox.logs.push(parts.join(' '));
+    };
+
+    global.curl = unsupported('curl');
+    global.createTimer = unsupported('createTimer');
+    global.execQuery = unsupported('N1QL query');
+    global.iter = unsupported('N1QL query');
+
+    return sandbox;
+})(this);
@@ -57,17 +57,24 @@ bool Timer::CreateTimerImpl(const v8::FunctionCallbackInfo<v8::Value> &args) {
   auto context = context_.Get(isolate_);
 
   auto js_exception = UnwrapData(isolate_)->js_exception;
-  auto epoch_info = Epoch(args[1]);
-  if (!epoch_info.is_valid) {
-    js_exception->Throw("Unable to compute epoch for the given Date instance");
-    return false;
-  }
-
   auto utils = UnwrapData(isolate_)->utils;
   auto v8worker = UnwrapData(isolate_)->v8worker;
 
   TimerInfo timer_info;
-  timer_info.epoch = epoch_info.epoch;
+  if (!args[4]->IsUndefined() && !ParseSchedule(args[4], timer_info)) {
+    return false;
+  }
+
+  // Recurring timer without a date first fires at the next time of its schedule
+  if (!args[1]->IsNull()) {
+    auto epoch_info = Epoch(args[1]);
+    if (!epoch_info.is_valid) {
+      js_exception->Throw("Unable to compute epoch for the given Date instance");
+      return false;
+    }
+    timer_info.epoch = epoch_info.epoch;
+  }
+
   timer_info.vb = v8worker->currently_processed_vb_;
   timer_info.seq_num = v8worker->currently_processed_seqno_;
   timer_info.callback = utils->GetFunctionName(args[0]);
@@ -101,8 +108,10 @@ bool Timer::ValidateArgs(const v8::FunctionCallbackInfo<v8::Value> &args) {
     return false;
   }
 
-  if (!args[1]->IsDate()) {
-    js_exception->Throw("First argument must be a JavaScript Date instance");
+  auto recurring = !args[4]->IsUndefined();
+  if (!args[1]->IsDate() && !(recurring && args[1]->IsNull())) {
+    js_exception->Throw("Second argument must be a JavaScript Date instance, "
+                        "or null for recurring timer");
     return false;
   }
 
@@ -114,6 +123,54 @@ bool Timer::ValidateArgs(const v8::FunctionCallbackInfo<v8::Value> &args) {
   return true;
 }
 
+// Reads schedule of a recurring timer from the options passed as fifth argument
+// of createTimer, either a 5 field cron expression evaluated in UTC or an
+// interval in seconds. Cron expression itself is validated when timer is stored.
+bool Timer::ParseSchedule(const v8::Local<v8::Value> &options_val,
+                          TimerInfo &timer_info) {
+  auto js_exception = UnwrapData(isolate_)->js_exception;
+  if (!options_val->IsObject()) {
+    js_exception->Throw("Fifth argument must be an object with either cron "
+                        "or interval of recurring timer");
+    return false;
+  }
+
+  auto context = context_.Get(isolate_);
+  auto options = options_val.As<v8::Object>();
+
+  v8::Local<v8::Value> cron_val, interval_val;
+  if (!TO_LOCAL(options->Get(context, v8Str(isolate_, "cron")), &cron_val) ||
+      !TO_LOCAL(options->Get(context, v8Str(isolate_, "interval")),
+                &interval_val)) {
+    return false;
+  }
+
+  if (cron_val->IsUndefined() == interval_val->IsUndefined()) {
+    js_exception->Throw(
+        "Either cron or interval must be specified for recurring timer");
+    return false;
+  }
+
+  if (!cron_val->IsUndefined()) {
+    if (!cron_val->IsString()) {
+      js_exception->Throw("cron of recurring timer must be a string");
+      return false;
+    }
+    timer_info.cron = UnwrapData(isolate_)->utils->ToCPPString(cron_val);
+    return true;
+  }
+
+  if (!interval_val->IsNumber() ||
+      interval_val.As<v8::Number>()->Value() < 1) {
+    js_exception->Throw(
+        "interval of recurring timer must be a positive number of seconds");
+    return false;
+  }
+  timer_info.interval =
+      static_cast<int64_t>(interval_val.As<v8::Number>()->Value());
+  return true;
+}
+
 void CreateTimer(const v8::FunctionCallbackInfo<v8::Value> &args) {
   auto isolate = args.GetIsolate();
   auto timer = UnwrapData(isolate)->timer;
@@ -176,6 +233,22 @@ std::string TimerInfo::ToJSON(v8::Isolate *isolate,
     }
   }
 
+  if (!cron.empty()) {
+    auto key = v8Str(isolate, "cron");
+    auto value = v8Str(isolate, cron);
+    if (!TO(entry->Set(context, key, value), &success) && !success) {
+      return json;
+    }
+  }
+
+  if (interval > 0) {
+    auto key = v8Str(isolate, "interval");
+    auto value = v8::Number::New(isolate, interval);
+    if (!TO(entry->Set(context, key, value), &success) && !success) {
+      return json;
+    }
+  }
+
   json = JSONStringify(isolate, entry);
   return json;
 }
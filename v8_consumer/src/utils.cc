@@ -148,6 +148,41 @@ std::string JSONStringify(v8::Isolate *isolate,
   return *utf8_result;
 }
 
+// Escapes string so that it can be embedded within a JSON string literal
+std::string EscapeJSONString(const std::string &str) {
+  std::string escaped;
+  char scratch[8];
+
+  for (const auto &c : str) {
+    switch (c) {
+    case '"':
+      escaped.append("\\\"");
+      break;
+    case '\\':
+      escaped.append("\\\\");
+      break;
+    case '\n':
+      escaped.append("\\n");
+      break;
+    case '\r':
+      escaped.append("\\r");
+      break;
+    case '\t':
+      escaped.append("\\t");
+      break;
+    default:
+      if (static_cast<unsigned char>(c) < 0x20) {
+        snprintf(scratch, sizeof(scratch), "\\u%04x", c);
+        escaped.append(scratch);
+      } else {
+        escaped.push_back(c);
+      }
+    }
+  }
+
+  return escaped;
+}
+
 // Extracts a C string from a V8 Utf8Value.
 const char *ToCString(const v8::String::Utf8Value &value) {
   return *value ? *value : "<std::string conversion failed>";
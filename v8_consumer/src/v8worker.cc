@@ -17,6 +17,7 @@
 #include "utils.h"
 
 #include "../../gen/js/builtin.h"
+#include "../../gen/js/sandbox.h"
 
 bool V8Worker::debugger_started_ = false;
 
@@ -29,6 +30,8 @@ std::atomic<int64_t> on_update_success = {0};
 std::atomic<int64_t> on_update_failure = {0};
 std::atomic<int64_t> on_delete_success = {0};
 std::atomic<int64_t> on_delete_failure = {0};
+std::atomic<int64_t> on_expiry_success = {0};
+std::atomic<int64_t> on_expiry_failure = {0};
 
 std::atomic<int64_t> timer_create_failure = {0};
 std::atomic<int64_t> timer_alarm_delete_failure = {0};
@@ -39,10 +42,12 @@ std::atomic<int64_t> messages_processed_counter = {0};
 
 std::atomic<int64_t> dcp_delete_msg_counter = {0};
 std::atomic<int64_t> dcp_mutation_msg_counter = {0};
+std::atomic<int64_t> dcp_expiry_msg_counter = {0};
 std::atomic<int64_t> timer_msg_counter = {0};
 
 std::atomic<int64_t> enqueued_dcp_delete_msg_counter = {0};
 std::atomic<int64_t> enqueued_dcp_mutation_msg_counter = {0};
+std::atomic<int64_t> enqueued_dcp_expiry_msg_counter = {0};
 std::atomic<int64_t> enqueued_timer_msg_counter = {0};
 
 const char *GetUsernameCbBucket(void *cookie, const char *host,
@@ -187,6 +192,8 @@ V8Worker::V8Worker(v8::Platform *platform, handler_config_t *h_config,
   curl_timeout = h_config->curl_timeout;
   histogram_ = new Histogram(HIST_FROM, HIST_TILL, HIST_WIDTH);
   thread_exit_cond_.store(false);
+  sandbox_events_enqueued_.store(0);
+  sandbox_events_routed_.store(0);
   v8::Isolate::CreateParams create_params;
   create_params.array_buffer_allocator =
       v8::ArrayBuffer::Allocator::NewDefaultAllocator();
@@ -224,6 +231,19 @@ V8Worker::V8Worker(v8::Platform *platform, handler_config_t *h_config,
   global->Set(v8::String::NewFromUtf8(isolate_, "createTimer"),
               v8::FunctionTemplate::New(isolate_, CreateTimer));
 
+  // Curl aliases evaluate to their base URL, e.g. curl(api + "/orders", {})
+  curl_bindings_ = config->curl_bindings;
+  for (auto &binding : curl_bindings_) {
+    auto credential = h_config->curl_credentials.find(binding.alias);
+    if (credential != h_config->curl_credentials.end()) {
+      binding.password = credential->second.first;
+      binding.bearer_key = credential->second.second;
+    }
+
+    global->Set(v8Str(isolate_, binding.alias),
+                v8Str(isolate_, binding.base_url), v8::ReadOnly);
+  }
+
   if (try_catch.HasCaught()) {
     LOG(logError) << "Exception logged:"
                   << ExceptionString(isolate_, &try_catch) << std::endl;
@@ -249,6 +269,13 @@ V8Worker::V8Worker(v8::Platform *platform, handler_config_t *h_config,
   execute_start_time_ = Time::now();
 
   cb_source_bucket_.assign(config->source_bucket);
+  dead_letter_enabled_ = !config->dead_letter_bucket.empty();
+  sandbox_ = h_config->sandbox;
+
+  // Init payload carries constants updated after deploy, test invocations
+  // only have the ones saved in deployment config
+  constants_ =
+      h_config->constants.empty() ? config->constants : h_config->constants;
 
   Bucket *bucket_handle = nullptr;
   execute_flag_ = false;
@@ -256,6 +283,12 @@ V8Worker::V8Worker(v8::Platform *platform, handler_config_t *h_config,
   max_task_duration_ = SECS_TO_NS * h_config->execution_timeout;
   timer_context_size = h_config->timer_context_size;
 
+  if (sandbox_) {
+    for (const auto &binding : config->component_configs["buckets"]) {
+      sandbox_bindings_[binding.first] = binding.second;
+    }
+  }
+
   if (!h_config->skip_lcb_bootstrap) {
     for (auto it = config->component_configs.begin();
          it != config->component_configs.end(); it++) {
@@ -265,10 +298,13 @@ V8Worker::V8Worker(v8::Platform *platform, handler_config_t *h_config,
           std::string bucket_alias = bucket->first;
           std::string bucket_name =
               config->component_configs["buckets"][bucket_alias][0];
+          bool read_only =
+              config->component_configs["buckets"][bucket_alias][2] == "r";
 
           bucket_handle = new Bucket(
               this, bucket_name.c_str(), settings_->kv_host_port.c_str(),
-              bucket_alias.c_str(), cb_source_bucket_ == bucket_name);
+              bucket_alias.c_str(),
+              read_only || cb_source_bucket_ == bucket_name);
 
           bucket_handles_.push_back(bucket_handle);
         }
@@ -308,6 +344,7 @@ V8Worker::V8Worker(v8::Platform *platform, handler_config_t *h_config,
   delete config;
 
   this->timer_queue_ = new Queue<timer_msg_t>();
+  this->dead_letter_queue_ = new Queue<dead_letter_msg_t>();
   this->worker_queue_ = new Queue<worker_msg_t>();
 
   std::thread r_thr(&V8Worker::RouteMessage, this);
@@ -329,12 +366,14 @@ V8Worker::~V8Worker() {
   context_.Reset();
   on_update_.Reset();
   on_delete_.Reset();
+  on_expiry_.Reset();
   delete conn_pool_;
   delete n1ql_handle_;
   delete settings_;
   delete histogram_;
   delete js_exception_;
   delete timer_queue_;
+  delete dead_letter_queue_;
   delete worker_queue_;
 }
 
@@ -429,6 +468,13 @@ int V8Worker::V8WorkerLoad(std::string script_to_execute) {
   script_to_execute_ = script_to_execute;
   LOG(logTrace) << "script to execute: " << RM(script_to_execute) << std::endl;
 
+  // Constants are made available before handler code runs, so that its global
+  // scope could refer to them as well
+  if (!InstallConstants(constants_)) {
+    LOG(logError) << "Failed to install constants, handler would run without them"
+                  << std::endl;
+  }
+
   if (!ExecuteScript(source)) {
     return kFailedToCompileJs;
   }
@@ -446,7 +492,14 @@ int V8Worker::V8WorkerLoad(std::string script_to_execute) {
     return kToLocalFailed;
   }
 
-  if (!on_update_def->IsFunction() && !on_delete_def->IsFunction()) {
+  v8::Local<v8::Value> on_expiry_def;
+  if (!TO_LOCAL(global->Get(context, v8Str(isolate_, "OnExpiry")),
+                &on_expiry_def)) {
+    return kToLocalFailed;
+  }
+
+  if (!on_update_def->IsFunction() && !on_delete_def->IsFunction() &&
+      !on_expiry_def->IsFunction()) {
     return kNoHandlersDefined;
   }
 
@@ -460,6 +513,15 @@ int V8Worker::V8WorkerLoad(std::string script_to_execute) {
     on_delete_.Reset(isolate_, on_delete_fun);
   }
 
+  if (on_expiry_def->IsFunction()) {
+    auto on_expiry_fun = on_expiry_def.As<v8::Function>();
+    on_expiry_.Reset(isolate_, on_expiry_fun);
+  }
+
+  if (sandbox_ && !InstallSandbox()) {
+    return kFailedToCompileJs;
+  }
+
   if (!bucket_handles_.empty()) {
     auto bucket_handle = bucket_handles_.begin();
 
@@ -502,6 +564,7 @@ void V8Worker::RouteMessage() {
 
     int vb_no = 0;
     int64_t seq_no = 0;
+    bool replay = false;
 
     switch (getEvent(msg.header->event)) {
     case eDCP:
@@ -516,7 +579,28 @@ void V8Worker::RouteMessage() {
               bucketop_filters_validity_[vb_no].Set(false);
             }
           } else {
-            this->SendDelete(msg.header->metadata, vb_no, seq_no);
+            auto result = this->SendDelete(msg.header->metadata, vb_no, seq_no);
+            if (result == kOnDeleteCallFail) {
+              SetSandboxException("OnDelete handler isn't defined");
+            }
+          }
+        }
+        break;
+      case oExpiry:
+        dcp_expiry_msg_counter++;
+        if (kSuccess == ParseMetadata(msg.header->metadata, vb_no, seq_no)) {
+          auto is_valid = bucketop_filters_validity_[vb_no].Get();
+          auto filter_seq_no = bucketop_filters_[vb_no].Get();
+          if (is_valid && seq_no <= filter_seq_no) {
+            if (seq_no == filter_seq_no) {
+              bucketop_filters_validity_[vb_no].Set(false);
+            }
+          } else {
+            auto result = this->SendExpiry(msg.header->metadata, vb_no, seq_no);
+            if (result == kOnExpiryCallFail || result == kOnDeleteCallFail) {
+              SetSandboxException(
+                  "Neither OnExpiry nor OnDelete handler is defined");
+            }
           }
         }
         break;
@@ -525,21 +609,30 @@ void V8Worker::RouteMessage() {
             (const void *)msg.payload->payload.c_str());
         val.assign(payload->value()->str());
         dcp_mutation_msg_counter++;
-        if (kSuccess == ParseMetadata(msg.header->metadata, vb_no, seq_no)) {
+        if (kSuccess ==
+            ParseMetadata(msg.header->metadata, vb_no, seq_no, replay)) {
           auto is_valid = bucketop_filters_validity_[vb_no].Get();
           auto filter_seq_no = bucketop_filters_[vb_no].Get();
-          if (is_valid && seq_no <= filter_seq_no) {
+          // Replayed dead letter carries seqno of its original mutation
+          if (!replay && is_valid && seq_no <= filter_seq_no) {
             if (seq_no == filter_seq_no) {
               bucketop_filters_validity_[vb_no].Set(false);
             }
           } else {
-            this->SendUpdate(val, msg.header->metadata, vb_no, seq_no, "json");
+            auto result = this->SendUpdate(val, msg.header->metadata, vb_no,
+                                           seq_no, "json", replay);
+            if (result == kOnUpdateCallFail) {
+              SetSandboxException("OnUpdate handler isn't defined");
+            }
           }
         }
         break;
       default:
         break;
       }
+      if (sandbox_) {
+        sandbox_events_routed_++;
+      }
       break;
     case eTimer:
       switch (getTimerOpcode(msg.header->opcode)) {
@@ -557,6 +650,24 @@ void V8Worker::RouteMessage() {
         break;
       }
       break;
+    case eApp_Worker_Setting:
+      switch (getAppWorkerSettingOpcode(msg.header->opcode)) {
+      case oConstants: {
+        v8::Locker locker(isolate_);
+        v8::Isolate::Scope isolate_scope(isolate_);
+        v8::HandleScope handle_scope(isolate_);
+
+        auto context = context_.Get(isolate_);
+        v8::Context::Scope context_scope(context);
+
+        if (InstallConstants(msg.header->metadata)) {
+          constants_ = msg.header->metadata;
+        }
+      } break;
+      default:
+        break;
+      }
+      break;
     case eDebugger:
       switch (getDebuggerOpcode(msg.header->opcode)) {
       case oDebuggerStart:
@@ -628,7 +739,7 @@ void V8Worker::UpdateHistogram(Time::time_point start_time) {
 }
 
 int V8Worker::SendUpdate(std::string value, std::string meta, int vb_no,
-                         int64_t seq_no, std::string doc_type) {
+                         int64_t seq_no, std::string doc_type, bool replay) {
   Time::time_point start_time = Time::now();
 
   v8::Locker locker(isolate_);
@@ -656,10 +767,13 @@ int V8Worker::SendUpdate(std::string value, std::string meta, int vb_no,
   }
 
   currently_processed_vb_ = vb_no;
-  currently_processed_seqno_ = seq_no;
-  vb_seq_[vb_no].Set(vb_no);
-  vb_seq_validity_[vb_no].Set(true);
-  processed_bucketops_[vb_no].Set(seq_no);
+  // Replay mustn't take vbucket progress back to seqno of the original mutation
+  if (!replay) {
+    currently_processed_seqno_ = seq_no;
+    vb_seq_[vb_no].Set(vb_no);
+    vb_seq_validity_[vb_no].Set(true);
+    processed_bucketops_[vb_no].Set(seq_no);
+  }
   if (on_update_.IsEmpty()) {
     UpdateHistogram(start_time);
     return kOnUpdateCallFail;
@@ -691,6 +805,11 @@ int V8Worker::SendUpdate(std::string value, std::string meta, int vb_no,
   if (try_catch.HasCaught()) {
     LOG(logDebug) << "OnUpdate Exception: "
                   << ExceptionString(isolate_, &try_catch) << std::endl;
+    AddDeadLetter(meta, vb_no, seq_no, "mutation", try_catch);
+    if (sandbox_) {
+      std::string category;
+      GetFailureDetails(try_catch, category, sandbox_exception_);
+    }
     UpdateHistogram(start_time);
     on_update_failure++;
     return kOnUpdateCallFail;
@@ -753,6 +872,11 @@ int V8Worker::SendDelete(std::string meta, int vb_no, int64_t seq_no) {
   if (try_catch.HasCaught()) {
     LOG(logDebug) << "OnDelete Exception: "
                   << ExceptionString(isolate_, &try_catch) << std::endl;
+    AddDeadLetter(meta, vb_no, seq_no, "deletion", try_catch);
+    if (sandbox_) {
+      std::string category;
+      GetFailureDetails(try_catch, category, sandbox_exception_);
+    }
     UpdateHistogram(start_time);
     on_delete_failure++;
     return kOnDeleteCallFail;
@@ -763,6 +887,71 @@ int V8Worker::SendDelete(std::string meta, int vb_no, int64_t seq_no) {
   return kSuccess;
 }
 
+// Handlers that don't define OnExpiry get expirations delivered to OnDelete,
+// same as when KV didn't distinguish between them
+int V8Worker::SendExpiry(std::string meta, int vb_no, int64_t seq_no) {
+  if (on_expiry_.IsEmpty()) {
+    return SendDelete(meta, vb_no, seq_no);
+  }
+
+  Time::time_point start_time = Time::now();
+
+  v8::Locker locker(isolate_);
+  v8::Isolate::Scope isolate_scope(isolate_);
+  v8::HandleScope handle_scope(isolate_);
+
+  auto context = context_.Get(isolate_);
+  v8::Context::Scope context_scope(context);
+
+  LOG(logTrace) << " meta: " << RU(meta) << std::endl;
+  v8::TryCatch try_catch(isolate_);
+
+  v8::Local<v8::Value> args[1];
+  if (!TO_LOCAL(v8::JSON::Parse(context, v8Str(isolate_, meta)), &args[0])) {
+    return kToLocalFailed;
+  }
+
+  currently_processed_vb_ = vb_no;
+  currently_processed_seqno_ = seq_no;
+  vb_seq_[vb_no].Set(vb_no);
+  vb_seq_validity_[vb_no].Set(true);
+  processed_bucketops_[vb_no].Set(seq_no);
+
+  assert(!try_catch.HasCaught());
+
+  if (debugger_started_) {
+    if (!agent_->IsStarted()) {
+      agent_->Start(isolate_, platform_, src_path_.c_str());
+    }
+
+    agent_->PauseOnNextJavascriptStatement("Break on start");
+    return DebugExecute("OnExpiry", args, 1) ? kSuccess : kOnExpiryCallFail;
+  }
+
+  auto on_doc_expiry = on_expiry_.Get(isolate_);
+
+  execute_flag_ = true;
+  execute_start_time_ = Time::now();
+  RetryWithFixedBackoff(std::numeric_limits<int>::max(), 10,
+                        IsTerminatingRetriable, IsExecutionTerminating,
+                        isolate_);
+
+  on_doc_expiry->Call(context->Global(), 1, args);
+  execute_flag_ = false;
+  if (try_catch.HasCaught()) {
+    LOG(logDebug) << "OnExpiry Exception: "
+                  << ExceptionString(isolate_, &try_catch) << std::endl;
+    AddDeadLetter(meta, vb_no, seq_no, "expiration", try_catch);
+    UpdateHistogram(start_time);
+    on_expiry_failure++;
+    return kOnExpiryCallFail;
+  }
+
+  UpdateHistogram(start_time);
+  on_expiry_success++;
+  return kSuccess;
+}
+
 void V8Worker::SendTimer(const TimerEvent &event) {
   LOG(logTrace) << "Got timer event, context:" << RU(event.context)
                 << " callback:" << RU(event.callback) << std::endl;
@@ -821,6 +1010,11 @@ void V8Worker::SendTimer(const TimerEvent &event) {
     ++timer_alarm_delete_failure;
   }
 
+  // Context of a recurring timer is carried over to its next alarm
+  if (event.context_key.empty()) {
+    return;
+  }
+
   info = metadata_bucket_->Delete(event.context_key, event.context_cas);
   if (!info.success) {
     ++timer_context_delete_failure;
@@ -876,6 +1070,9 @@ void V8Worker::Enqueue(header_t *h, message_t *p) {
                 << " opcode: " << static_cast<int16_t>(h->opcode)
                 << " partition: " << h->partition
                 << " metadata: " << RU(h->metadata) << std::endl;
+  if (sandbox_ && getEvent(h->event) == eDCP) {
+    sandbox_events_enqueued_++;
+  }
   worker_queue_->Push(msg);
 }
 
@@ -990,6 +1187,264 @@ void V8Worker::GetBucketOpsMessages(std::vector<uv_buf_t> &messages) {
   }
 }
 
+void V8Worker::GetDeadLetterMessages(std::vector<uv_buf_t> &messages,
+                                     size_t window_size) {
+  int64_t entry_count =
+      std::min(dead_letter_queue_->Count(), static_cast<int64_t>(window_size));
+
+  for (int64_t idx = 0; idx < entry_count; ++idx) {
+    dead_letter_msg_t dead_letter_msg;
+    if (!dead_letter_queue_->Pop(dead_letter_msg))
+      break;
+    auto curr_messages = BuildResponse(
+        dead_letter_msg.entry, mDead_Letter_Response, deadLetterEntry);
+    for (auto &msg : curr_messages) {
+      messages.push_back(msg);
+    }
+  }
+}
+
+// Queues up failed event along with failure details, so that Go side can
+// persist it to the dead letter bucket. Entry is assembled by hand as the
+// isolate may still be terminating in case of execution timeout.
+void V8Worker::AddDeadLetter(const std::string &meta, int vb_no,
+                             int64_t seq_no, const std::string &event,
+                             v8::TryCatch &try_catch) {
+  if (!dead_letter_enabled_) {
+    return;
+  }
+
+  std::string category, exception;
+  GetFailureDetails(try_catch, category, exception);
+
+  std::string entry = R"({"meta":)" + meta + R"(,"vb":)" +
+                      std::to_string(vb_no) + R"(,"seq_no":)" +
+                      std::to_string(seq_no) + R"(,"event":")" + event +
+                      R"(","category":")" + category +
+                      R"(","exception":")" + EscapeJSONString(exception) +
+                      R"("})";
+
+  dead_letter_msg_t msg;
+  msg.entry = entry;
+  dead_letter_queue_->Push(msg);
+}
+
+// Describes why handler invocation failed, telling execution timeouts apart
+// from exceptions thrown by handler code
+void V8Worker::GetFailureDetails(v8::TryCatch &try_catch, std::string &category,
+                                 std::string &exception) {
+  if (try_catch.HasTerminated()) {
+    category = "timeout";
+    exception = "Execution timed out after " +
+                std::to_string(max_task_duration_ / (SECS_TO_NS)) +
+                " seconds";
+  } else {
+    category = "exception";
+    exception = ExceptionString(isolate_, &try_catch);
+  }
+}
+
+// Replaces bucket bindings and side-effecting builtins with recording
+// stand-ins, so that test invocation of handler doesn't reach real buckets
+bool V8Worker::InstallSandbox() {
+  if (!ExecuteScript(v8Str(isolate_, (const char *)js_sandbox))) {
+    LOG(logError) << "Failed to install sandbox" << std::endl;
+    return false;
+  }
+
+  for (const auto &binding : sandbox_bindings_) {
+    auto read_only =
+        binding.second[2] == "r" || binding.second[0] == cb_source_bucket_;
+    auto bind = "__sandbox.bind(\"" + binding.first + "\", \"" +
+                binding.second[0] + "\", " +
+                (read_only ? "true" : "false") + ");";
+    if (!ExecuteScript(v8Str(isolate_, bind))) {
+      LOG(logError) << "Failed to bind sandbox bucket for alias: "
+                    << binding.first << std::endl;
+      return false;
+    }
+  }
+
+  return true;
+}
+
+// Binds each key of the constants JSON object as a read-only global that can't
+// be deleted. Values are deep frozen, as they're shared by all events handled by
+// the worker. Updates replace the whole set, aliases bound earlier but missing
+// from constants throw when read. Expects isolate and context to be entered.
+bool V8Worker::InstallConstants(const std::string &constants) {
+  if (constants.empty() && constant_aliases_.empty()) {
+    return true;
+  }
+
+  v8::HandleScope handle_scope(isolate_);
+  v8::TryCatch try_catch(isolate_);
+
+  auto context = context_.Get(isolate_);
+  auto global = context->Global();
+
+  v8::Local<v8::Value> constants_val;
+  if (constants.empty()) {
+    constants_val = v8::Object::New(isolate_);
+  } else if (!TO_LOCAL(v8::JSON::Parse(context, v8Str(isolate_, constants)),
+                       &constants_val) ||
+             !constants_val->IsObject()) {
+    LOG(logError) << "Constants aren't a JSON object: " << RU(constants)
+                  << std::endl;
+    return false;
+  }
+
+  if (!DeepFreeze(context, constants_val)) {
+    LOG(logError) << "Failed to freeze constants" << std::endl;
+    return false;
+  }
+
+  // Bound constants read from the latest constants object, so an update only
+  // has to bind aliases that weren't bound before
+  auto constants_obj = constants_val.As<v8::Object>();
+  constant_values_.Reset(isolate_, constants_obj);
+
+  v8::Local<v8::Array> aliases;
+  if (!TO_LOCAL(constants_obj->GetOwnPropertyNames(context), &aliases)) {
+    return false;
+  }
+
+  for (uint32_t i = 0; i < aliases->Length(); ++i) {
+    v8::Local<v8::Value> alias, value;
+    if (!TO_LOCAL(aliases->Get(context, i), &alias) ||
+        !TO_LOCAL(constants_obj->Get(context, alias), &value)) {
+      return false;
+    }
+
+    v8::String::Utf8Value alias_utf8(alias);
+    std::string alias_name(ToCString(alias_utf8));
+    if (constant_aliases_.count(alias_name) > 0) {
+      continue;
+    }
+
+    // Handler can neither assign, redefine nor delete a constant
+    auto attributes = static_cast<v8::PropertyAttribute>(
+        v8::ReadOnly | v8::DontEnum | v8::DontDelete);
+    if (!global
+             ->SetAccessor(context, alias.As<v8::String>(), GetConstant,
+                           nullptr, v8::MaybeLocal<v8::Value>(),
+                           v8::DEFAULT, attributes)
+             .FromMaybe(false)) {
+      LOG(logError) << "Failed to bind constant: " << alias_name << std::endl;
+      return false;
+    }
+    constant_aliases_.insert(alias_name);
+  }
+
+  LOG(logInfo) << "Bound " << aliases->Length() << " constants" << std::endl;
+  return true;
+}
+
+// Freezes the object along with all objects nested within. Parsed JSON has no
+// cycles, hence no bookkeeping of objects already visited.
+bool V8Worker::DeepFreeze(const v8::Local<v8::Context> &context,
+                          const v8::Local<v8::Value> &value) {
+  if (!value->IsObject()) {
+    return true;
+  }
+
+  auto obj = value.As<v8::Object>();
+  v8::Local<v8::Array> keys;
+  if (!TO_LOCAL(obj->GetOwnPropertyNames(context), &keys)) {
+    return false;
+  }
+
+  for (uint32_t i = 0; i < keys->Length(); ++i) {
+    v8::Local<v8::Value> key, nested;
+    if (!TO_LOCAL(keys->Get(context, i), &key) ||
+        !TO_LOCAL(obj->Get(context, key), &nested) ||
+        !DeepFreeze(context, nested)) {
+      return false;
+    }
+  }
+
+  return obj->SetIntegrityLevel(context, v8::IntegrityLevel::kFrozen)
+      .FromMaybe(false);
+}
+
+// Constants dropped by an update stay bound, as they can't be deleted, but
+// reading them throws as reading an undefined global would
+void V8Worker::GetConstant(v8::Local<v8::Name> name,
+                           const v8::PropertyCallbackInfo<v8::Value> &info) {
+  auto isolate = info.GetIsolate();
+  auto context = isolate->GetCurrentContext();
+  auto w = UnwrapData(isolate)->v8worker;
+
+  auto constants = w->constant_values_.Get(isolate);
+  v8::Local<v8::Value> value;
+  if (!constants.IsEmpty() &&
+      constants->HasOwnProperty(context, name).FromMaybe(false) &&
+      TO_LOCAL(constants->Get(context, name), &value)) {
+    info.GetReturnValue().Set(value);
+    return;
+  }
+
+  v8::String::Utf8Value name_utf8(name);
+  auto msg = std::string(ToCString(name_utf8)) + " is not defined";
+  isolate->ThrowException(v8::Exception::ReferenceError(v8Str(isolate, msg)));
+}
+
+// Reports back bucket writes, log lines and exception if any, of handler runs
+// against test events enqueued so far. Test events reach the worker as
+// regular DCP events, so they go through bucket op filtering and routing.
+// Request id is echoed back, for Go side to tell a late reply from the
+// result of its current request.
+std::string V8Worker::TestRun(const std::string &request_id) {
+  while (sandbox_events_routed_.load() < sandbox_events_enqueued_.load()) {
+    if (thread_exit_cond_.load()) {
+      return R"({"request_id":")" + request_id +
+             R"(","exception":"Worker is shutting down"})";
+    }
+    std::this_thread::sleep_for(std::chrono::milliseconds(10));
+  }
+
+  v8::Locker locker(isolate_);
+  v8::Isolate::Scope isolate_scope(isolate_);
+  v8::HandleScope handle_scope(isolate_);
+
+  auto context = context_.Get(isolate_);
+  v8::Context::Scope context_scope(context);
+
+  v8::Local<v8::Value> sandbox_val;
+  if (!TO_LOCAL(context->Global()->Get(context, v8Str(isolate_, "__sandbox")),
+                &sandbox_val) ||
+      !sandbox_val->IsObject()) {
+    return R"({"request_id":")" + request_id +
+           R"(","exception":"Sandbox isn't installed"})";
+  }
+
+  auto sandbox = sandbox_val.As<v8::Object>();
+  auto response = v8::Object::New(isolate_);
+  response->Set(v8Str(isolate_, "request_id"), v8Str(isolate_, request_id));
+  response->Set(v8Str(isolate_, "logs"), sandbox->Get(v8Str(isolate_, "logs")));
+  response->Set(v8Str(isolate_, "mutations"),
+                sandbox->Get(v8Str(isolate_, "mutations")));
+  if (!sandbox_exception_.empty()) {
+    response->Set(v8Str(isolate_, "exception"),
+                  v8Str(isolate_, sandbox_exception_));
+  }
+  auto result = JSONStringify(isolate_, response);
+
+  // Next test event starts off with no logs, writes or exception recorded
+  sandbox_exception_.clear();
+  if (!ExecuteScript(v8Str(isolate_, "__sandbox.reset();"))) {
+    LOG(logError) << "Failed to reset sandbox" << std::endl;
+  }
+
+  return result;
+}
+
+void V8Worker::SetSandboxException(const std::string &exception) {
+  if (sandbox_ && sandbox_exception_.empty()) {
+    sandbox_exception_ = exception;
+  }
+}
+
 std::vector<uv_buf_t> V8Worker::BuildResponse(const std::string &payload,
                                               int8_t msg_type,
                                               int8_t response_opcode) {
@@ -1016,6 +1471,12 @@ std::vector<uv_buf_t> V8Worker::BuildResponse(const std::string &payload,
 
 int V8Worker::ParseMetadata(const std::string &metadata, int &vb_no,
                             int64_t &seq_no) {
+  bool replay = false;
+  return ParseMetadata(metadata, vb_no, seq_no, replay);
+}
+
+int V8Worker::ParseMetadata(const std::string &metadata, int &vb_no,
+                            int64_t &seq_no, bool &replay) {
   v8::Locker locker(isolate_);
   v8::Isolate::Scope isolate_scope(isolate_);
   v8::HandleScope handle_scope(isolate_);
@@ -1045,6 +1506,13 @@ int V8Worker::ParseMetadata(const std::string &metadata, int &vb_no,
     return kToLocalFailed;
   }
 
+  v8::Local<v8::Value> replay_val;
+  if (!TO_LOCAL(metadata_obj->Get(context, v8Str(isolate_, "replay")),
+                &replay_val)) {
+    return kToLocalFailed;
+  }
+  replay = replay_val->IsTrue();
+
   if (seq_val->IsNumber() && vb_val->IsNumber()) {
     v8::Local<v8::Integer> vb_val_int;
     if (!TO_LOCAL(vb_val->ToInteger(context), &vb_val_int)) {
@@ -1059,6 +1527,13 @@ int V8Worker::ParseMetadata(const std::string &metadata, int &vb_no,
     vb_no = vb_val_int->Value();
     seq_no = seq_val_int->Value();
   }
+
+  // Per vbucket state is indexed by vb_no, which comes from outside the worker
+  if (vb_no < 0 || vb_no >= NUM_VBUCKETS) {
+    LOG(logError) << "vbucket: " << vb_no << " in metadata is out of range"
+                  << std::endl;
+    return kInvalidVbucket;
+  }
   return kSuccess;
 }
 
@@ -1086,5 +1561,6 @@ void V8Worker::ClearTimerFilter(int vb_no) { timer_filters_[vb_no].Set(false); }
 void V8Worker::SetThreadExitFlag() {
   thread_exit_cond_.store(true);
   timer_queue_->Close();
+  dead_letter_queue_->Close();
   worker_queue_->Close();
 }